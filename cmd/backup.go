@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/engmtcdrm/go-pardon"
+	pp "github.com/engmtcdrm/go-prettyprint"
+	"github.com/engmtcdrm/mellon/app"
+	"github.com/engmtcdrm/mellon/env"
+	"github.com/engmtcdrm/mellon/header"
+	"github.com/engmtcdrm/mellon/secrets"
+)
+
+// backupPassphraseEnv is the environment variable that can hold the passphrase of
+// backups, so scripts can create and restore them without being prompted.
+const backupPassphraseEnv = "MELLON_BACKUP_PASSPHRASE"
+
+func init() {
+	backupCmd.Flags().StringVarP(
+		&output,
+		"output",
+		"o",
+		"",
+		fmt.Sprintf("(optional) File to write the backup to. Defaults to %s-backup-<timestamp>.backup in the current directory", app.Name),
+	)
+
+	backupCmd.MarkFlagFilename("output")
+
+	backupCmd.AddCommand(backupVerifyCmd)
+	rootCmd.AddCommand(backupCmd)
+}
+
+// askBackupPassphrase returns the passphrase of a backup. It is read from the
+// environment variable MELLON_BACKUP_PASSPHRASE if set, otherwise the user is
+// prompted, twice when confirm is set.
+func askBackupPassphrase(confirm bool) ([]byte, error) {
+	if p, ok := os.LookupEnv(backupPassphraseEnv); ok {
+		if p == "" {
+			return nil, fmt.Errorf("%s cannot be empty", backupPassphraseEnv)
+		}
+		return []byte(p), nil
+	}
+
+	if machineReadable() {
+		return nil, fmt.Errorf("%w with --output-format: set %s to the passphrase of the backup", errPromptRefused, backupPassphraseEnv)
+	}
+
+	if !stdinIsTerminal() {
+		return nil, fmt.Errorf("%w as stdin is not a terminal: set %s to the passphrase of the backup", errPromptRefused, backupPassphraseEnv)
+	}
+
+	if confirm {
+		return promptNewPassphrase("Enter a passphrase for the backup:", "Confirm the passphrase for the backup:")
+	}
+
+	var pass []byte
+	promptPass := pardon.NewPassword(&pass).
+		Title("Enter the passphrase of the backup:")
+
+	if err := promptPass.Ask(); err != nil {
+		return nil, err
+	}
+
+	fmt.Println()
+
+	return pass, nil
+}
+
+// openBackup reads the backup at path and opens it with its passphrase.
+func openBackup(path string) (*secrets.Backup, error) {
+	path, err := env.ExpandTilde(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read backup '%s': %w", path, err)
+	}
+
+	pass, err := askBackupPassphrase(false)
+	if err != nil {
+		return nil, err
+	}
+	defer secrets.ClearSecret(&pass)
+
+	return secrets.OpenBackup(data, pass)
+}
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Back up all secrets into a single encrypted file",
+	Long: fmt.Sprintf(
+		"Back up all secrets into a single encrypted file.\n\n"+
+			"The backup holds every secret along with its metadata, its previous versions and the encryption key. "+
+			"It is sealed with a passphrase of its own rather than the encryption key, so it is safe to keep on shared storage. "+
+			"The passphrase can also be provided through the environment variable %s.\n\n"+
+			"Secrets are restored with the restore command, and a backup can be checked without restoring it with backup verify.",
+		backupPassphraseEnv,
+	),
+	Example: fmt.Sprintf("  %s backup\n  %s backup -o ~/backups/secrets.backup\n  %s backup verify ~/backups/secrets.backup", app.Name, app.Name, app.Name),
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !machineReadable() {
+			header.PrintHeader()
+		}
+
+		if len(secretFiles) == 0 {
+			return errors.New("no secrets found to back up")
+		}
+
+		path := output
+		if path == "" {
+			path = fmt.Sprintf("%s-backup-%s.backup", app.Name, time.Now().Format("20060102-150405"))
+		}
+
+		path, err := env.ExpandTilde(path)
+		if err != nil {
+			return err
+		}
+
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("file '%s' already exists", path)
+		}
+
+		pass, err := askBackupPassphrase(true)
+		if err != nil {
+			return err
+		}
+		defer secrets.ClearSecret(&pass)
+
+		backup, manifest, err := secrets.CreateBackup(env.Instance.KeyPath(), secretFiles, pass)
+		if err != nil {
+			return fmt.Errorf("could not back up secrets: %w", err)
+		}
+
+		if err := writeOutputFile(path, backup, "backup"); err != nil {
+			return err
+		}
+
+		return printComplete(
+			fmt.Sprintf("Backed up %d secret(s) to %s", len(manifest.Secrets), pp.Green(path)),
+			result{Action: "backed_up", Secrets: manifest.Secrets, Path: path},
+		)
+	},
+}
+
+var backupVerifyCmd = &cobra.Command{
+	Use:     "verify <file>",
+	Short:   "Verify a backup without restoring it",
+	Long:    "Verify a backup without restoring it.\n\nThe backup is opened with its passphrase and every file in it is checked against the checksums recorded when it was created.",
+	Example: fmt.Sprintf("  %s backup verify %s-backup-20250101-120000.backup", app.Name, app.Name),
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !machineReadable() {
+			header.PrintHeader()
+		}
+
+		backup, err := openBackup(args[0])
+		if err != nil {
+			return err
+		}
+
+		if machineReadable() {
+			return printOutput(struct {
+				Path      string    `json:"path"`
+				CreatedAt time.Time `json:"created_at"`
+				Secrets   []string  `json:"secrets"`
+				Versions  int       `json:"versions"` // Number of previous versions of the secrets
+			}{args[0], backup.Manifest.CreatedAt, backup.Manifest.Secrets, backup.Versions()})
+		}
+
+		fmt.Println(pp.Completef("Backup %s is intact", pp.Green(args[0])))
+		fmt.Println()
+		fmt.Printf("Created:  %s\n", backup.Manifest.CreatedAt.Local().Format("2006-01-02 15:04:05"))
+		fmt.Printf("Secrets:  %d\n", len(backup.Manifest.Secrets))
+		fmt.Printf("Versions: %d previous version(s)\n", backup.Versions())
+
+		return nil
+	},
+}
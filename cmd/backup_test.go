@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/engmtcdrm/mellon/env"
+)
+
+// TestBackupRestoreCommand tests backing up secrets, verifying the backup and
+// restoring secrets from it.
+func TestBackupRestoreCommand(t *testing.T) {
+	env.Init()
+
+	secretName := "testbackup/secret"
+	backupFile := filepath.Join(t.TempDir(), "secrets.backup")
+
+	withPassphrase := func(pass string, args ...string) *exec.Cmd {
+		cmd := exec.Command(testBinary, args...)
+		cmd.Env = append(os.Environ(), backupPassphraseEnv+"="+pass)
+		return cmd
+	}
+
+	view := func() (string, error) {
+		output, err := exec.Command(testBinary, "view", "--secret", secretName).Output()
+		return string(output), err
+	}
+
+	if output, err := exec.Command(testBinary, "create", "--secret", secretName, "--field", fieldFile(t, "user", "app"), "--field", fieldFile(t, "password", "backedup")).CombinedOutput(); err != nil {
+		t.Fatalf("failed to create secret: %v, output: %s", err, output)
+	}
+	defer exec.Command(testBinary, "delete", "--secret", secretName, "--force").Run()
+
+	if output, err := withPassphrase("backup-pass", "backup", "-o", backupFile).CombinedOutput(); err != nil {
+		t.Fatalf("failed to back up secrets: %v, output: %s", err, output)
+	}
+
+	if info, err := os.Stat(backupFile); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("expected backup file to have mode 0600, got: %v", err)
+	}
+
+	// The backup is sealed, nothing in it can be read without the passphrase
+	data, err := os.ReadFile(backupFile)
+	if err != nil || strings.Contains(string(data), "testbackup") {
+		t.Errorf("expected backup to not reveal the names of secrets, error: %v", err)
+	}
+
+	if output, err := withPassphrase("backup-pass", "backup", "-o", backupFile).CombinedOutput(); err == nil {
+		t.Errorf("expected backing up to an existing file to fail, got: %s", output)
+	}
+
+	output, err := withPassphrase("backup-pass", "backup", "verify", backupFile).CombinedOutput()
+	if err != nil || !strings.Contains(string(output), "is intact") {
+		t.Errorf("expected backup to verify, got: %v, output: %s", err, output)
+	}
+
+	if output, err := withPassphrase("wrong-pass", "backup", "verify", backupFile).CombinedOutput(); err == nil {
+		t.Errorf("expected verifying with the wrong passphrase to fail, got: %s", output)
+	}
+
+	if output, err := exec.Command(testBinary, "delete", "--secret", secretName, "--force").CombinedOutput(); err != nil {
+		t.Fatalf("failed to delete secret: %v, output: %s", err, output)
+	}
+
+	// A dry run changes nothing
+	output, err = withPassphrase("backup-pass", "restore", backupFile, "--dry-run").CombinedOutput()
+	if err != nil || !strings.Contains(string(output), "Would restore") {
+		t.Errorf("expected dry run to list the secret, got: %v, output: %s", err, output)
+	}
+
+	if _, err := view(); err == nil {
+		t.Errorf("expected secret to not be restored by a dry run")
+	}
+
+	if output, err := withPassphrase("backup-pass", "restore", backupFile).CombinedOutput(); err != nil {
+		t.Fatalf("failed to restore backup: %v, output: %s", err, output)
+	}
+
+	output, err = exec.Command(testBinary, "view", "--secret", secretName, "--field", "password").CombinedOutput()
+	if err != nil || string(output) != "backedup" {
+		t.Errorf("expected restored field 'backedup', got: %s, error: %v", output, err)
+	}
+
+	// Existing secrets are kept when merging and replaced when overwriting
+	if output, err := exec.Command(testBinary, "update", "--secret", secretName, "--field", fieldFile(t, "password", "changed")).CombinedOutput(); err != nil {
+		t.Fatalf("failed to update secret: %v, output: %s", err, output)
+	}
+
+	if output, err := withPassphrase("backup-pass", "restore", backupFile, "--mode", "merge").CombinedOutput(); err != nil {
+		t.Fatalf("failed to restore backup: %v, output: %s", err, output)
+	}
+
+	output, _ = exec.Command(testBinary, "view", "--secret", secretName, "--field", "password").CombinedOutput()
+	if string(output) != "changed" {
+		t.Errorf("expected existing secret to be kept, got: %s", output)
+	}
+
+	if output, err := withPassphrase("backup-pass", "restore", backupFile, "--mode", "overwrite").CombinedOutput(); err != nil {
+		t.Fatalf("failed to restore backup: %v, output: %s", err, output)
+	}
+
+	output, _ = exec.Command(testBinary, "view", "--secret", secretName, "--field", "password").CombinedOutput()
+	if string(output) != "backedup" {
+		t.Errorf("expected existing secret to be overwritten, got: %s", output)
+	}
+
+	if output, err := withPassphrase("backup-pass", "restore", backupFile, "--mode", "replace").CombinedOutput(); err == nil {
+		t.Errorf("expected invalid mode to fail, got: %s", output)
+	}
+}
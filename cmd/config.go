@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	pp "github.com/engmtcdrm/go-prettyprint"
+	"github.com/engmtcdrm/mellon/app"
+	"github.com/engmtcdrm/mellon/config"
+	"github.com/engmtcdrm/mellon/env"
+	"github.com/engmtcdrm/mellon/header"
+)
+
+func init() {
+	configCmd.AddCommand(configListCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+
+	rootCmd.AddCommand(configCmd)
+}
+
+// settingCompletion completes the names of the configuration settings.
+func settingCompletion(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	return config.Keys(), cobra.ShellCompDirectiveNoFileComp
+}
+
+// settingInfo is a setting and its value, output when the output is machine-readable.
+type settingInfo struct {
+	Setting     string `json:"setting"`
+	Value       string `json:"value"`
+	Description string `json:"description,omitempty"`
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the configuration",
+	Long:  fmt.Sprintf("Manage the configuration.\n\nThe configuration is stored in ~/%s/config.json.", app.DotName),
+}
+
+var configListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List all settings and their values",
+	Long:    "List all settings and their values",
+	Example: fmt.Sprintf("  %s config list", app.Name),
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		settings := make([]settingInfo, 0, len(config.Keys()))
+		for _, key := range config.Keys() {
+			value, _ := cfg.Get(key)
+			description, _ := config.Describe(key)
+			settings = append(settings, settingInfo{key, value, description})
+		}
+
+		if machineReadable() {
+			return printOutput(settings)
+		}
+
+		header.PrintHeader()
+
+		rows := make([][]string, 0, len(settings))
+		for _, s := range settings {
+			rows = append(rows, []string{s.Setting, s.Value, s.Description})
+		}
+
+		printTable([]string{"SETTING", "VALUE", "DESCRIPTION"}, rows, pp.Yellow)
+		fmt.Println()
+
+		return nil
+	},
+}
+
+var configGetCmd = &cobra.Command{
+	Use:               "get <setting>",
+	Short:             "Print the value of a setting",
+	Long:              "Print the value of a setting",
+	Example:           fmt.Sprintf("  %s config get history.retention", app.Name),
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: settingCompletion,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		value, err := cfg.Get(args[0])
+		if err != nil {
+			return usageError(err)
+		}
+
+		if machineReadable() {
+			return printOutput(settingInfo{Setting: args[0], Value: value})
+		}
+
+		fmt.Println(value)
+
+		return nil
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:               "set <setting> <value>",
+	Short:             "Change the value of a setting",
+	Long:              "Change the value of a setting",
+	Example:           fmt.Sprintf("  %s config set history.retention 20", app.Name),
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: settingCompletion,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := cfg.Set(args[0], args[1]); err != nil {
+			return usageError(err)
+		}
+
+		if err := cfg.Save(env.Instance.ConfigPath()); err != nil {
+			return err
+		}
+
+		if machineReadable() {
+			value, _ := cfg.Get(args[0])
+			return printOutput(settingInfo{Setting: args[0], Value: value})
+		}
+
+		fmt.Println(pp.Completef("Setting %s changed to %s", args[0], args[1]))
+
+		return nil
+	},
+}
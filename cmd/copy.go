@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	pp "github.com/engmtcdrm/go-prettyprint"
+	"github.com/engmtcdrm/mellon/app"
+	"github.com/engmtcdrm/mellon/env"
+	"github.com/engmtcdrm/mellon/secrets"
+)
+
+func init() {
+	rootCmd.AddCommand(copyCmd)
+}
+
+var copyCmd = &cobra.Command{
+	Use:               "copy <secret> <new-name>",
+	Aliases:           []string{"cp"},
+	Short:             "Copy a secret",
+	Long:              "Copy a secret.\n\nThe encrypted secret is copied along with its metadata and history, without decrypting it. To copy every secret in a namespace, end both names with a slash.",
+	Example:           fmt.Sprintf("  %s copy db/password db/password-backup\n  %s cp staging/ qa/", app.Name, app.Name),
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: sourceCompletion,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		plan, err := planRelocations(args[0], args[1])
+		if err != nil {
+			return err
+		}
+
+		res := result{Action: "copied", Secrets: []string{}, Sources: []string{}}
+
+		for _, r := range plan {
+			if _, err := secrets.CopySecret(env.Instance.SecretsPath(), r.secret, r.newName); err != nil {
+				return err
+			}
+
+			res.Secrets = append(res.Secrets, r.newName)
+			res.Sources = append(res.Sources, r.secret.Name())
+
+			if !machineReadable() {
+				fmt.Println(pp.Completef("Copied %s to %s", r.secret.Name(), pp.Green(r.newName)))
+			}
+		}
+
+		return printResult(res)
+	},
+}
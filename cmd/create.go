@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"errors"
 	"fmt"
 	"path/filepath"
 
@@ -38,7 +37,33 @@ func init() {
 		"(optional) Whether to delete the plain text secret file after encryption",
 	)
 
+	createCmd.Flags().StringVar(
+		&description,
+		"description",
+		"",
+		"(optional) A description of the secret",
+	)
+	createCmd.Flags().StringVar(
+		&owner,
+		"owner",
+		"",
+		"(optional) The owner of the secret",
+	)
+	createCmd.Flags().StringArrayVar(
+		&tags,
+		"tag",
+		nil,
+		"(optional) A tag to label the secret with. Can be repeated to add multiple tags",
+	)
+
+	addExpiryFlags(createCmd)
+	addFieldFlag(createCmd)
+	addRawFlag(createCmd, "(optional) Whether to store the secret exactly as given, e.g. for binary files or PEM files that need a trailing newline. By default leading and trailing whitespace is trimmed")
+	addGenerateFlags(createCmd)
+	addInputFlags(createCmd)
+
 	createCmd.MarkFlagFilename("file")
+	createCmd.RegisterFlagCompletionFunc("tag", tagFlagCompletion)
 
 	rootCmd.AddCommand(createCmd)
 }
@@ -46,14 +71,18 @@ func init() {
 var createCmd = &cobra.Command{
 	Use:     "create",
 	Short:   "Create a secret",
-	Long:    "Create a secret.\n\nWhen using the flags -s/--secret and -f/--file, the secret will be read from the specified file and encrypted.\n\nIf no flags are provided, an interactive prompt will be used to enter the secret and its name.",
-	Example: fmt.Sprintf("  %s create\n  %s create -s my_secret -f /path/to/secret.txt", app.Name, app.Name),
+	Long:    "Create a secret.\n\nWhen using the flags -s/--secret and -f/--file, the secret will be read from the specified file and encrypted.\n\nWhen using the flags -s/--secret and --stdin or --fd, the secret will be read from stdin or the file descriptor and encrypted, so it is never written to a file.\n\nWhen using the flag --generate, a password, passphrase or token is generated and encrypted without ever being shown. See the generate command for the flags configuring it.\n\nWhen using the flag --field, a structured secret holding named fields is created instead of a single value. Fields given without a value are prompted for.\n\nIf no flags are provided, an interactive prompt will be used to enter the secret and its name. Prompts are refused when stdin is not a terminal.",
+	Example: fmt.Sprintf("  %s create\n  %s create -s my_secret -f /path/to/secret.txt\n  %s create -s my_secret -f /path/to/secret.txt --description \"Deploy token\" --owner ops --tag prod --tag ci\n  %s create -s db --field host=db.example.com --field port=5432 --field user=app --field password\n  %s create -s tls-key -f ./server.key --raw\n  %s create -s api/token --generate --type urlsafe\n  some-tool | %s create -s ci/token --stdin", app.Name, app.Name, app.Name, app.Name, app.Name, app.Name, app.Name),
 	PreRunE: validateUpdateCreateFlags,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		var err error
 		var newSecret *secrets.Secret
 
-		if secretName != "" && secretFile != "" {
+		if len(secretFields) > 0 {
+			return createFieldsSecret(cmd)
+		}
+
+		if secretName != "" && (secretFile != "" || generate || readsInput(cmd)) {
 			secretFilePath := filepath.Join(env.Instance.SecretsPath(), secretName+env.Instance.SecretExt())
 
 			newSecret, err := secrets.NewSecret(env.Instance.KeyPath(), secretName, secretFilePath)
@@ -62,14 +91,38 @@ var createCmd = &cobra.Command{
 			}
 
 			if secretPtr := secrets.FindSecretByName(newSecret.Name(), secretFiles); secretPtr != nil {
-				return errors.New("secret with that name already exists")
+				return fmt.Errorf("secret with that name %w", secrets.ErrExists)
 			}
 
-			if err := newSecret.EncryptFromFile(secretFile, cleanupFile); err != nil {
-				return fmt.Errorf("could not encrypt secret from file '%s': %w", secretFile, err)
+			switch {
+			case generate:
+				if err := encryptGenerated(newSecret, rawSecret); err != nil {
+					return err
+				}
+			case readsInput(cmd):
+				secret, err := readSecretInput()
+				if err != nil {
+					return err
+				}
+
+				if err := newSecret.Encrypt(secret, rawSecret); err != nil {
+					return fmt.Errorf("could not encrypt secret: %w", err)
+				}
+			default:
+				if err := newSecret.EncryptFromFile(secretFile, cleanupFile, rawSecret); err != nil {
+					return fmt.Errorf("could not encrypt secret from file '%s': %w", secretFile, err)
+				}
 			}
 
-			return nil
+			if err := applyMetadataFlags(cmd, newSecret); err != nil {
+				return err
+			}
+
+			return printResult(result{Action: "created", Secrets: []string{newSecret.Name()}})
+		}
+
+		if err := requireTerminal(); err != nil {
+			return err
 		}
 
 		header.PrintHeader()
@@ -87,13 +140,13 @@ var createCmd = &cobra.Command{
 		} else {
 			secretPtr := secrets.FindSecretByName(secretName, secretFiles)
 			if secretPtr != nil {
-				return fmt.Errorf("secret %s already exists", pp.Red(secretName))
+				return fmt.Errorf("secret %s %w", pp.Red(secretName), secrets.ErrExists)
 			}
 		}
 
 		var secret []byte
 
-		if secretFile == "" {
+		if secretFile == "" && !generate {
 			promptSecret := pardon.NewPassword(&secret).
 				Title("Enter a secret to secure:")
 
@@ -109,16 +162,24 @@ var createCmd = &cobra.Command{
 			return fmt.Errorf("could not create secret: %w", err)
 		}
 
-		if secretFile == "" {
-			if err := newSecret.Encrypt(secret); err != nil {
+		if generate {
+			if err := encryptGenerated(newSecret, rawSecret); err != nil {
+				return err
+			}
+		} else if secretFile == "" {
+			if err := newSecret.Encrypt(secret, rawSecret); err != nil {
 				return fmt.Errorf("could not encrypt secret: %w", err)
 			}
 		} else {
-			if err := newSecret.EncryptFromFile(secretFile, cleanupFile); err != nil {
+			if err := newSecret.EncryptFromFile(secretFile, cleanupFile, rawSecret); err != nil {
 				return fmt.Errorf("could not encrypt secret from file '%s': %w", secretFile, err)
 			}
 		}
 
+		if err := applyMetadataFlags(cmd, newSecret); err != nil {
+			return err
+		}
+
 		fmt.Println(pp.Complete("Secret encrypted and saved"))
 		fmt.Println()
 		fmt.Printf("You can run the commmand %s to view the unencrypted secret\n", pp.Greenf("%s view -s %s", env.Instance.ExeCmd(), secretName))
@@ -126,3 +187,58 @@ var createCmd = &cobra.Command{
 		return nil
 	},
 }
+
+// createFieldsSecret creates a structured secret from the fields provided through the
+// --field flag. Prompts are only shown for a missing name or field values.
+func createFieldsSecret(cmd *cobra.Command) error {
+	interactive := secretName == "" || fieldsNeedPrompt()
+
+	if interactive {
+		if err := requireTerminal(); err != nil {
+			return err
+		}
+
+		header.PrintHeader()
+	}
+
+	if secretName == "" {
+		promptQuestion := pardon.NewQuestion(&secretName).
+			Title("Enter a name for the secret:").
+			Validate(validateSecretName)
+
+		if err := promptQuestion.Ask(); err != nil {
+			return err
+		}
+
+		fmt.Println()
+	} else if secretPtr := secrets.FindSecretByName(secretName, secretFiles); secretPtr != nil {
+		return fmt.Errorf("secret with that name %w", secrets.ErrExists)
+	}
+
+	newSecret, err := secrets.NewSecret(env.Instance.KeyPath(), secretName, filepath.Join(env.Instance.SecretsPath(), secretName+env.Instance.SecretExt()))
+	if err != nil {
+		return fmt.Errorf("could not create secret: %w", err)
+	}
+
+	values, err := readFieldFlags()
+	if err != nil {
+		return err
+	}
+
+	if err := newSecret.EncryptFields(values); err != nil {
+		return fmt.Errorf("could not encrypt secret: %w", err)
+	}
+
+	if err := applyMetadataFlags(cmd, newSecret); err != nil {
+		return err
+	}
+
+	if interactive {
+		fmt.Println(pp.Complete("Secret encrypted and saved"))
+		fmt.Println()
+		fmt.Printf("You can run the commmand %s to view a field of the unencrypted secret\n", pp.Greenf("%s view -s %s --field <name>", env.Instance.ExeCmd(), secretName))
+		return nil
+	}
+
+	return printResult(result{Action: "created", Secrets: []string{newSecret.Name()}})
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/engmtcdrm/mellon/env"
@@ -12,7 +13,9 @@ import (
 
 var testBinary string
 
-// TestMain builds the CLI binary once for all tests and cleans up after.
+// TestMain builds the CLI binary once for all tests and cleans up after. The tests and
+// the binary run with a temporary home directory, so they never touch the secrets,
+// key, snapshots or trash of the user running them.
 func TestMain(m *testing.M) {
 	testBinary = filepath.Join(os.TempDir(), "mellon-test-bin")
 	projectRoot, err := filepath.Abs(filepath.Join(".."))
@@ -28,10 +31,20 @@ func TestMain(m *testing.M) {
 		panic("failed to build test binary: " + err.Error() + "\n" + string(out))
 	}
 
+	// Set after building, as the Go build and module caches are found through the home directory
+	testHome, err := os.MkdirTemp("", "mellon-test-home-")
+	if err != nil {
+		panic("failed to create test home directory: " + err.Error())
+	}
+	os.Setenv("HOME", testHome)
+	os.Setenv("USERPROFILE", testHome)
+	env.SetHome(testHome)
+
 	code := m.Run()
 
-	// Clean up the test binary after tests
+	// Clean up the test binary and home directory after tests
 	os.Remove(testBinary)
+	os.RemoveAll(testHome)
 	os.Exit(code)
 }
 
@@ -420,3 +433,127 @@ func TestCreateCommand_ForceOverwrite(t *testing.T) {
 		t.Logf("Force flag appears to be implemented")
 	}
 }
+
+// TestCreateCommand_Stdin tests creating secrets from stdin and a file descriptor.
+func TestCreateCommand_Stdin(t *testing.T) {
+	env.Init()
+
+	secretName := "teststdin"
+
+	cmd := exec.Command(testBinary, "create", "--secret", secretName, "--stdin")
+	cmd.Stdin = strings.NewReader("  from stdin\n")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("failed to create secret from stdin: %v, output: %s", err, output)
+	}
+	defer exec.Command(testBinary, "delete", "--secret", secretName, "--force").Run()
+
+	output, err := exec.Command(testBinary, "view", "--secret", secretName).Output()
+	if err != nil || string(output) != "from stdin" {
+		t.Errorf("expected the trimmed secret read from stdin, got: %q, error: %v", output, err)
+	}
+
+	fdName := "teststdinfd"
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	w.WriteString("from fd\n")
+	w.Close()
+
+	cmd = exec.Command(testBinary, "create", "--secret", fdName, "--fd", "3", "--raw")
+	cmd.ExtraFiles = []*os.File{r}
+	output, err = cmd.CombinedOutput()
+	r.Close()
+	if err != nil {
+		t.Fatalf("failed to create secret from file descriptor: %v, output: %s", err, output)
+	}
+	defer exec.Command(testBinary, "delete", "--secret", fdName, "--force").Run()
+
+	output, err = exec.Command(testBinary, "view", "--secret", fdName).Output()
+	if err != nil || string(output) != "from fd\n" {
+		t.Errorf("expected the exact secret read from the file descriptor, got: %q, error: %v", output, err)
+	}
+
+	cmd = exec.Command(testBinary, "create", "--secret", "teststdinempty", "--stdin")
+	cmd.Stdin = strings.NewReader("\n")
+	if output, err := cmd.CombinedOutput(); err == nil {
+		exec.Command(testBinary, "delete", "--secret", "teststdinempty", "--force").Run()
+		t.Errorf("expected an empty secret to fail, got: %s", output)
+	}
+
+	if output, err := exec.Command(testBinary, "create", "--stdin").CombinedOutput(); err == nil {
+		t.Errorf("expected --stdin without -s/--secret to fail, got: %s", output)
+	}
+}
+
+// TestCreateCommand_FieldValues tests that values of fields are read from files or
+// stdin, and never taken from the command line.
+func TestCreateCommand_FieldValues(t *testing.T) {
+	env.Init()
+
+	secretName := "testfieldvalues"
+
+	output, err := exec.Command(testBinary, "create", "--secret", secretName, "--field", "password=inline").CombinedOutput()
+	if err == nil {
+		exec.Command(testBinary, "delete", "--secret", secretName, "--force").Run()
+		t.Fatalf("expected a value given on the command line to fail, got: %s", output)
+	}
+
+	if !strings.Contains(string(output), "password=@file") {
+		t.Errorf("expected the error to suggest reading the value from a file, got: %s", output)
+	}
+
+	cmd := exec.Command(testBinary, "create", "--secret", secretName, "--field", "password=-", "--field", fieldFile(t, "user", "app"))
+	cmd.Stdin = strings.NewReader("from stdin\n")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("failed to create secret with a field read from stdin: %v, output: %s", err, output)
+	}
+	defer exec.Command(testBinary, "delete", "--secret", secretName, "--force").Run()
+
+	for field, expected := range map[string]string{"password": "from stdin", "user": "app"} {
+		output, err := exec.Command(testBinary, "view", "--secret", secretName, "--field", field).Output()
+		if err != nil || string(output) != expected {
+			t.Errorf("expected field %s to be %q, got: %q, error: %v", field, expected, output, err)
+		}
+	}
+
+	if output, err := exec.Command(testBinary, "update", "--secret", secretName, "--field", "password=-", "--field", "user=-").CombinedOutput(); err == nil {
+		t.Errorf("expected reading two fields from stdin to fail, got: %s", output)
+	}
+}
+
+// fieldFile writes value to a temporary file and returns the --field flag value that
+// reads field name from it.
+func fieldFile(t *testing.T, name string, value string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(value), 0600); err != nil {
+		t.Fatalf("failed to write value of field %s: %v", name, err)
+	}
+
+	return name + "=@" + path
+}
+
+// TestCreateCommand_NotTerminal tests that prompts are refused when stdin is not a terminal.
+func TestCreateCommand_NotTerminal(t *testing.T) {
+	env.Init()
+
+	for _, args := range [][]string{
+		{"create"},
+		{"create", "--secret", "testnotterminal"},
+		{"create", "--secret", "testnotterminal", "--field", "password"},
+	} {
+		cmd := exec.Command(testBinary, args...)
+		cmd.Stdin = strings.NewReader("value\n")
+		output, err := cmd.CombinedOutput()
+		if err == nil {
+			exec.Command(testBinary, "delete", "--secret", "testnotterminal", "--force").Run()
+			t.Errorf("expected %v to fail, got: %s", args, output)
+		}
+		if !strings.Contains(string(output), "stdin is not a terminal") {
+			t.Errorf("expected %v to refuse to prompt, got: %s", args, output)
+		}
+	}
+}
@@ -1,13 +1,14 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/engmtcdrm/go-pardon"
 	pp "github.com/engmtcdrm/go-prettyprint"
 	"github.com/engmtcdrm/mellon/app"
 	"github.com/engmtcdrm/mellon/env"
-	"github.com/engmtcdrm/mellon/header"
 	"github.com/engmtcdrm/mellon/secrets"
 	"github.com/engmtcdrm/mellon/secrets/prompts"
 	"github.com/spf13/cobra"
@@ -37,30 +38,147 @@ func init() {
 		"(optional) Whether to delete all secrets",
 	)
 
+	deleteCmd.Flags().BoolVarP(
+		&recursive,
+		"recursive",
+		"r",
+		false,
+		"(optional) Whether to delete every secret in the namespace provided, including nested namespaces",
+	)
+	deleteCmd.Flags().BoolVar(
+		&purgeDelete,
+		"purge",
+		false,
+		"(optional) Whether to delete the secrets permanently instead of moving them to the trash, also shredding them from the snapshots",
+	)
+
+	addTagFilterFlags(deleteCmd)
+
 	deleteCmd.MarkFlagsMutuallyExclusive("secret", "all")
+	deleteCmd.MarkFlagsMutuallyExclusive("secret", "recursive")
+	deleteCmd.MarkFlagsMutuallyExclusive("all", "recursive")
+	deleteCmd.MarkFlagsMutuallyExclusive("secret", "tag")
+	deleteCmd.MarkFlagsMutuallyExclusive("secret", "not-tag")
 	deleteCmd.RegisterFlagCompletionFunc("secret", secretFlagCompletion)
 
 	rootCmd.AddCommand(deleteCmd)
 }
 
+func validateDeleteFlags(cmd *cobra.Command, args []string) error {
+	if recursive && len(args) == 0 {
+		return errors.New("flag -r/--recursive requires a namespace, e.g. prod/")
+	}
+
+	if !recursive && len(args) > 0 {
+		return fmt.Errorf("use flag -r/--recursive to delete every secret in namespace '%s'", args[0])
+	}
+
+	return nil
+}
+
+// snapshotBeforeDelete takes a snapshot before secrets are moved to the trash. No
+// snapshot is taken with --purge, as purged secrets must not be recoverable.
+func snapshotBeforeDelete() error {
+	if purgeDelete {
+		return nil
+	}
+
+	return takeSnapshot("delete")
+}
+
+// removeSecret moves the secret to the trash, or deletes it permanently with --purge,
+// shredding it from the snapshots as well.
+func removeSecret(secret secrets.Secret) error {
+	if purgeDelete {
+		return secrets.PurgeSecret(env.Instance.SecretsPath(), secret)
+	}
+
+	_, err := secrets.TrashSecret(env.Instance.SecretsPath(), secret)
+	return err
+}
+
+// deleteAction returns the action reported when secrets are deleted.
+func deleteAction() string {
+	if purgeDelete {
+		return "purged"
+	}
+
+	return "trashed"
+}
+
+// deleteWarning returns the warning shown when confirming a deletion.
+func deleteWarning() string {
+	if purgeDelete {
+		return pp.Red("There is no going back.")
+	}
+
+	return pp.Yellow(fmt.Sprintf("Deleted secrets can be restored from the trash with %s restore -s <name>.", env.Instance.ExeCmd()))
+}
+
 var deleteCmd = &cobra.Command{
-	Use:     "delete",
-	Short:   "Delete a secret",
-	Long:    "Delete a secret",
-	Example: fmt.Sprintf("  %s delete", app.Name),
+	Use:               "delete [namespace/]",
+	Short:             "Delete a secret",
+	Long:              "Delete a secret.\n\nDeleted secrets are moved to the trash, from where they can be restored with the restore command until the trash is emptied. Use --purge to delete them permanently: no snapshot is taken, and the copies of them kept in earlier snapshots are shredded, so they cannot be restored.\n\nWith the flag -r/--recursive, every secret in the namespace provided is deleted.",
+	Example:           fmt.Sprintf("  %s delete\n  %s delete -s my_secret\n  %s delete --tag temp\n  %s delete --recursive prod/\n  %s delete --all\n  %s delete -s my_secret --force --purge", app.Name, app.Name, app.Name, app.Name, app.Name, app.Name),
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: namespaceCompletion,
+	PreRunE:           validateDeleteFlags,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		var selectedSecret secrets.Secret
 
 		if !forceDelete {
-			header.PrintHeader()
+			if err := promptHeader(); err != nil {
+				return err
+			}
 		}
 
-		if deleteAll {
+		filter := tagFilter()
+
+		if deleteAll || !filter.IsEmpty() || recursive {
+			available := secretFiles
+			var selection []string
+
+			if recursive {
+				namespace := secrets.NormalizeNamespace(args[0])
+				available = secrets.FilterNamespace(secretFiles, namespace)
+				selection = append(selection, "in namespace "+namespace)
+			}
+
+			if !filter.IsEmpty() {
+				selection = append(selection, filter.String())
+			}
+
+			targets, err := secrets.FilterSecrets(available, filter)
+			if err != nil {
+				return err
+			}
+
+			what := "ALL secrets"
+			if len(selection) > 0 {
+				what = fmt.Sprintf("all %d secret(s) %s", len(targets), strings.Join(selection, " "))
+
+				if len(targets) == 0 {
+					if !forceDelete {
+						fmt.Println(pp.Failf("No secrets %s found to delete", strings.Join(selection, " ")))
+					}
+					return printResult(result{Action: deleteAction(), Secrets: []string{}})
+				}
+			}
+
 			finalDelete := confirmationWord
 			if !forceDelete {
+				if len(selection) > 0 {
+					fmt.Println(pp.Info("The following secrets will be deleted"))
+					fmt.Println()
+					for _, secret := range targets {
+						fmt.Printf("  - %s\n", pp.Red(secret.Name()))
+					}
+					fmt.Println()
+				}
+
 				confirmDelete := false
 				promptConfirm2 := pardon.NewConfirm(&confirmDelete).
-					Title(fmt.Sprintf("Are you sure you want to delete ALL secrets? %s", pp.Red("There is no going back.")))
+					Title(fmt.Sprintf("Are you sure you want to delete %s? %s", what, deleteWarning()))
 
 				if err := promptConfirm2.Ask(); err != nil {
 					return err
@@ -68,7 +186,7 @@ var deleteCmd = &cobra.Command{
 
 				if !confirmDelete {
 					fmt.Println()
-					fmt.Println(pp.Fail("Aborted deleting all secrets"))
+					fmt.Println(pp.Fail("Aborted deleting secrets"))
 					return nil
 				}
 
@@ -86,17 +204,23 @@ var deleteCmd = &cobra.Command{
 			}
 
 			if finalDelete == confirmationWord {
-				for _, secret := range secretFiles {
-					if err := secrets.RemoveSecret(env.Instance.SecretsPath(), secret); err != nil {
+				if err := snapshotBeforeDelete(); err != nil {
+					return err
+				}
+
+				for _, secret := range targets {
+					if err := removeSecret(secret); err != nil {
 						return fmt.Errorf("could not remove secret '%s': %w", secret.Name(), err)
 					}
 				}
 
 				if !forceDelete {
-					fmt.Println(pp.Complete("All secrets deleted successfully"))
+					fmt.Println(pp.Completef("Deleted %d secret(s) successfully", len(targets)))
 				}
+
+				return printResult(result{Action: deleteAction(), Secrets: secretNames(targets)})
 			} else {
-				fmt.Println(pp.Fail("Aborted deleting all secrets"))
+				fmt.Println(pp.Fail("Aborted deleting secrets"))
 			}
 
 			return nil
@@ -105,7 +229,7 @@ var deleteCmd = &cobra.Command{
 		if secretName != "" {
 			secretPtr := secrets.FindSecretByName(secretName, secretFiles)
 			if secretPtr == nil {
-				return fmt.Errorf("could not delete secret '%s': does not exist", secretName)
+				return fmt.Errorf("could not delete secret '%s': %w", secretName, secrets.ErrNotFound)
 			}
 			selectedSecret = *secretPtr
 
@@ -113,7 +237,7 @@ var deleteCmd = &cobra.Command{
 			if !forceDelete {
 				confirmDelete = false
 				promptConfirm := pardon.NewConfirm(&confirmDelete).
-					Title(fmt.Sprintf("Are you sure you want to delete %s?", pp.Red(secretName)))
+					Title(fmt.Sprintf("Are you sure you want to delete %s? %s", pp.Red(secretName), deleteWarning()))
 
 				if err := promptConfirm.Ask(); err != nil {
 					return err
@@ -123,13 +247,19 @@ var deleteCmd = &cobra.Command{
 			}
 
 			if confirmDelete {
-				if err := secrets.RemoveSecret(env.Instance.SecretsPath(), selectedSecret); err != nil {
+				if err := snapshotBeforeDelete(); err != nil {
+					return err
+				}
+
+				if err := removeSecret(selectedSecret); err != nil {
 					return fmt.Errorf("could not remove secret '%s': %w", selectedSecret.Name(), err)
 				}
 
 				if !forceDelete {
 					fmt.Println(pp.Complete("Secret deleted successfully"))
 				}
+
+				return printResult(result{Action: deleteAction(), Secrets: []string{selectedSecret.Name()}})
 			} else {
 				fmt.Println(pp.Fail("Aborted deleting secret"))
 			}
@@ -137,9 +267,11 @@ var deleteCmd = &cobra.Command{
 			return nil
 		}
 
-		header.PrintHeader()
+		if err := promptHeader(); err != nil {
+			return err
+		}
 
-		options, err := prompts.GetSecretOptions(secretFiles, "delete", env.Instance.ExeCmd())
+		options, err := prompts.GetSecretOptions(secretFiles, filter, "delete", env.Instance.ExeCmd())
 		if err != nil {
 			return err
 		}
@@ -158,7 +290,7 @@ var deleteCmd = &cobra.Command{
 
 			confirmDelete = false
 			promptConfirm := pardon.NewConfirm(&confirmDelete).
-				Title(fmt.Sprintf("Are you sure you want to delete %s?", pp.Red(selectedSecret.Name())))
+				Title(fmt.Sprintf("Are you sure you want to delete %s? %s", pp.Red(selectedSecret.Name()), deleteWarning()))
 
 			if err := promptConfirm.Ask(); err != nil {
 				return err
@@ -168,7 +300,11 @@ var deleteCmd = &cobra.Command{
 		fmt.Println()
 
 		if confirmDelete {
-			if err := secrets.RemoveSecret(env.Instance.SecretsPath(), selectedSecret); err != nil {
+			if err := snapshotBeforeDelete(); err != nil {
+				return err
+			}
+
+			if err := removeSecret(selectedSecret); err != nil {
 				return fmt.Errorf("could not remove secret '%s': %w", selectedSecret.Name(), err)
 			}
 
@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/engmtcdrm/mellon/env"
@@ -370,3 +371,88 @@ func TestDeleteCommand_EdgeCases(t *testing.T) {
 	// Test edge case: only force flag - should enter interactive mode
 	t.Skip("Skipping interactive test: delete with only force flag")
 }
+
+// TestDeleteCommand_TagFilter tests deleting every secret with a tag.
+func TestDeleteCommand_TagFilter(t *testing.T) {
+	env.Init()
+
+	secretFile := filepath.Join(t.TempDir(), "secret.txt")
+
+	if err := os.WriteFile(secretFile, []byte("tagsecret"), 0644); err != nil {
+		t.Fatalf("failed to write secret file: %v", err)
+	}
+
+	tagged := map[string][]string{
+		"testdeletetag1": {"--tag", "deletetemp"},
+		"testdeletetag2": {"--tag", "deletetemp", "--tag", "deletekeep"},
+		"testdeletetag3": {},
+	}
+
+	for name, tagArgs := range tagged {
+		args := append([]string{"create", "--secret", name, "--file", secretFile}, tagArgs...)
+		if output, err := exec.Command(testBinary, args...).CombinedOutput(); err != nil {
+			t.Fatalf("failed to create secret '%s': %v, output: %s", name, err, output)
+		}
+		defer exec.Command(testBinary, "delete", "--secret", name, "--force").Run()
+	}
+
+	// Tag filters cannot be combined with a single secret
+	cmd := exec.Command(testBinary, "delete", "--secret", "testdeletetag1", "--tag", "deletetemp", "--force")
+	if _, err := cmd.CombinedOutput(); err == nil {
+		t.Errorf("expected error for mutually exclusive flags --secret and --tag, got none")
+	}
+
+	cmd = exec.Command(testBinary, "delete", "--tag", "deletetemp", "--not-tag", "deletekeep", "--force")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("expected success deleting by tag, got error: %v, output: %s", err, output)
+	}
+
+	for name, shouldExist := range map[string]bool{"testdeletetag1": false, "testdeletetag2": true, "testdeletetag3": true} {
+		_, err := os.Stat(filepath.Join(env.Instance.SecretsPath(), name+env.Instance.SecretExt()))
+		if shouldExist && err != nil {
+			t.Errorf("expected secret '%s' to still exist", name)
+		}
+		if !shouldExist && !os.IsNotExist(err) {
+			t.Errorf("expected secret '%s' to be deleted", name)
+		}
+	}
+}
+
+// TestDeleteCommand_Recursive tests deleting every secret in a namespace.
+func TestDeleteCommand_Recursive(t *testing.T) {
+	env.Init()
+
+	secretFile := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(secretFile, []byte("recursivecontent"), 0644); err != nil {
+		t.Fatalf("failed to write secret file: %v", err)
+	}
+
+	names := []string{"testdeletens/deep/nested/a", "testdeletens/b", "testdeletenskeep"}
+	for _, name := range names {
+		if output, err := exec.Command(testBinary, "create", "--secret", name, "--file", secretFile).CombinedOutput(); err != nil {
+			t.Fatalf("failed to create secret '%s': %v, output: %s", name, err, output)
+		}
+		defer exec.Command(testBinary, "delete", "--secret", name, "--force").Run()
+	}
+
+	if output, err := exec.Command(testBinary, "delete", "testdeletens/", "--force").CombinedOutput(); err == nil {
+		t.Errorf("expected error deleting a namespace without --recursive, got output: %s", output)
+	}
+
+	if output, err := exec.Command(testBinary, "delete", "--recursive", "testdeletens/", "--force").CombinedOutput(); err != nil {
+		t.Fatalf("failed to delete namespace: %v, output: %s", err, output)
+	}
+
+	if _, err := os.Stat(filepath.Join(env.Instance.SecretsPath(), "testdeletens")); !os.IsNotExist(err) {
+		t.Errorf("expected namespace directory to be removed, got: %v", err)
+	}
+
+	output, err := exec.Command(testBinary, "list", "--print").Output()
+	if err != nil {
+		t.Fatalf("failed to list secrets: %v", err)
+	}
+
+	if strings.Contains(string(output), "testdeletens/") || !strings.Contains(string(output), "testdeletenskeep") {
+		t.Errorf("expected only the namespace to be deleted, got: %s", output)
+	}
+}
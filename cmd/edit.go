@@ -0,0 +1,245 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/engmtcdrm/go-pardon"
+	pp "github.com/engmtcdrm/go-prettyprint"
+	"github.com/engmtcdrm/mellon/app"
+	"github.com/engmtcdrm/mellon/env"
+	"github.com/engmtcdrm/mellon/secrets"
+	"github.com/engmtcdrm/mellon/secrets/prompts"
+)
+
+func init() {
+	editCmd.Flags().StringVarP(
+		&secretName,
+		"secret",
+		"s",
+		"",
+		"(optional) The name of the secret to edit. If this flag is not provided, you will be prompted to select a secret to edit",
+	)
+	addRawFlag(editCmd, "(optional) Whether to store the edited secret exactly as saved, including the trailing newline most editors add. Defaults to how the secret was stored before")
+	addAllowExpiredFlag(editCmd, "(optional) Whether to allow editing a secret that has expired")
+
+	editCmd.RegisterFlagCompletionFunc("secret", secretFlagCompletion)
+
+	rootCmd.AddCommand(editCmd)
+}
+
+// editorCommand returns the command line of the editor to edit secrets with, from
+// $VISUAL or $EDITOR.
+func editorCommand() []string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if args := strings.Fields(os.Getenv(name)); len(args) > 0 {
+			return args
+		}
+	}
+
+	if runtime.GOOS == "windows" {
+		return []string{"notepad"}
+	}
+
+	return []string{"vi"}
+}
+
+// runEditor opens the file at path in the editor and waits for it to exit. Signals
+// received by mellon, e.g. Ctrl-C, are passed on to the editor instead of stopping
+// mellon, so the file is always removed afterwards. An error is returned if the editor
+// fails or is killed by a signal.
+func runEditor(path string) error {
+	args := append(editorCommand(), path)
+
+	editor := exec.Command(args[0], args[1:]...)
+	editor.Stdin = os.Stdin
+	editor.Stdout = os.Stdout
+	editor.Stderr = os.Stderr
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, forwardedSignals...)
+	defer signal.Stop(signals)
+
+	if err := editor.Start(); err != nil {
+		return fmt.Errorf("could not start editor '%s': %w\n\nSet %s or %s to the editor to use", args[0], err, pp.Green("$VISUAL"), pp.Green("$EDITOR"))
+	}
+
+	go func() {
+		for sig := range signals {
+			editor.Process.Signal(sig)
+		}
+	}()
+
+	err := editor.Wait()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return fmt.Errorf("editor '%s' failed with %s, the secret was not changed", args[0], exitErr.ProcessState)
+	}
+
+	return err
+}
+
+// confirmEditOnDisk warns that no memory backed directory is available, so the secret
+// would be decrypted into a file on disk, and asks whether to edit it anyway.
+func confirmEditOnDisk() (bool, error) {
+	if err := requireInteractive(); err != nil {
+		return false, err
+	}
+
+	if !stdinIsTerminal() {
+		return false, fmt.Errorf("%w as stdin is not a terminal: no memory backed directory was found to edit the secret in", errPromptRefused)
+	}
+
+	fmt.Println(pp.Alertf(
+		"No memory backed directory was found, so the decrypted secret would be written to a file in %s, on disk. "+
+			"The file is shredded once the editor exits, but copies of it may remain on disk. "+
+			"Set %s to a directory on tmpfs to avoid this.",
+		os.TempDir(), pp.Green("$XDG_RUNTIME_DIR"),
+	))
+	fmt.Println()
+
+	confirm := false
+	promptConfirm := pardon.NewConfirm(&confirm).
+		Title("Do you want to edit the secret on disk anyway?")
+
+	if err := promptConfirm.Ask(); err != nil {
+		return false, err
+	}
+
+	fmt.Println()
+
+	return confirm, nil
+}
+
+var editCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Edit a secret in an editor",
+	Long: "Edit a secret in the editor set with $VISUAL or $EDITOR, e.g. a multi-line JSON key or PEM bundle.\n\n" +
+		"The secret is decrypted into a file only you can read, in a directory kept in memory such as $XDG_RUNTIME_DIR or /dev/shm. " +
+		"If neither is available, you are warned and asked before the secret is written to the system temp directory on disk instead. " +
+		"It is encrypted again only if it was changed, and the file is shredded once the editor exits, even if the editor fails or is interrupted.",
+	Example: fmt.Sprintf("  %s edit\n  %s edit -s gcp/service-account\n  VISUAL='code --wait' %s edit -s tls/bundle --raw", app.Name, app.Name, app.Name),
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var selectedSecret secrets.Secret
+
+		if secretName == "" {
+			if err := promptHeader(); err != nil {
+				return err
+			}
+
+			options, err := prompts.GetSecretOptions(secretFiles, secrets.TagFilter{}, "edit", env.Instance.ExeCmd())
+			if err != nil {
+				return err
+			}
+
+			promptSelect := pardon.NewSelect(&selectedSecret).
+				Title("What secret do you want to edit?").
+				Options(options...)
+
+			if err := promptSelect.Ask(); err != nil {
+				return err
+			}
+
+			fmt.Println()
+		} else {
+			secretPtr := secrets.FindSecretByName(secretName, secretFiles)
+			if secretPtr == nil {
+				return fmt.Errorf("could not edit secret '%s': %w", secretName, secrets.ErrNotFound)
+			}
+			selectedSecret = *secretPtr
+		}
+
+		if err := requireSingleValue(selectedSecret); err != nil {
+			return err
+		}
+
+		meta, err := selectedSecret.Metadata()
+		if err != nil {
+			return err
+		}
+		if meta.IsOTP() {
+			return fmt.Errorf("secret '%s' holds a one-time password and cannot be edited\n\nUse command %s to replace it", selectedSecret.Name(), pp.Greenf("%s otp add", env.Instance.ExeCmd()))
+		}
+
+		raw, err := rawMode(cmd, selectedSecret)
+		if err != nil {
+			return err
+		}
+
+		allowDisk := false
+		if _, err := env.MemTempDir(); errors.Is(err, env.ErrNoMemTempDir) {
+			if allowDisk, err = confirmEditOnDisk(); err != nil {
+				return err
+			}
+
+			if !allowDisk {
+				fmt.Println(pp.Fail("Aborted editing the secret"))
+				return nil
+			}
+		}
+
+		value, err := selectedSecret.Decrypt()
+		if err != nil {
+			return err
+		}
+		defer secrets.ClearSecret(&value)
+
+		path, err := secrets.WriteEditFile(selectedSecret.Name(), value, allowDisk)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := secrets.RemoveEditFile(path); err != nil {
+				fmt.Fprintln(os.Stderr, pp.Alertf("Could not remove the temporary file '%s', delete it by hand: %s", path, err))
+			}
+		}()
+
+		if err := runEditor(path); err != nil {
+			return err
+		}
+
+		edited, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("could not read edited secret: %w", err)
+		}
+
+		// Without --raw the value is trimmed when stored, so whitespace added by the
+		// editor alone is not a change
+		changed := !bytes.Equal(value, edited)
+		if !raw {
+			changed = !bytes.Equal(bytes.TrimSpace(value), bytes.TrimSpace(edited))
+		}
+
+		if !changed {
+			secrets.ClearSecret(&edited)
+
+			if machineReadable() {
+				return printOutput(result{Action: "unchanged", Secrets: []string{selectedSecret.Name()}})
+			}
+
+			fmt.Println(pp.Info("No changes made, the secret was not updated"))
+			return nil
+		}
+
+		if err := takeSnapshot("update"); err != nil {
+			secrets.ClearSecret(&edited)
+			return err
+		}
+
+		if err := selectedSecret.Encrypt(edited, raw); err != nil {
+			return fmt.Errorf("could not encrypt secret: %w", err)
+		}
+
+		return printComplete("Secret encrypted and saved", result{Action: "updated", Secrets: []string{selectedSecret.Name()}})
+	},
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/engmtcdrm/mellon/env"
+)
+
+// editCommand returns the edit command for the secret, run with the editor script and
+// with its temporary files in runtimeDir.
+func editCommand(secretName string, editor string, runtimeDir string) *exec.Cmd {
+	cmd := exec.Command(testBinary, "edit", "--secret", secretName)
+	cmd.Env = append(os.Environ(), "VISUAL=", "EDITOR="+editor, "XDG_RUNTIME_DIR="+runtimeDir)
+	return cmd
+}
+
+// writeEditor writes a shell script to use as the editor and returns its path.
+func writeEditor(t *testing.T, script string) string {
+	path := filepath.Join(t.TempDir(), "editor.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0o755); err != nil {
+		t.Fatalf("failed to write editor: %v", err)
+	}
+	return path
+}
+
+// memRuntimeDir returns a new directory on tmpfs to use as XDG_RUNTIME_DIR, skipping
+// the test when none is available.
+func memRuntimeDir(t *testing.T) string {
+	dir, err := os.MkdirTemp("/dev/shm", "mellon-test-")
+	if err != nil || !env.IsMemoryBacked(dir) {
+		t.Skip("/dev/shm is not available on tmpfs")
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	return dir
+}
+
+// TestEditCommand tests editing a secret in an editor, leaving it unchanged, and the
+// editor failing, checking the temporary file is always removed.
+func TestEditCommand(t *testing.T) {
+	env.Init()
+
+	secretName := "testedit"
+	runtimeDir := memRuntimeDir(t)
+	logDir := t.TempDir()
+
+	secretFile := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(secretFile, []byte("original value"), 0644); err != nil {
+		t.Fatalf("failed to write secret file: %v", err)
+	}
+
+	if output, err := exec.Command(testBinary, "create", "--secret", secretName, "--file", secretFile).CombinedOutput(); err != nil {
+		t.Fatalf("failed to create secret: %v, output: %s", err, output)
+	}
+	defer exec.Command(testBinary, "delete", "--secret", secretName, "--force").Run()
+
+	editor := writeEditor(t, `stat -c %a "$1" > "`+logDir+`/mode"; cp "$1" "`+logDir+`/before"; printf 'edited\nvalue\n' > "$1"`)
+
+	if output, err := editCommand(secretName, editor, runtimeDir).CombinedOutput(); err != nil {
+		t.Fatalf("failed to edit secret: %v, output: %s", err, output)
+	}
+
+	if mode, _ := os.ReadFile(filepath.Join(logDir, "mode")); strings.TrimSpace(string(mode)) != "600" {
+		t.Errorf("expected the temporary file to have mode 600, got: %s", mode)
+	}
+	if before, _ := os.ReadFile(filepath.Join(logDir, "before")); string(before) != "original value" {
+		t.Errorf("expected the editor to be given the decrypted secret, got: %q", before)
+	}
+
+	output, err := exec.Command(testBinary, "view", "--secret", secretName).Output()
+	if err != nil || string(output) != "edited\nvalue" {
+		t.Errorf("expected the edited secret, got: %q, error: %v", output, err)
+	}
+
+	// Saving the same value, even with a trailing newline, is not a change
+	output, err = editCommand(secretName, writeEditor(t, `printf 'edited\nvalue\n' > "$1"`), runtimeDir).CombinedOutput()
+	if err != nil || !strings.Contains(string(output), "No changes made") {
+		t.Errorf("expected no changes to be made, got: %s, error: %v", output, err)
+	}
+
+	historyOutput, err := exec.Command(testBinary, "history", "--secret", secretName, "--print").Output()
+	if err != nil || len(strings.Fields(string(historyOutput))) != 2 {
+		t.Errorf("expected a single previous version, got: %q, error: %v", historyOutput, err)
+	}
+
+	// A failing editor leaves the secret unchanged
+	if output, err := editCommand(secretName, writeEditor(t, `printf 'discarded' > "$1"; exit 3`), runtimeDir).CombinedOutput(); err == nil {
+		t.Errorf("expected a failing editor to fail the edit, got: %s", output)
+	}
+
+	output, err = exec.Command(testBinary, "view", "--secret", secretName).Output()
+	if err != nil || string(output) != "edited\nvalue" {
+		t.Errorf("expected the secret to be unchanged, got: %q, error: %v", output, err)
+	}
+
+	entries, err := os.ReadDir(runtimeDir)
+	if err != nil || len(entries) != 0 {
+		t.Errorf("expected no temporary files to be left behind, got: %v, error: %v", entries, err)
+	}
+
+	if output, err := editCommand("testeditmissing", editor, runtimeDir).CombinedOutput(); err == nil {
+		t.Errorf("expected editing a missing secret to fail, got: %s", output)
+	}
+
+	// A runtime directory on disk is not used, /dev/shm is used instead
+	diskDir := t.TempDir()
+	if !env.IsMemoryBacked(diskDir) {
+		editor = writeEditor(t, `printf '%s' "$1" > "`+logDir+`/path"; printf 'edited\nvalue\n' > "$1"`)
+		if output, err := editCommand(secretName, editor, diskDir).CombinedOutput(); err != nil {
+			t.Fatalf("failed to edit secret: %v, output: %s", err, output)
+		}
+
+		if path, _ := os.ReadFile(filepath.Join(logDir, "path")); !strings.HasPrefix(string(path), "/dev/shm/") {
+			t.Errorf("expected the secret to be edited in /dev/shm, got: %s", path)
+		}
+	}
+}
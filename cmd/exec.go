@@ -0,0 +1,326 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"regexp"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/engmtcdrm/mellon/app"
+	"github.com/engmtcdrm/mellon/env"
+	"github.com/engmtcdrm/mellon/secrets"
+)
+
+const reValidEnvVar = `^[A-Za-z_][A-Za-z0-9_]*$`
+
+func init() {
+	execCmd.Flags().StringArrayVar(
+		&envMappings,
+		"env",
+		nil,
+		"(optional) An environment variable to set from a secret as VAR=secret, or VAR=secret#field for a field of a structured secret. Can be repeated to set multiple variables",
+	)
+	execCmd.Flags().StringVar(
+		&envFile,
+		"env-file",
+		"",
+		"(optional) A file mapping environment variables to secrets, with one VAR=secret or VAR=secret#field per line. Blank lines and lines starting with # are ignored",
+	)
+
+	execCmd.Flags().BoolVar(
+		&resolveEnv,
+		"resolve-env",
+		false,
+		"(optional) Whether to replace inherited environment variables whose value is a secret reference, such as mellon://prod/db#password, with the value of the secret",
+	)
+	addAllowExpiredFlag(execCmd, "(optional) Whether to allow passing secrets that have expired to the command")
+
+	// Flags after the command belong to the command, not to exec
+	execCmd.Flags().SetInterspersed(false)
+	execCmd.MarkFlagFilename("env-file")
+	execCmd.RegisterFlagCompletionFunc("env", envFlagCompletion)
+
+	rootCmd.AddCommand(execCmd)
+}
+
+// envMapping maps an environment variable to the secret, and optionally the field of
+// a structured secret, holding its value.
+type envMapping struct {
+	name   string
+	secret string
+	field  string
+	source string // Where the mapping was declared, used in error messages
+}
+
+// parseEnvMapping parses a mapping given as VAR=secret or VAR=secret#field.
+func parseEnvMapping(s string, source string) (envMapping, error) {
+	name, ref, ok := strings.Cut(strings.TrimSpace(s), "=")
+	if !ok || ref == "" {
+		return envMapping{}, fmt.Errorf("%s: invalid mapping '%s'. Use VAR=secret or VAR=secret#field", source, s)
+	}
+
+	name = strings.TrimSpace(name)
+	if !regexp.MustCompile(reValidEnvVar).MatchString(name) {
+		return envMapping{}, fmt.Errorf("%s: invalid environment variable name '%s'", source, name)
+	}
+
+	secret, field, _ := strings.Cut(strings.TrimSpace(ref), "#")
+
+	return envMapping{name: name, secret: secret, field: field, source: source}, nil
+}
+
+// readEnvFile reads the mappings declared in a mapping file.
+func readEnvFile(path string) ([]envMapping, error) {
+	path, err := env.ExpandTilde(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read mapping file: %w", err)
+	}
+	defer f.Close()
+
+	var mappings []envMapping
+	var errs []error
+
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		m, err := parseEnvMapping(line, fmt.Sprintf("%s:%d", path, lineNum))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		mappings = append(mappings, m)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read mapping file: %w", err)
+	}
+
+	return mappings, errors.Join(errs...)
+}
+
+// resolveSecretValue decrypts the named secret, selecting a field when one is given.
+func resolveSecretValue(name string, field string) ([]byte, error) {
+	secretPtr := secrets.FindSecretByName(name, secretFiles)
+	if secretPtr == nil {
+		return nil, fmt.Errorf("secret '%s' %w", name, secrets.ErrNotFound)
+	}
+
+	value, err := secretPtr.Decrypt()
+	if err != nil {
+		return nil, err
+	}
+
+	if field == "" {
+		return value, nil
+	}
+
+	fieldValue, err := secrets.Field(value, field)
+	secrets.ClearSecret(&value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read field of secret '%s': %w", name, err)
+	}
+
+	return []byte(fieldValue), nil
+}
+
+// lastMappings keeps only the last mapping of each environment variable, so later
+// mappings override earlier ones without decrypting secrets that are not used.
+func lastMappings(mappings []envMapping) []envMapping {
+	last := map[string]int{}
+	for i, m := range mappings {
+		last[m.name] = i
+	}
+
+	var kept []envMapping
+	for i, m := range mappings {
+		if last[m.name] == i {
+			kept = append(kept, m)
+		}
+	}
+
+	return kept
+}
+
+// secretEnv decrypts the secrets of every mapping into VAR=value pairs. Every
+// mapping is tried so all problems are reported at once.
+func secretEnv(mappings []envMapping) ([]string, error) {
+	var pairs []string
+	var errs []error
+
+	for _, m := range mappings {
+		value, err := resolveSecretValue(m.secret, m.field)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s: %w", m.source, m.name, err))
+			continue
+		}
+
+		pairs = append(pairs, m.name+"="+string(value))
+		secrets.ClearSecret(&value)
+	}
+
+	return pairs, errors.Join(errs...)
+}
+
+// mergeEnv returns base with the variables in overrides added, replacing any
+// variables of the same name.
+func mergeEnv(base []string, overrides []string) []string {
+	names := map[string]bool{}
+	for _, pair := range overrides {
+		name, _, _ := strings.Cut(pair, "=")
+		names[name] = true
+	}
+
+	merged := make([]string, 0, len(base)+len(overrides))
+	for _, pair := range base {
+		name, _, _ := strings.Cut(pair, "=")
+		if !names[name] {
+			merged = append(merged, pair)
+		}
+	}
+
+	return append(merged, overrides...)
+}
+
+// runChild runs the command with the given environment, forwarding signals received
+// by mellon to it. It returns the exit code of the command, or 128 plus the signal
+// number if the command was killed by a signal, like shells do.
+func runChild(args []string, environ []string) (int, error) {
+	child := exec.Command(args[0], args[1:]...)
+	child.Env = environ
+	child.Stdin = os.Stdin
+	child.Stdout = os.Stdout
+	child.Stderr = os.Stderr
+
+	// Registered before the command starts, so a signal received while it starts is
+	// queued and forwarded instead of killing mellon and orphaning the command
+	signals := make(chan os.Signal, len(forwardedSignals))
+	signal.Notify(signals, forwardedSignals...)
+	defer signal.Stop(signals)
+
+	if err := child.Start(); err != nil {
+		return 0, fmt.Errorf("could not start command '%s': %w", args[0], err)
+	}
+
+	go func() {
+		for sig := range signals {
+			child.Process.Signal(sig)
+		}
+	}()
+
+	err := child.Wait()
+	if err == nil {
+		return 0, nil
+	}
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return 0, err
+	}
+
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal()), nil
+	}
+
+	return exitErr.ExitCode(), nil
+}
+
+// envFlagCompletion completes the secret part of VAR=secret mappings.
+func envFlagCompletion(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	name, _, ok := strings.Cut(toComplete, "=")
+	if !ok {
+		return nil, cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var completions []string
+	for _, secret := range secretFiles {
+		completions = append(completions, name+"="+secret.Name())
+	}
+
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+var execCmd = &cobra.Command{
+	Use:   "exec [flags] -- <command> [args...]",
+	Short: "Run a command with secrets as environment variables",
+	Long:  "Run a command with secrets as environment variables.\n\nThe secrets are only set in the environment of the command, so they do not show up in the process list or shell history. Signals are forwarded to the command and its exit code is passed through.\n\nWith --resolve-env, inherited environment variables whose value is a reference such as mellon://prod/db#password are replaced with the value of the secret.",
+	Example: fmt.Sprintf(
+		"  %s exec --env GITHUB_TOKEN=github-token -- ./deploy.sh\n  %s exec --env DB_PASS=prod/db#password -- psql\n  %s exec --env-file ./ci.env -- make release\n  DB_PASS=mellon://prod/db#password %s exec --resolve-env -- psql",
+		app.Name, app.Name, app.Name, app.Name,
+	),
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var mappings []envMapping
+		var errs []error
+
+		if envFile != "" {
+			fileMappings, err := readEnvFile(envFile)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			mappings = append(mappings, fileMappings...)
+		}
+
+		// Mappings given as flags take precedence over those in the mapping file
+		for _, s := range envMappings {
+			m, err := parseEnvMapping(s, "--env")
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			mappings = append(mappings, m)
+		}
+
+		if len(errs) > 0 {
+			return errors.Join(errs...)
+		}
+
+		environ := os.Environ()
+
+		if resolveEnv {
+			resolver := newReferenceResolver()
+			defer resolver.clear()
+
+			resolved, err := resolver.resolveEnv(environ)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			environ = resolved
+		}
+
+		pairs, err := secretEnv(lastMappings(mappings))
+		if err != nil {
+			errs = append(errs, err)
+		}
+
+		if len(errs) > 0 {
+			return fmt.Errorf("could not resolve secrets for command:\n%w", errors.Join(errs...))
+		}
+
+		code, err := runChild(args, mergeEnv(environ, pairs))
+		if err != nil {
+			return err
+		}
+
+		if code != 0 {
+			os.Exit(code)
+		}
+
+		return nil
+	},
+}
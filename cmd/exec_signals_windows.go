@@ -0,0 +1,10 @@
+//go:build windows
+
+package cmd
+
+import (
+	"os"
+)
+
+// forwardedSignals are the signals passed on to the command run by exec.
+var forwardedSignals = []os.Signal{os.Interrupt}
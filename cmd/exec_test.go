@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/engmtcdrm/mellon/env"
+)
+
+// TestExecCommand tests running a command with secrets as environment variables.
+func TestExecCommand(t *testing.T) {
+	env.Init()
+
+	tmpDir := t.TempDir()
+	secretFile := filepath.Join(tmpDir, "secret.txt")
+	if err := os.WriteFile(secretFile, []byte("exectoken"), 0644); err != nil {
+		t.Fatalf("failed to write secret file: %v", err)
+	}
+
+	if output, err := exec.Command(testBinary, "create", "--secret", "testexec/token", "--file", secretFile).CombinedOutput(); err != nil {
+		t.Fatalf("failed to create secret: %v, output: %s", err, output)
+	}
+	defer exec.Command(testBinary, "delete", "--secret", "testexec/token", "--force").Run()
+
+	if output, err := exec.Command(testBinary, "create", "--secret", "testexec/db", "--field", fieldFile(t, "password", "execpass")).CombinedOutput(); err != nil {
+		t.Fatalf("failed to create structured secret: %v, output: %s", err, output)
+	}
+	defer exec.Command(testBinary, "delete", "--secret", "testexec/db", "--force").Run()
+
+	mappingFile := filepath.Join(tmpDir, "ci.env")
+	if err := os.WriteFile(mappingFile, []byte("# CI secrets\nDB_PASS=testexec/db#password\n\nTOKEN=testexec/missing\n"), 0644); err != nil {
+		t.Fatalf("failed to write mapping file: %v", err)
+	}
+
+	// Flags given after the command belong to the command
+	cmd := exec.Command(testBinary, "exec", "--env", "TOKEN=testexec/token", "--env-file", mappingFile, "--", "sh", "-c", `printf '%s %s' "$TOKEN" "$DB_PASS"; exit 3`, "--env")
+	output, err := cmd.Output()
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+		t.Errorf("expected exit code 3 to be passed through, got: %v", err)
+	}
+
+	if string(output) != "exectoken execpass" {
+		t.Errorf("expected secrets in the environment of the command, got: %s", output)
+	}
+
+	// Every unknown secret is reported before anything runs
+	cmd = exec.Command(testBinary, "exec", "--env", "A=testexec/missing-a", "--env", "B=testexec/missing-b", "--", "sh", "-c", "echo ran")
+	output, err = cmd.CombinedOutput()
+	if err == nil || strings.Contains(string(output), "ran") {
+		t.Errorf("expected command not to run with unknown secrets, got: %s", output)
+	}
+
+	if !strings.Contains(string(output), "testexec/missing-a") || !strings.Contains(string(output), "testexec/missing-b") {
+		t.Errorf("expected all unknown secrets to be reported, got: %s", output)
+	}
+
+	for _, args := range [][]string{
+		{"exec", "--env", "1BAD=testexec/token", "--", "true"},
+		{"exec", "--env", "NOVALUE", "--", "true"},
+		{"exec", "--env", "TOKEN=testexec/token"},
+	} {
+		if output, err := exec.Command(testBinary, args...).CombinedOutput(); err == nil {
+			t.Errorf("expected error for %v, got output: %s", args, output)
+		}
+	}
+}
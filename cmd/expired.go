@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/spf13/cobra"
+
+	pp "github.com/engmtcdrm/go-prettyprint"
+	"github.com/engmtcdrm/mellon/app"
+	"github.com/engmtcdrm/mellon/header"
+	"github.com/engmtcdrm/mellon/secrets"
+)
+
+func init() {
+	expiredCmd.Flags().StringVarP(
+		&within,
+		"within",
+		"w",
+		"7d",
+		"(optional) Also list secrets expiring within this duration, e.g. 14d. Use 0s to only list expired secrets",
+	)
+	expiredCmd.Flags().BoolVarP(
+		&print,
+		"print",
+		"p",
+		false,
+		"(optional) Whether to print only the names of the secrets without additional information",
+	)
+
+	addTagFilterFlags(expiredCmd)
+
+	rootCmd.AddCommand(expiredCmd)
+}
+
+func validateExpiredFlags(cmd *cobra.Command, args []string) error {
+	if _, err := secrets.ParseDuration(within); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+var expiredCmd = &cobra.Command{
+	Use:     "expired",
+	Short:   "List expired and soon to expire secrets",
+	Long:    "List expired and soon to expire secrets.\n\nExits with a non-zero code if any secret has expired or expires within the given duration, so it can be used to gate CI jobs.",
+	Example: fmt.Sprintf("  %s expired\n  %s expired --within 30d\n  %s expired --within 0s --print", app.Name, app.Name, app.Name),
+	PreRunE: validateExpiredFlags,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		window, _ := secrets.ParseDuration(within)
+		now := time.Now()
+
+		entries, err := loadSecretEntries(secretFiles, tagFilter())
+		if err != nil {
+			return err
+		}
+
+		var due []secretEntry
+		for _, entry := range entries {
+			if entry.meta.IsExpired(now.Add(window)) {
+				due = append(due, entry)
+			}
+		}
+
+		sortSecretEntries(due, "expires", false)
+
+		if machineReadable() {
+			if err := printOutput(secretInfos(due)); err != nil {
+				return err
+			}
+		} else if print {
+			for _, entry := range due {
+				fmt.Println(entry.secret.Name())
+			}
+		} else {
+			header.PrintHeader()
+
+			if len(due) == 0 {
+				fmt.Println(pp.Completef("No secrets expired or expiring within %s", within))
+				return nil
+			}
+
+			fmt.Println(pp.Alert("Expired and soon to expire secrets"))
+			fmt.Println()
+
+			rows := make([][]string, 0, len(due))
+			for _, entry := range due {
+				rows = append(rows, []string{
+					entry.secret.Name(),
+					formatTime(entry.meta.ExpiresAt),
+					expiryStatus(entry.meta.ExpiresAt, now),
+				})
+			}
+
+			printTable([]string{"NAME", "EXPIRES", "STATUS"}, rows, pp.Red)
+			fmt.Println()
+		}
+
+		if len(due) == 0 {
+			return nil
+		}
+
+		return codedError{codeExpired, fmt.Errorf("%d secret(s) expired or expiring within %s", len(due), within)}
+	},
+}
+
+// expiryStatus describes how long ago a secret expired or how soon it expires.
+func expiryStatus(expiresAt time.Time, now time.Time) string {
+	if !expiresAt.After(now) {
+		return "expired " + approxDuration(now.Sub(expiresAt)) + " ago"
+	}
+
+	return "expires in " + approxDuration(expiresAt.Sub(now))
+}
+
+// approxDuration formats a duration using only its largest unit, e.g. 3d instead of 3d4h12m.
+func approxDuration(d time.Duration) string {
+	s := secrets.FormatDuration(d.Round(time.Minute))
+
+	if i := strings.IndexFunc(s, unicode.IsLetter); i >= 0 {
+		return s[:i+1]
+	}
+
+	return s
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/engmtcdrm/mellon/env"
+)
+
+// TestExpiredCommand tests listing expired secrets and refusing to view them.
+func TestExpiredCommand(t *testing.T) {
+	env.Init()
+
+	secretFile := filepath.Join(t.TempDir(), "secret.txt")
+	secretContent := "expiredsecretcontent"
+
+	if err := os.WriteFile(secretFile, []byte(secretContent), 0644); err != nil {
+		t.Fatalf("failed to write secret file: %v", err)
+	}
+
+	expiring := map[string][]string{
+		"testexpired-past": {"--expires-at", "2020-01-01", "--tag", "expiredtest"},
+		"testexpired-soon": {"--expires-in", "2d", "--tag", "expiredtest"},
+		"testexpired-late": {"--expires-in", "90d", "--tag", "expiredtest"},
+	}
+
+	for name, expiryArgs := range expiring {
+		args := append([]string{"create", "--secret", name, "--file", secretFile}, expiryArgs...)
+		if output, err := exec.Command(testBinary, args...).CombinedOutput(); err != nil {
+			t.Fatalf("failed to create secret '%s': %v, output: %s", name, err, output)
+		}
+		defer exec.Command(testBinary, "delete", "--secret", name, "--force").Run()
+	}
+
+	cmd := exec.Command(testBinary, "expired", "--print", "--tag", "expiredtest")
+	output, err := cmd.Output()
+	if err == nil {
+		t.Errorf("expected non-zero exit code when secrets are expiring, got none")
+	}
+
+	if strings.Fields(string(output))[0] != "testexpired-past" || !strings.Contains(string(output), "testexpired-soon") || strings.Contains(string(output), "testexpired-late") {
+		t.Errorf("expected expired and soon to expire secrets only, got: %s", output)
+	}
+
+	cmd = exec.Command(testBinary, "expired", "--print", "--tag", "expiredtest", "--within", "0s")
+	output, _ = cmd.Output()
+	if strings.TrimSpace(string(output)) != "testexpired-past" {
+		t.Errorf("expected only the expired secret with --within 0s, got: %s", output)
+	}
+
+	cmd = exec.Command(testBinary, "expired", "--tag", "expiredtest", "--not-tag", "expiredtest")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("expected success when no secrets are expiring, got error: %v, output: %s", err, output)
+	}
+
+	cmd = exec.Command(testBinary, "view", "--secret", "testexpired-past")
+	if _, err := cmd.CombinedOutput(); err == nil {
+		t.Errorf("expected error viewing expired secret, got none")
+	}
+
+	cmd = exec.Command(testBinary, "view", "--secret", "testexpired-past", "--allow-expired")
+	output, err = cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("expected success viewing expired secret with --allow-expired, got error: %v, output: %s", err, output)
+	}
+
+	if string(output) != secretContent {
+		t.Errorf("expected secret content '%s', got '%s'", secretContent, output)
+	}
+
+	// Every command decrypting secrets refuses expired ones unless allowed
+	cmd = exec.Command(testBinary, "exec", "--env", "VALUE=testexpired-past", "--", "sh", "-c", `printf '%s' "$VALUE"`)
+	output, err = cmd.CombinedOutput()
+	if err == nil {
+		t.Errorf("expected error passing expired secret to a command, got none")
+	}
+
+	if !strings.Contains(string(output), "--allow-expired") {
+		t.Errorf("expected hint on --allow-expired, got: %s", output)
+	}
+
+	cmd = exec.Command(testBinary, "exec", "--allow-expired", "--env", "VALUE=testexpired-past", "--", "sh", "-c", `printf '%s' "$VALUE"`)
+	output, err = cmd.Output()
+	if err != nil {
+		t.Fatalf("expected success passing expired secret with --allow-expired, got error: %v, output: %s", err, output)
+	}
+
+	if string(output) != secretContent {
+		t.Errorf("expected secret content '%s', got '%s'", secretContent, output)
+	}
+
+	// Removing the expiry makes the secret viewable again
+	cmd = exec.Command(testBinary, "update", "--secret", "testexpired-past", "--no-expiry")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("expected success removing expiry, got error: %v, output: %s", err, output)
+	}
+
+	cmd = exec.Command(testBinary, "view", "--secret", "testexpired-past")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("expected success viewing secret without expiry, got error: %v, output: %s", err, output)
+	}
+
+	cmd = exec.Command(testBinary, "create", "--secret", "testexpired-invalid", "--file", secretFile, "--expires-in", "soon")
+	if _, err := cmd.CombinedOutput(); err == nil {
+		t.Errorf("expected error for invalid --expires-in, got none")
+		exec.Command(testBinary, "delete", "--secret", "testexpired-invalid", "--force").Run()
+	}
+}
@@ -0,0 +1,282 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/engmtcdrm/mellon/app"
+	"github.com/engmtcdrm/mellon/exporter"
+	"github.com/engmtcdrm/mellon/secrets"
+)
+
+func init() {
+	exportCmd.Flags().StringVar(
+		&exportFormat,
+		"format",
+		"dotenv",
+		fmt.Sprintf("(optional) The format to export the secrets in. One of: %s", strings.Join(exporter.Formats, ", ")),
+	)
+	exportCmd.Flags().StringVar(
+		&prefix,
+		"prefix",
+		"",
+		"(optional) The namespace of the secrets to export, e.g. prod/. Keys are named relative to it. Defaults to exporting every secret",
+	)
+	exportCmd.Flags().StringVarP(
+		&output,
+		"output",
+		"o",
+		"",
+		"(optional) File to write the exported secrets to. Defaults to outputting to stdout",
+	)
+	exportCmd.Flags().StringVar(
+		&keyCase,
+		"key-case",
+		"upper",
+		fmt.Sprintf("(optional) How names of secrets are turned into keys, e.g. db/password into DB_PASSWORD. One of: %s", strings.Join(exporter.KeyCases, ", ")),
+	)
+	exportCmd.Flags().StringArrayVar(
+		&keyMappings,
+		"key",
+		nil,
+		"(optional) The key of an exported secret as KEY=secret, or KEY=secret#field for a field of a structured secret. Can be repeated to name multiple keys",
+	)
+	exportCmd.Flags().StringVar(
+		&k8sName,
+		"k8s-name",
+		"",
+		"(optional) The name of the Kubernetes Secret. Defaults to the namespace exported, e.g. prod-db for prod/db/ (only used with format k8s-secret)",
+	)
+	exportCmd.Flags().StringVar(
+		&k8sNamespace,
+		"k8s-namespace",
+		"",
+		"(optional) The Kubernetes namespace of the Secret (only used with format k8s-secret)",
+	)
+
+	addTagFilterFlags(exportCmd)
+	addAllowExpiredFlag(exportCmd, "(optional) Whether to allow exporting secrets that have expired")
+
+	exportCmd.MarkFlagFilename("output")
+	exportCmd.RegisterFlagCompletionFunc("format", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return exporter.Formats, cobra.ShellCompDirectiveNoFileComp
+	})
+	exportCmd.RegisterFlagCompletionFunc("key-case", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return exporter.KeyCases, cobra.ShellCompDirectiveNoFileComp
+	})
+	exportCmd.RegisterFlagCompletionFunc("prefix", namespaceCompletion)
+	exportCmd.RegisterFlagCompletionFunc("key", envFlagCompletion)
+
+	rootCmd.AddCommand(exportCmd)
+}
+
+func validateExportFlags(cmd *cobra.Command, args []string) error {
+	if !slices.Contains(exporter.Formats, exportFormat) {
+		return fmt.Errorf("invalid format '%s'. Must be one of: %s", exportFormat, strings.Join(exporter.Formats, ", "))
+	}
+
+	if !slices.Contains(exporter.KeyCases, keyCase) {
+		return fmt.Errorf("invalid key case '%s'. Must be one of: %s", keyCase, strings.Join(exporter.KeyCases, ", "))
+	}
+
+	if exportFormat != "k8s-secret" && (k8sName != "" || k8sNamespace != "") {
+		return errors.New("flags --k8s-name and --k8s-namespace can only be used with format k8s-secret")
+	}
+
+	return nil
+}
+
+// parseKeyMappings parses the keys given as KEY=secret or KEY=secret#field, returning
+// the keys by secret reference.
+func parseKeyMappings(mappings []string) (map[string]string, error) {
+	keys := map[string]string{}
+
+	for _, m := range mappings {
+		key, ref, ok := strings.Cut(m, "=")
+		if !ok || key == "" || ref == "" {
+			return nil, fmt.Errorf("invalid key '%s'. Use KEY=secret or KEY=secret#field", m)
+		}
+
+		keys[ref] = key
+	}
+
+	return keys, nil
+}
+
+// exportEntries decrypts the secrets into entries to export, naming every value
+// relative to the namespace. Structured secrets export a value for each field. Every
+// secret is tried so all problems are reported at once.
+func exportEntries(available []secrets.Secret, namespace string, keys map[string]string) ([]exporter.Entry, error) {
+	var entries []exporter.Entry
+	var errs []error
+
+	used := map[string]bool{}
+	keyOf := func(ref string, relName string) string {
+		if key, ok := keys[ref]; ok {
+			used[ref] = true
+			return key
+		}
+		return exporter.Key(relName, keyCase)
+	}
+
+	for _, secret := range available {
+		relName := strings.TrimPrefix(secret.Name(), namespace)
+
+		meta, err := secret.Metadata()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if !meta.IsStructured() {
+			value, err := secret.Decrypt()
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+
+			entries = append(entries, exporter.Entry{Key: keyOf(secret.Name(), relName), Value: value})
+			continue
+		}
+
+		fields, err := secret.DecryptFields()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		for _, field := range slices.Sorted(maps.Keys(fields)) {
+			entries = append(entries, exporter.Entry{
+				Key:   keyOf(secret.Name()+"#"+field, relName+"/"+field),
+				Value: []byte(fields[field]),
+			})
+		}
+	}
+
+	for ref := range keys {
+		if !used[ref] {
+			errs = append(errs, fmt.Errorf("--key: '%s' is not among the exported secrets", ref))
+		}
+	}
+
+	if len(errs) > 0 {
+		clearEntries(entries)
+		return nil, errors.Join(errs...)
+	}
+
+	return entries, nil
+}
+
+// validateExportKeys checks every key is valid for the format and used only once.
+func validateExportKeys(entries []exporter.Entry) error {
+	var errs []error
+	seen := map[string]bool{}
+
+	for _, e := range entries {
+		if err := exporter.ValidateKey(exportFormat, e.Key); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if seen[e.Key] {
+			errs = append(errs, fmt.Errorf("key '%s' is used by more than one secret. Use --key to name them differently", e.Key))
+		}
+		seen[e.Key] = true
+	}
+
+	return errors.Join(errs...)
+}
+
+// clearEntries wipes the values of the entries from memory.
+func clearEntries(entries []exporter.Entry) {
+	for i := range entries {
+		secrets.ClearSecret(&entries[i].Value)
+	}
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export secrets to another format",
+	Long: "Export secrets to another format.\n\n" +
+		"Supported formats are:\n" +
+		"  dotenv      KEY=\"value\" lines\n" +
+		"  json        a JSON object\n" +
+		"  shell       export KEY='value' lines for POSIX shells\n" +
+		"  k8s-secret  a Kubernetes v1 Secret manifest with base64 encoded data\n\n" +
+		"Keys are named after the secrets relative to the --prefix namespace, e.g. prod/db/password becomes DB_PASSWORD with --prefix prod/. " +
+		"Structured secrets export a key for each field. Use --key to name keys explicitly.",
+	Example: fmt.Sprintf(
+		"  %s export --prefix prod/ -o .env\n  %s export --format shell --prefix prod/ --key DB_PASS=prod/db/password\n  %s export --format k8s-secret --prefix prod/ --key-case lower -o secret.yml",
+		app.Name, app.Name, app.Name,
+	),
+	Args:    cobra.NoArgs,
+	PreRunE: validateExportFlags,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		keys, err := parseKeyMappings(keyMappings)
+		if err != nil {
+			return err
+		}
+
+		available := secretFiles
+		namespace := ""
+		if prefix != "" {
+			namespace = secrets.NormalizeNamespace(prefix)
+			available = secrets.FilterNamespace(secretFiles, namespace)
+		}
+
+		filter := tagFilter()
+		selected, err := loadSecretEntries(available, filter)
+		if err != nil {
+			return err
+		}
+
+		if len(selected) == 0 {
+			return errors.New("no secrets found to export")
+		}
+
+		toExport := make([]secrets.Secret, 0, len(selected))
+		for _, entry := range selected {
+			toExport = append(toExport, entry.secret)
+		}
+
+		entries, err := exportEntries(toExport, namespace, keys)
+		if err != nil {
+			return fmt.Errorf("could not export secrets:\n%w", err)
+		}
+		defer clearEntries(entries)
+
+		if err := validateExportKeys(entries); err != nil {
+			return fmt.Errorf("could not export secrets:\n%w", err)
+		}
+
+		opts := exporter.Options{Name: k8sName, Namespace: k8sNamespace}
+		if opts.Name == "" {
+			opts.Name = strings.ReplaceAll(exporter.Key(namespace, "lower"), "_", "-")
+			if opts.Name == "" {
+				opts.Name = app.Name
+			}
+		}
+
+		exported, err := exporter.Format(exportFormat, entries, opts)
+		if err != nil {
+			return err
+		}
+		defer secrets.ClearSecret(&exported)
+
+		if output == "" {
+			fmt.Print(string(exported))
+			return nil
+		}
+
+		if err := writeOutputFile(output, exported, "exported secrets"); err != nil {
+			return err
+		}
+
+		return printResult(result{Action: "written", Secrets: secretNames(toExport), Path: output})
+	},
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/engmtcdrm/mellon/env"
+)
+
+// TestExportCommand tests exporting secrets to other formats.
+func TestExportCommand(t *testing.T) {
+	env.Init()
+
+	tmpDir := t.TempDir()
+	secretFile := filepath.Join(tmpDir, "secret.txt")
+	if err := os.WriteFile(secretFile, []byte("it's a secret"), 0644); err != nil {
+		t.Fatalf("failed to write secret file: %v", err)
+	}
+
+	if output, err := exec.Command(testBinary, "create", "--secret", "testexport/api-key", "--file", secretFile).CombinedOutput(); err != nil {
+		t.Fatalf("failed to create secret: %v, output: %s", err, output)
+	}
+	defer exec.Command(testBinary, "delete", "--secret", "testexport/api-key", "--force").Run()
+
+	if output, err := exec.Command(testBinary, "create", "--secret", "testexport/db", "--field", fieldFile(t, "user", "app"), "--field", fieldFile(t, "password", "dbpass")).CombinedOutput(); err != nil {
+		t.Fatalf("failed to create structured secret: %v, output: %s", err, output)
+	}
+	defer exec.Command(testBinary, "delete", "--secret", "testexport/db", "--force").Run()
+
+	outFile := filepath.Join(tmpDir, "out", ".env")
+	if output, err := exec.Command(testBinary, "export", "--prefix", "testexport/", "-o", outFile).CombinedOutput(); err != nil {
+		t.Fatalf("failed to export secrets: %v, output: %s", err, output)
+	}
+
+	exported, err := os.ReadFile(outFile)
+	if err != nil {
+		t.Fatalf("failed to read exported file: %v", err)
+	}
+
+	expected := "API_KEY=\"it's a secret\"\nDB_PASSWORD=\"dbpass\"\nDB_USER=\"app\"\n"
+	if string(exported) != expected {
+		t.Errorf("expected exported file:\n%s\ngot:\n%s", expected, exported)
+	}
+
+	if info, err := os.Stat(outFile); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("expected exported file to have mode 0600, got: %v, error: %v", info.Mode().Perm(), err)
+	}
+
+	// Keys can be named explicitly
+	output, err := exec.Command(testBinary, "export", "--format", "shell", "--prefix", "testexport", "--key", "DB_PASS=testexport/db#password", "--key", "TOKEN=testexport/api-key").Output()
+	if err != nil {
+		t.Fatalf("failed to export secrets: %v", err)
+	}
+
+	expected = "export DB_PASS='dbpass'\nexport DB_USER='app'\nexport TOKEN='it'\\''s a secret'\n"
+	if string(output) != expected {
+		t.Errorf("expected shell exports:\n%s\ngot:\n%s", expected, output)
+	}
+
+	output, err = exec.Command(testBinary, "export", "--format", "k8s-secret", "--prefix", "testexport/", "--key-case", "lower").Output()
+	if err != nil {
+		t.Fatalf("failed to export secrets: %v", err)
+	}
+
+	for _, line := range []string{"kind: Secret", "  name: testexport", "  api_key: aXQncyBhIHNlY3JldA==", "  db_password: ZGJwYXNz"} {
+		if !strings.Contains(string(output), line+"\n") {
+			t.Errorf("expected manifest to contain '%s', got:\n%s", line, output)
+		}
+	}
+
+	// Keys that collide are reported
+	output, err = exec.Command(testBinary, "export", "--prefix", "testexport/", "--key", "DB_USER=testexport/db#password").CombinedOutput()
+	if err == nil || !strings.Contains(string(output), "DB_USER") {
+		t.Errorf("expected duplicate keys to be reported, got: %s", output)
+	}
+}
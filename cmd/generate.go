@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/engmtcdrm/mellon/app"
+	"github.com/engmtcdrm/mellon/generator"
+	"github.com/engmtcdrm/mellon/secrets"
+)
+
+// generatorFlags are the flags configuring generated values.
+var generatorFlags = []string{"type", "length", "words", "separator", "classes", "exclude", "no-ambiguous"}
+
+func init() {
+	addGeneratorFlags(generateCmd)
+
+	rootCmd.AddCommand(generateCmd)
+}
+
+// addGeneratorFlags adds the flags configuring generated values.
+func addGeneratorFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(
+		&generateType,
+		"type",
+		"password",
+		"(optional) The kind of value to generate. One of: password, pronounceable, passphrase, hex, base64, urlsafe",
+	)
+	cmd.Flags().IntVar(
+		&genLength,
+		"length",
+		0,
+		fmt.Sprintf("(optional) The number of characters of passwords, or of random bytes of hex, base64 and urlsafe tokens. Defaults to %d for passwords and %d for tokens", generator.DefaultLength, generator.DefaultTokenLength),
+	)
+	cmd.Flags().IntVar(
+		&genWords,
+		"words",
+		generator.DefaultWords,
+		"(optional) The number of words of passphrases",
+	)
+	cmd.Flags().StringVar(
+		&genSeparator,
+		"separator",
+		"-",
+		"(optional) The separator between the words of passphrases",
+	)
+	cmd.Flags().StringSliceVar(
+		&genClasses,
+		"classes",
+		generator.Classes,
+		"(optional) The character classes of passwords, each used at least once. Any of: lower, upper, digits, symbols",
+	)
+	cmd.Flags().StringVar(
+		&genExclude,
+		"exclude",
+		"",
+		"(optional) Characters never used in passwords",
+	)
+	cmd.Flags().BoolVar(
+		&noAmbiguous,
+		"no-ambiguous",
+		false,
+		"(optional) Whether to leave characters that are easily confused, e.g. 0 and O, out of passwords",
+	)
+
+	cmd.RegisterFlagCompletionFunc("type", cobra.FixedCompletions(generator.Types, cobra.ShellCompDirectiveNoFileComp))
+	cmd.RegisterFlagCompletionFunc("classes", cobra.FixedCompletions(generator.Classes, cobra.ShellCompDirectiveNoFileComp))
+}
+
+// addGenerateFlags adds the --generate flag used to store a generated secret, along
+// with the flags configuring it. It must be called after the --file and --field flags
+// are added.
+func addGenerateFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(
+		&generate,
+		"generate",
+		false,
+		"(optional) Whether to store a generated secret instead of reading it from a file or prompting for it. The secret is never shown. It is configured with the same flags as the generate command",
+	)
+	addGeneratorFlags(cmd)
+
+	cmd.MarkFlagsMutuallyExclusive("generate", "file")
+	cmd.MarkFlagsMutuallyExclusive("generate", "field")
+}
+
+// validateGeneratorFlags checks that the flags configuring generated values are only
+// used along with --generate.
+func validateGeneratorFlags(cmd *cobra.Command) error {
+	if generate {
+		return nil
+	}
+
+	for _, name := range generatorFlags {
+		if cmd.Flags().Changed(name) {
+			return fmt.Errorf("flag --%s can only be used with --generate", name)
+		}
+	}
+
+	return nil
+}
+
+// encryptGenerated encrypts a generated value as the secret. The value is never shown
+// nor written anywhere else.
+func encryptGenerated(secret *secrets.Secret, raw bool) error {
+	value, err := generateValue()
+	if err != nil {
+		return err
+	}
+
+	if err := secret.Encrypt(value, raw); err != nil {
+		return fmt.Errorf("could not encrypt generated secret: %w", err)
+	}
+
+	return nil
+}
+
+// generateValue generates a value as configured by the generator flags. The caller
+// is responsible for clearing it.
+func generateValue() ([]byte, error) {
+	value, err := generator.Generate(generator.Options{
+		Type:        generateType,
+		Length:      genLength,
+		Words:       genWords,
+		Separator:   genSeparator,
+		Classes:     genClasses,
+		Exclude:     genExclude,
+		NoAmbiguous: noAmbiguous,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not generate secret: %w", err)
+	}
+
+	return value, nil
+}
+
+var generateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate a password, passphrase or token",
+	Long: "Generate a random password, passphrase or token and print it.\n\n" +
+		"Passwords use every character class given with --classes at least once, and never the characters given with --exclude. " +
+		"Pronounceable passwords alternate consonants and vowels, capitalise one letter and end with a digit and a symbol, depending on the classes. " +
+		"Passphrases are made of words from the EFF diceware wordlist, and tokens of random bytes encoded as hex, base64 or URL-safe base64.\n\n" +
+		"Use --generate with the create and update commands to store a generated secret straight away, without it being shown.",
+	Example: fmt.Sprintf(
+		"  %s generate\n  %s generate --length 32 --classes lower,upper,digits --no-ambiguous\n  %s generate --type passphrase --words 8\n  %s generate --type hex --length 16\n  %s create -s db/password --generate --length 32",
+		app.Name, app.Name, app.Name, app.Name, app.Name,
+	),
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		value, err := generateValue()
+		if err != nil {
+			return err
+		}
+		defer secrets.ClearSecret(&value)
+
+		if machineReadable() {
+			return printOutput(struct {
+				Type  string `json:"type"`
+				Value string `json:"value"`
+			}{generateType, string(value)})
+		}
+
+		fmt.Println(string(value))
+
+		return nil
+	},
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/engmtcdrm/mellon/env"
+)
+
+// TestGenerateCommand tests generating values, and storing them with create and
+// update without them being shown.
+func TestGenerateCommand(t *testing.T) {
+	env.Init()
+
+	output, err := exec.Command(testBinary, "generate", "--length", "40", "--classes", "digits").Output()
+	if err != nil {
+		t.Fatalf("failed to generate password: %v", err)
+	}
+	if value := strings.TrimSuffix(string(output), "\n"); len(value) != 40 || strings.Trim(value, "0123456789") != "" {
+		t.Errorf("expected 40 digits, got: %q", value)
+	}
+
+	output, err = exec.Command(testBinary, "generate", "--type", "passphrase", "--words", "5", "--separator", " ").Output()
+	if err != nil || len(strings.Fields(string(output))) != 5 {
+		t.Errorf("expected a passphrase of 5 words, got: %q, error: %v", output, err)
+	}
+
+	if output, err := exec.Command(testBinary, "generate", "--type", "pin").CombinedOutput(); err == nil {
+		t.Errorf("expected an invalid type to fail, got: %s", output)
+	}
+
+	secretName := "testgenerate"
+
+	output, err = exec.Command(testBinary, "create", "--secret", secretName, "--generate", "--type", "hex", "--length", "16").CombinedOutput()
+	if err != nil {
+		t.Fatalf("failed to create generated secret: %v, output: %s", err, output)
+	}
+	defer exec.Command(testBinary, "delete", "--secret", secretName, "--force").Run()
+
+	if len(output) != 0 {
+		t.Errorf("expected the generated secret not to be shown, got: %s", output)
+	}
+
+	first, err := exec.Command(testBinary, "view", "--secret", secretName).Output()
+	if err != nil {
+		t.Fatalf("failed to view generated secret: %v", err)
+	}
+	if raw, err := hex.DecodeString(string(first)); err != nil || len(raw) != 16 {
+		t.Errorf("expected 16 random bytes encoded as hex, got: %q", first)
+	}
+
+	if output, err := exec.Command(testBinary, "update", "--secret", secretName, "--generate", "--type", "hex", "--length", "16").CombinedOutput(); err != nil {
+		t.Fatalf("failed to update generated secret: %v, output: %s", err, output)
+	}
+
+	second, err := exec.Command(testBinary, "view", "--secret", secretName).Output()
+	if err != nil || string(second) == string(first) {
+		t.Errorf("expected the secret to be replaced with a new generated value, got: %q, error: %v", second, err)
+	}
+
+	if output, err := exec.Command(testBinary, "update", "--secret", secretName, "--length", "16").CombinedOutput(); err == nil {
+		t.Errorf("expected generator flags without --generate to fail, got: %s", output)
+	}
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/engmtcdrm/go-pardon"
+	pp "github.com/engmtcdrm/go-prettyprint"
+	"github.com/engmtcdrm/mellon/app"
+	"github.com/engmtcdrm/mellon/env"
+	"github.com/engmtcdrm/mellon/header"
+	"github.com/engmtcdrm/mellon/secrets"
+	"github.com/engmtcdrm/mellon/secrets/prompts"
+)
+
+func init() {
+	historyCmd.Flags().StringVarP(
+		&secretName,
+		"secret",
+		"s",
+		"",
+		"(optional) The name of the secret to list the versions of",
+	)
+	historyCmd.Flags().BoolVarP(
+		&print,
+		"print",
+		"p",
+		false,
+		"(optional) Whether to print only the version numbers without additional information. This only works with the option -s/--secret",
+	)
+
+	historyCmd.RegisterFlagCompletionFunc("secret", secretFlagCompletion)
+
+	rootCmd.AddCommand(historyCmd)
+}
+
+var historyCmd = &cobra.Command{
+	Use:     "history",
+	Short:   "List the versions of a secret",
+	Long:    fmt.Sprintf("List the versions of a secret.\n\nEvery time the value of a secret changes, the previous value is kept as a version in the history. The number of versions kept is set with the setting %s.", pp.Green("history.retention")),
+	Example: fmt.Sprintf("  %s history\n  %s history -s awesome-secret", app.Name, app.Name),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var selectedSecret secrets.Secret
+
+		if secretName == "" {
+			if err := promptHeader(); err != nil {
+				return err
+			}
+
+			options, err := prompts.GetSecretOptions(secretFiles, secrets.TagFilter{}, "list the history of", env.Instance.ExeCmd())
+			if err != nil {
+				return err
+			}
+
+			promptSelect := pardon.NewSelect(&selectedSecret).
+				Options(options...).
+				Title("What secret do you want to list the history of?")
+
+			if err := promptSelect.Ask(); err != nil {
+				return err
+			}
+
+			fmt.Println()
+		} else {
+			secretPtr := secrets.FindSecretByName(secretName, secretFiles)
+			if secretPtr == nil {
+				return fmt.Errorf("could not list history of secret '%s': secret %w", secretName, secrets.ErrNotFound)
+			}
+			selectedSecret = *secretPtr
+		}
+
+		versions, err := selectedSecret.Versions()
+		if err != nil {
+			return err
+		}
+
+		// Newest versions first
+		slices.Reverse(versions)
+
+		if machineReadable() {
+			return printOutput(versions)
+		}
+
+		if print && secretName != "" {
+			for _, v := range versions {
+				fmt.Println(v.Number)
+			}
+			return nil
+		}
+
+		if secretName != "" {
+			header.PrintHeader()
+		}
+
+		fmt.Println(pp.Info("Versions of secret " + pp.Green(selectedSecret.Name())))
+		fmt.Println()
+
+		rows := make([][]string, 0, len(versions))
+		for _, v := range versions {
+			status := ""
+			if v.Current {
+				status = "current"
+			}
+
+			rows = append(rows, []string{strconv.Itoa(v.Number), formatTime(v.RotatedAt), status})
+		}
+
+		printTable([]string{"VERSION", "ROTATED", "STATUS"}, rows, pp.Yellow)
+		fmt.Println()
+
+		return nil
+	},
+}
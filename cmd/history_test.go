@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/engmtcdrm/mellon/env"
+)
+
+// TestHistoryCommand tests listing, viewing and rolling back to previous versions of a secret.
+func TestHistoryCommand(t *testing.T) {
+	env.Init()
+
+	secretName := "testhistory-secret"
+	secretFile := filepath.Join(t.TempDir(), "secret.txt")
+
+	for i, value := range []string{"first", "second", "third"} {
+		if err := os.WriteFile(secretFile, []byte(value), 0644); err != nil {
+			t.Fatalf("failed to write secret file: %v", err)
+		}
+
+		command := "update"
+		if i == 0 {
+			command = "create"
+		}
+
+		if output, err := exec.Command(testBinary, command, "--secret", secretName, "--file", secretFile).CombinedOutput(); err != nil {
+			t.Fatalf("failed to %s secret: %v, output: %s", command, err, output)
+		}
+	}
+	defer exec.Command(testBinary, "delete", "--secret", secretName, "--force").Run()
+
+	output, err := exec.Command(testBinary, "history", "--secret", secretName, "--print").Output()
+	if err != nil {
+		t.Fatalf("failed to list history: %v", err)
+	}
+
+	if strings.Join(strings.Fields(string(output)), ",") != "3,2,1" {
+		t.Errorf("expected versions 3,2,1, got: %s", output)
+	}
+
+	output, err = exec.Command(testBinary, "view", "--secret", secretName, "--version", "1").Output()
+	if err != nil {
+		t.Fatalf("failed to view version 1: %v", err)
+	}
+
+	if string(output) != "first" {
+		t.Errorf("expected version 1 to be 'first', got: %s", output)
+	}
+
+	if _, err := exec.Command(testBinary, "view", "--secret", secretName, "--version", "9").CombinedOutput(); err == nil {
+		t.Errorf("expected error viewing a version that does not exist")
+	}
+
+	// Rekeying re-encrypts previous versions too
+	if output, err := exec.Command(testBinary, "rekey", "--force").CombinedOutput(); err != nil {
+		t.Fatalf("failed to rekey: %v, output: %s", err, output)
+	}
+
+	if output, err := exec.Command(testBinary, "rollback", "--secret", secretName, "--to", "1", "--force").CombinedOutput(); err != nil {
+		t.Fatalf("failed to roll back: %v, output: %s", err, output)
+	}
+
+	output, err = exec.Command(testBinary, "view", "--secret", secretName).Output()
+	if err != nil {
+		t.Fatalf("failed to view secret: %v", err)
+	}
+
+	if string(output) != "first" {
+		t.Errorf("expected rolled back secret to be 'first', got: %s", output)
+	}
+
+	output, err = exec.Command(testBinary, "view", "--secret", secretName, "--version", "3").Output()
+	if err != nil || string(output) != "third" {
+		t.Errorf("expected replaced version 3 to be kept as 'third', got: %s, error: %v", output, err)
+	}
+
+	if output, err := exec.Command(testBinary, "delete", "--secret", secretName, "--force").CombinedOutput(); err != nil {
+		t.Fatalf("failed to delete secret: %v, output: %s", err, output)
+	}
+
+	if _, err := os.Stat(filepath.Join(env.Instance.HistoryPath(), secretName)); !os.IsNotExist(err) {
+		t.Errorf("expected history of deleted secret to be removed, got: %v", err)
+	}
+}
+
+// TestRollbackCommand_RequiresFlags tests that rollback refuses to run without a secret and version.
+func TestRollbackCommand_RequiresFlags(t *testing.T) {
+	for _, args := range [][]string{
+		{"rollback", "--to", "1"},
+		{"rollback", "--secret", "testhistory-missing"},
+		{"rollback", "--secret", "testhistory-missing", "--to", "0"},
+		{"rollback", "--secret", "testhistory-missing", "--to", "1", "--force"},
+	} {
+		if output, err := exec.Command(testBinary, args...).CombinedOutput(); err == nil {
+			t.Errorf("expected error for %v, got output: %s", args, output)
+		}
+	}
+}
+
+// TestConfigCommand tests reading and changing settings.
+func TestConfigCommand(t *testing.T) {
+	env.Init()
+
+	original, err := exec.Command(testBinary, "config", "get", "history.retention").Output()
+	if err != nil {
+		t.Fatalf("failed to get setting: %v", err)
+	}
+	defer exec.Command(testBinary, "config", "set", "history.retention", strings.TrimSpace(string(original))).Run()
+
+	if output, err := exec.Command(testBinary, "config", "set", "history.retention", "4").CombinedOutput(); err != nil {
+		t.Fatalf("failed to set setting: %v, output: %s", err, output)
+	}
+
+	output, err := exec.Command(testBinary, "config", "get", "history.retention").Output()
+	if err != nil || strings.TrimSpace(string(output)) != "4" {
+		t.Errorf("expected setting to be 4, got: %s, error: %v", output, err)
+	}
+
+	for _, args := range [][]string{
+		{"config", "set", "history.retention", "-1"},
+		{"config", "set", "history.retention", "many"},
+		{"config", "get", "unknown.setting"},
+	} {
+		if output, err := exec.Command(testBinary, args...).CombinedOutput(); err == nil {
+			t.Errorf("expected error for %v, got output: %s", args, output)
+		}
+	}
+}
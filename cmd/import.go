@@ -0,0 +1,275 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	pp "github.com/engmtcdrm/go-prettyprint"
+	"github.com/engmtcdrm/mellon/app"
+	"github.com/engmtcdrm/mellon/env"
+	"github.com/engmtcdrm/mellon/importer"
+	"github.com/engmtcdrm/mellon/secrets"
+)
+
+func init() {
+	importCmd.Flags().StringVar(
+		&importFormat,
+		"format",
+		"",
+		fmt.Sprintf("(optional) The format of the file. One of: %s. Defaults to detecting it from the file extension", strings.Join(importer.Formats, ", ")),
+	)
+	importCmd.Flags().StringVar(
+		&prefix,
+		"prefix",
+		"",
+		"(optional) The namespace to import the secrets into, e.g. prod/",
+	)
+	importCmd.Flags().BoolVar(
+		&skipExisting,
+		"skip-existing",
+		false,
+		"(optional) Whether to skip entries for secrets that already exist",
+	)
+	importCmd.Flags().BoolVar(
+		&overwrite,
+		"overwrite",
+		false,
+		"(optional) Whether to overwrite secrets that already exist. Their previous values are kept in the history",
+	)
+	importCmd.Flags().BoolVarP(
+		&cleanupFile,
+		"cleanup",
+		"c",
+		false,
+		"(optional) Whether to delete the plain text file after all secrets are imported",
+	)
+
+	addRawFlag(importCmd, "(optional) Whether to store the values exactly as given. By default leading and trailing whitespace is trimmed")
+
+	importCmd.MarkFlagsMutuallyExclusive("skip-existing", "overwrite")
+	importCmd.RegisterFlagCompletionFunc("format", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return importer.Formats, cobra.ShellCompDirectiveNoFileComp
+	})
+	importCmd.RegisterFlagCompletionFunc("prefix", namespaceCompletion)
+
+	rootCmd.AddCommand(importCmd)
+}
+
+// importEntry is an entry of an import file together with the secret it is stored in.
+type importEntry struct {
+	entry    importer.Entry
+	name     string          // Name of the secret, including the prefix
+	existing *secrets.Secret // The secret with the same name, if it already exists
+}
+
+// planImport checks the entries to import before anything is written, reporting
+// every invalid name, duplicate and conflict with an existing secret at once.
+func planImport(entries []importer.Entry, namespace string) ([]importEntry, error) {
+	var plan []importEntry
+	var errs []error
+	var conflicts []string
+
+	seen := map[string]bool{}
+
+	for _, entry := range entries {
+		name := namespace + entry.Name
+
+		if err := secrets.ValidateName(name); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+
+		if seen[name] {
+			errs = append(errs, fmt.Errorf("%s: appears more than once", name))
+			continue
+		}
+		seen[name] = true
+
+		existing := secrets.FindSecretByName(name, secretFiles)
+		if existing != nil && !skipExisting && !overwrite {
+			conflicts = append(conflicts, name)
+		}
+
+		plan = append(plan, importEntry{entry: entry, name: name, existing: existing})
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("could not import, invalid entries found:\n%w", errors.Join(errs...))
+	}
+
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("could not import, %d secret(s) already exist: %s\n\nUse --skip-existing to keep them or --overwrite to replace them", len(conflicts), strings.Join(conflicts, ", "))
+	}
+
+	return plan, nil
+}
+
+// importSecret stores the value or fields of an entry in its secret.
+func importSecret(e importEntry) error {
+	var err error
+
+	secret := e.existing
+	if secret == nil {
+		secret, err = secrets.NewSecret(env.Instance.KeyPath(), e.name, filepath.Join(env.Instance.SecretsPath(), e.name+env.Instance.SecretExt()))
+		if err != nil {
+			return fmt.Errorf("could not create secret: %w", err)
+		}
+	}
+
+	if e.entry.Fields != nil {
+		err = secret.EncryptFields(e.entry.Fields)
+	} else {
+		err = secret.Encrypt(e.entry.Value, rawSecret)
+	}
+	if err != nil || e.entry.Description == "" {
+		return err
+	}
+
+	meta, err := secret.Metadata()
+	if err != nil {
+		return err
+	}
+
+	meta.Description = e.entry.Description
+
+	return secret.SaveMetadata(meta)
+}
+
+// readImport reads the entries to import from the file, or password store directory,
+// at path.
+func readImport(path string) ([]importer.Entry, error) {
+	if importFormat == "pass" {
+		return importer.ParsePassStore(path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read file '%s': %w", path, err)
+	}
+	defer secrets.ClearSecret(&data)
+
+	return importer.Parse(importFormat, data)
+}
+
+// passStoreDir returns the directory of the pass(1) password store.
+func passStoreDir() string {
+	if dir := os.Getenv("PASSWORD_STORE_DIR"); dir != "" {
+		return dir
+	}
+
+	return "~/.password-store"
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Import secrets from a file",
+	Long: "Import secrets from a file, creating a secret for every entry.\n\n" +
+		"Supported formats are:\n" +
+		"  dotenv     KEY=value lines\n" +
+		"  json       a JSON object, nested objects become namespaces\n" +
+		"  yaml       a YAML mapping, nested mappings become namespaces\n" +
+		"  csv        a CSV file with a header naming a name and a value column\n" +
+		"  keepass    a KeePass 2 XML export\n" +
+		"  bitwarden  an unencrypted Bitwarden JSON export\n" +
+		"  1password  a 1Password CSV export\n" +
+		"  browser    a Chrome or Firefox password CSV export\n" +
+		"  pass       a pass(1) password store directory, decrypted with gpg. Defaults to $PASSWORD_STORE_DIR or ~/.password-store\n\n" +
+		"Logins from password managers become structured secrets with username, password, url and notes fields, and folders become namespaces.\n\n" +
+		"Every entry is checked before anything is written, and the import fails if any secret already exists unless --skip-existing or --overwrite is given.",
+	Example: fmt.Sprintf(
+		"  %s import .env --prefix myapp/\n  %s import --format json secrets.json --overwrite\n  %s import --format csv exported.csv --skip-existing --cleanup\n  %s import --format bitwarden bitwarden_export.json --prefix work/\n  %s import --format pass",
+		app.Name, app.Name, app.Name, app.Name, app.Name,
+	),
+	Args: cobra.MaximumNArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if importFormat != "pass" && len(args) == 0 {
+			return errors.New("a file to import is required")
+		}
+
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		input := passStoreDir()
+		if len(args) > 0 {
+			input = args[0]
+		}
+
+		path, err := env.ExpandTilde(input)
+		if err != nil {
+			return err
+		}
+
+		if importFormat == "" {
+			if info, err := os.Stat(path); err == nil && info.IsDir() {
+				importFormat = "pass"
+			} else if importFormat, err = importer.DetectFormat(path); err != nil {
+				return err
+			}
+		}
+
+		// Checked once the format is known, a password store may be detected from its path
+		if importFormat == "pass" && cleanupFile {
+			return errors.New("flag -c/--cleanup cannot be used to remove a password store")
+		}
+
+		entries, err := readImport(path)
+		if err != nil {
+			return fmt.Errorf("could not import '%s':\n%w", path, err)
+		}
+
+		namespace := ""
+		if prefix != "" {
+			namespace = secrets.NormalizeNamespace(prefix)
+		}
+
+		plan, err := planImport(entries, namespace)
+		if err != nil {
+			return err
+		}
+
+		if err := takeSnapshot("import"); err != nil {
+			return err
+		}
+
+		res := result{Action: "imported", Secrets: []string{}, Path: path}
+
+		for _, e := range plan {
+			if e.existing != nil && skipExisting {
+				res.Skipped = append(res.Skipped, e.name)
+				if !machineReadable() {
+					fmt.Println(pp.Infof("Skipped %s, it already exists", e.name))
+				}
+				continue
+			}
+
+			if err := importSecret(e); err != nil {
+				return fmt.Errorf("could not import secret '%s': %w", e.name, err)
+			}
+
+			res.Secrets = append(res.Secrets, e.name)
+			if !machineReadable() {
+				fmt.Println(pp.Completef("Imported %s", pp.Green(e.name)))
+			}
+		}
+
+		if cleanupFile {
+			if err := secrets.CleanupFile(path); err != nil {
+				return err
+			}
+		}
+
+		if machineReadable() {
+			return printOutput(res)
+		}
+
+		fmt.Println()
+		fmt.Printf("Imported %d secret(s) from '%s'\n", len(res.Secrets), path)
+
+		return nil
+	},
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/engmtcdrm/mellon/env"
+)
+
+// TestImportCommand tests importing secrets from files.
+func TestImportCommand(t *testing.T) {
+	env.Init()
+
+	tmpDir := t.TempDir()
+
+	names := []string{"testimport/API_KEY", "testimport/DB_PASS", "testimport/prod/token", "testimport/csv-secret"}
+	for _, name := range names {
+		defer exec.Command(testBinary, "delete", "--secret", name, "--force").Run()
+	}
+
+	view := func(name string) string {
+		output, err := exec.Command(testBinary, "view", "--secret", name).Output()
+		if err != nil {
+			t.Errorf("failed to view secret '%s': %v", name, err)
+		}
+		return string(output)
+	}
+
+	dotenvFile := filepath.Join(tmpDir, ".env")
+	if err := os.WriteFile(dotenvFile, []byte("API_KEY=abc123\nexport DB_PASS=\"p@ss word\"\n"), 0644); err != nil {
+		t.Fatalf("failed to write import file: %v", err)
+	}
+
+	if output, err := exec.Command(testBinary, "import", dotenvFile, "--prefix", "testimport").CombinedOutput(); err != nil {
+		t.Fatalf("failed to import dotenv file: %v, output: %s", err, output)
+	}
+
+	if value := view("testimport/API_KEY"); value != "abc123" {
+		t.Errorf("expected imported value 'abc123', got: %s", value)
+	}
+
+	if value := view("testimport/DB_PASS"); value != "p@ss word" {
+		t.Errorf("expected imported value 'p@ss word', got: %s", value)
+	}
+
+	// Existing secrets are reported and nothing is written
+	jsonFile := filepath.Join(tmpDir, "secrets.json")
+	if err := os.WriteFile(jsonFile, []byte(`{"API_KEY": "new", "prod": {"token": "tok"}}`), 0644); err != nil {
+		t.Fatalf("failed to write import file: %v", err)
+	}
+
+	output, err := exec.Command(testBinary, "import", jsonFile, "--prefix", "testimport/").CombinedOutput()
+	if err == nil || !strings.Contains(string(output), "testimport/API_KEY") {
+		t.Errorf("expected import to fail on existing secret, got: %s", output)
+	}
+
+	if _, err := exec.Command(testBinary, "view", "--secret", "testimport/prod/token").Output(); err == nil {
+		t.Errorf("expected nothing to be imported when a secret already exists")
+	}
+
+	if output, err := exec.Command(testBinary, "import", jsonFile, "--prefix", "testimport/", "--skip-existing").CombinedOutput(); err != nil {
+		t.Fatalf("failed to import with --skip-existing: %v, output: %s", err, output)
+	}
+
+	if value := view("testimport/API_KEY"); value != "abc123" {
+		t.Errorf("expected existing secret to be kept, got: %s", value)
+	}
+
+	if value := view("testimport/prod/token"); value != "tok" {
+		t.Errorf("expected nested object to be imported into a namespace, got: %s", value)
+	}
+
+	if output, err := exec.Command(testBinary, "import", jsonFile, "--prefix", "testimport/", "--overwrite").CombinedOutput(); err != nil {
+		t.Fatalf("failed to import with --overwrite: %v, output: %s", err, output)
+	}
+
+	if value := view("testimport/API_KEY"); value != "new" {
+		t.Errorf("expected existing secret to be overwritten, got: %s", value)
+	}
+
+	// Every invalid name is reported before anything is written
+	csvFile := filepath.Join(tmpDir, "secrets.csv")
+	if err := os.WriteFile(csvFile, []byte("name,value\ncsv-secret,csvvalue\nbad.name,x\nbad name,y\n"), 0644); err != nil {
+		t.Fatalf("failed to write import file: %v", err)
+	}
+
+	output, err = exec.Command(testBinary, "import", csvFile, "--prefix", "testimport").CombinedOutput()
+	if err == nil || !strings.Contains(string(output), "testimport/bad.name") || !strings.Contains(string(output), "testimport/bad name") {
+		t.Errorf("expected every invalid name to be reported, got: %s", output)
+	}
+
+	if err := os.WriteFile(csvFile, []byte("name,value\ncsv-secret,csvvalue\n"), 0644); err != nil {
+		t.Fatalf("failed to write import file: %v", err)
+	}
+
+	if output, err := exec.Command(testBinary, "import", "--format", "csv", csvFile, "--prefix", "testimport", "--cleanup").CombinedOutput(); err != nil {
+		t.Fatalf("failed to import csv file: %v, output: %s", err, output)
+	}
+
+	if value := view("testimport/csv-secret"); value != "csvvalue" {
+		t.Errorf("expected imported value 'csvvalue', got: %s", value)
+	}
+
+	if _, err := os.Stat(csvFile); !os.IsNotExist(err) {
+		t.Errorf("expected import file to be removed with --cleanup, got: %v", err)
+	}
+
+	// A password store is never removed, whether its format is given or detected
+	storeDir := filepath.Join(tmpDir, "password-store")
+	if err := os.MkdirAll(storeDir, 0700); err != nil {
+		t.Fatalf("failed to create password store: %v", err)
+	}
+
+	for _, args := range [][]string{{"import", "--format", "pass", storeDir, "--cleanup"}, {"import", storeDir, "--cleanup"}} {
+		output, err := exec.Command(testBinary, args...).CombinedOutput()
+		if err == nil || !strings.Contains(string(output), "cannot be used to remove a password store") {
+			t.Errorf("expected --cleanup to be refused for a password store, got: %s", output)
+		}
+	}
+
+	if _, err := os.Stat(storeDir); err != nil {
+		t.Errorf("expected password store to be kept, got: %v", err)
+	}
+
+	// Logins from password managers become structured secrets in their folders
+	bitwardenFile := filepath.Join(tmpDir, "bitwarden.json")
+	bitwarden := `{"encrypted": false, "folders": [{"id": "f1", "name": "Work Mail"}], "items": [{"type": 1, "folderId": "f1", "name": "Jane's Mail", "notes": "shared", "login": {"username": "jane", "password": "mail-pass", "uris": [{"uri": "https://mail.example.com"}]}}]}`
+	if err := os.WriteFile(bitwardenFile, []byte(bitwarden), 0644); err != nil {
+		t.Fatalf("failed to write import file: %v", err)
+	}
+	defer exec.Command(testBinary, "delete", "--secret", "testimport/Work-Mail/Jane-s-Mail", "--force").Run()
+
+	if output, err := exec.Command(testBinary, "import", "--format", "bitwarden", bitwardenFile, "--prefix", "testimport").CombinedOutput(); err != nil {
+		t.Fatalf("failed to import Bitwarden export: %v, output: %s", err, output)
+	}
+
+	for field, expected := range map[string]string{"username": "jane", "password": "mail-pass", "url": "https://mail.example.com", "notes": "shared"} {
+		output, err := exec.Command(testBinary, "view", "--secret", "testimport/Work-Mail/Jane-s-Mail", "--field", field).Output()
+		if err != nil || string(output) != expected {
+			t.Errorf("expected field '%s' to be '%s', got: %s, error: %v", field, expected, output, err)
+		}
+	}
+
+	output, err = exec.Command(testBinary, "list", "testimport/Work-Mail/").Output()
+	if err != nil || !strings.Contains(string(output), "Jane's Mail") {
+		t.Errorf("expected the title of the login as the description, got: %s, error: %v", output, err)
+	}
+}
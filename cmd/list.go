@@ -2,14 +2,25 @@ package cmd
 
 import (
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	pp "github.com/engmtcdrm/go-prettyprint"
 	"github.com/engmtcdrm/mellon/app"
 	"github.com/engmtcdrm/mellon/env"
 	"github.com/engmtcdrm/mellon/header"
+	"github.com/engmtcdrm/mellon/secrets"
 	"github.com/spf13/cobra"
 )
 
+const listTimeFormat = "2006-01-02 15:04" // Format of the timestamps shown in the list
+
+// listSortFields are the fields the list of secrets can be sorted by.
+var listSortFields = []string{"name", "description", "owner", "created", "updated", "rotated", "expires"}
+
 func init() {
 	listCmd.Flags().BoolVarP(
 		&print,
@@ -18,19 +29,84 @@ func init() {
 		false,
 		"(optional) Whether to print only the names of the secrets without additional information",
 	)
+	listCmd.Flags().StringVar(
+		&sortBy,
+		"sort",
+		"name",
+		fmt.Sprintf("(optional) The field to sort the secrets by. One of: %s", strings.Join(listSortFields, ", ")),
+	)
+	listCmd.Flags().BoolVarP(
+		&reverseSort,
+		"reverse",
+		"r",
+		false,
+		"(optional) Whether to reverse the sort order",
+	)
+
+	listCmd.Flags().BoolVar(
+		&tree,
+		"tree",
+		false,
+		"(optional) Whether to show the secrets as a tree of namespaces",
+	)
+
+	addTagFilterFlags(listCmd)
+
+	listCmd.MarkFlagsMutuallyExclusive("tree", "print")
+
+	listCmd.RegisterFlagCompletionFunc("sort", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return listSortFields, cobra.ShellCompDirectiveNoFileComp
+	})
 
 	rootCmd.AddCommand(listCmd)
 }
 
+// secretEntry is a secret together with its metadata.
+type secretEntry struct {
+	secret secrets.Secret
+	meta   secrets.Metadata
+}
+
+func validateListFlags(cmd *cobra.Command, args []string) error {
+	if !slices.Contains(listSortFields, sortBy) {
+		return fmt.Errorf("invalid sort field '%s'. Must be one of: %s", sortBy, strings.Join(listSortFields, ", "))
+	}
+
+	return nil
+}
+
 var listCmd = &cobra.Command{
-	Use:     "list",
-	Short:   "List available secrets",
-	Long:    "List available secrets",
-	Example: fmt.Sprintf("  %s list", app.Name),
+	Use:               "list [namespace/]",
+	Short:             "List available secrets",
+	Long:              "List available secrets along with their metadata.\n\nWhen a namespace is provided, only the secrets within it are listed.",
+	Example:           fmt.Sprintf("  %s list\n  %s list prod/\n  %s list --tree\n  %s list --sort updated --reverse\n  %s list --tag ci --not-tag prod\n  %s list --print", app.Name, app.Name, app.Name, app.Name, app.Name, app.Name),
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: namespaceCompletion,
+	PreRunE:           validateListFlags,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		filter := tagFilter()
+
+		available := secretFiles
+		namespace := ""
+		if len(args) > 0 {
+			namespace = secrets.NormalizeNamespace(args[0])
+			available = secrets.FilterNamespace(secretFiles, namespace)
+		}
+
+		entries, err := loadSecretEntries(available, filter)
+		if err != nil {
+			return err
+		}
+
+		sortSecretEntries(entries, sortBy, reverseSort)
+
+		if machineReadable() {
+			return printOutput(secretInfos(entries))
+		}
+
 		if print {
-			for _, secret := range secretFiles {
-				fmt.Println(secret.Name())
+			for _, entry := range entries {
+				fmt.Println(entry.secret.Name())
 			}
 
 			return nil
@@ -38,16 +114,259 @@ var listCmd = &cobra.Command{
 
 		header.PrintHeader()
 
-		if len(secretFiles) == 0 {
+		if len(entries) == 0 {
+			if !filter.IsEmpty() {
+				return fmt.Errorf("no secrets %s found to list", filter)
+			}
+
+			if namespace != "" {
+				return fmt.Errorf("no secrets found in namespace '%s'", namespace)
+			}
+
 			return fmt.Errorf("no available secrets to list\n\nUse command %s to create a secret", pp.Greenf("%s create", env.Instance.ExeCmd()))
 		}
 
 		fmt.Println(pp.Info("Available secrets"))
 		fmt.Println()
-		for _, secret := range secretFiles {
-			fmt.Printf("  - %s\n", pp.Green(secret.Name()))
+
+		if tree {
+			names := make([]string, 0, len(entries))
+			for _, entry := range entries {
+				names = append(names, strings.TrimPrefix(entry.secret.Name(), namespace))
+			}
+
+			printTree(namespace, names)
+			fmt.Println()
+
+			return nil
+		}
+
+		rows := make([][]string, 0, len(entries))
+		for _, entry := range entries {
+			rows = append(rows, []string{
+				entry.secret.Name(),
+				valueOrDash(entry.meta.Description),
+				valueOrDash(entry.meta.Owner),
+				valueOrDash(strings.Join(entry.meta.Tags, ",")),
+				formatTime(entry.meta.CreatedAt),
+				formatTime(entry.meta.UpdatedAt),
+				formatTime(entry.meta.RotatedAt),
+				formatExpiry(entry.meta),
+			})
 		}
 
+		printTable(
+			[]string{"NAME", "DESCRIPTION", "OWNER", "TAGS", "CREATED", "UPDATED", "ROTATED", "EXPIRES"},
+			rows,
+			pp.Green,
+		)
+
 		return nil
 	},
 }
+
+// secretInfos returns the secrets along with their metadata for machine-readable output.
+func secretInfos(entries []secretEntry) []secretInfo {
+	infos := make([]secretInfo, 0, len(entries))
+	for _, entry := range entries {
+		infos = append(infos, secretInfo{Name: entry.secret.Name(), Metadata: entry.meta})
+	}
+
+	return infos
+}
+
+// loadSecretEntries reads the metadata of every secret, keeping only the secrets
+// matching the tag filter.
+func loadSecretEntries(secretFiles []secrets.Secret, filter secrets.TagFilter) ([]secretEntry, error) {
+	entries := make([]secretEntry, 0, len(secretFiles))
+
+	for _, secret := range secretFiles {
+		meta, err := secret.Metadata()
+		if err != nil {
+			return nil, err
+		}
+
+		if !filter.Match(meta.Tags) {
+			continue
+		}
+
+		entries = append(entries, secretEntry{secret: secret, meta: meta})
+	}
+
+	return entries, nil
+}
+
+// sortSecretEntries sorts the secrets by the given field. Secrets with equal
+// values are sorted by name.
+func sortSecretEntries(entries []secretEntry, field string, reverse bool) {
+	slices.SortStableFunc(entries, func(a, b secretEntry) int {
+		var c int
+
+		switch field {
+		case "description":
+			c = strings.Compare(a.meta.Description, b.meta.Description)
+		case "owner":
+			c = strings.Compare(a.meta.Owner, b.meta.Owner)
+		case "created":
+			c = a.meta.CreatedAt.Compare(b.meta.CreatedAt)
+		case "updated":
+			c = a.meta.UpdatedAt.Compare(b.meta.UpdatedAt)
+		case "rotated":
+			c = a.meta.RotatedAt.Compare(b.meta.RotatedAt)
+		case "expires":
+			c = compareExpiry(a.meta.ExpiresAt, b.meta.ExpiresAt)
+		}
+
+		if c == 0 {
+			c = strings.Compare(a.secret.Name(), b.secret.Name())
+		}
+
+		if reverse {
+			return -c
+		}
+
+		return c
+	})
+}
+
+// printTable prints rows in aligned columns under the given headers. The first
+// column of each row is formatted with firstColFn.
+func printTable(headers []string, rows [][]string, firstColFn func(a ...any) string) {
+	widths := make([]int, len(headers))
+	for i, h := range headers {
+		widths[i] = utf8.RuneCountInString(h)
+	}
+
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+
+	line := func(cells []string, fn func(int, string) string) string {
+		var sb strings.Builder
+		sb.WriteString("  ")
+		for i, cell := range cells {
+			// Pad before formatting so escape codes do not throw off the alignment
+			padded := cell
+			if i < len(cells)-1 {
+				padded = fmt.Sprintf("%-*s  ", widths[i], cell)
+			}
+			sb.WriteString(fn(i, padded))
+		}
+		return strings.TrimRight(sb.String(), " ")
+	}
+
+	fmt.Println(line(headers, func(_ int, s string) string { return pp.Bold(s) }))
+	for _, row := range rows {
+		fmt.Println(line(row, func(i int, s string) string {
+			if i == 0 && firstColFn != nil {
+				return firstColFn(s)
+			}
+			return s
+		}))
+	}
+}
+
+// treeNode is a namespace or secret in the tree of secrets.
+type treeNode struct {
+	children map[string]*treeNode // Nested namespaces, by name
+	secrets  []string             // Secrets directly in this namespace
+}
+
+// printTree prints secret names as a tree of namespaces below root. Namespaces are
+// listed before the secrets they hold, each sorted by name.
+func printTree(root string, names []string) {
+	top := &treeNode{children: map[string]*treeNode{}}
+
+	for _, name := range names {
+		node := top
+		parts := strings.Split(name, "/")
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := node.children[part]
+			if !ok {
+				child = &treeNode{children: map[string]*treeNode{}}
+				node.children[part] = child
+			}
+			node = child
+		}
+		node.secrets = append(node.secrets, parts[len(parts)-1])
+	}
+
+	if root == "" {
+		root = "."
+	}
+	fmt.Println("  " + pp.Bold(pp.Blue(root)))
+
+	var walk func(node *treeNode, indent string)
+	walk = func(node *treeNode, indent string) {
+		namespaces := slices.Sorted(maps.Keys(node.children))
+		slices.Sort(node.secrets)
+
+		total := len(namespaces) + len(node.secrets)
+		i := 0
+
+		branch := func() (string, string) {
+			i++
+			if i == total {
+				return "└── ", "    "
+			}
+			return "├── ", "│   "
+		}
+
+		for _, name := range namespaces {
+			prefix, childIndent := branch()
+			fmt.Println("  " + indent + prefix + pp.Blue(name+"/"))
+			walk(node.children[name], indent+childIndent)
+		}
+
+		for _, name := range node.secrets {
+			prefix, _ := branch()
+			fmt.Println("  " + indent + prefix + pp.Green(name))
+		}
+	}
+
+	walk(top, "")
+}
+
+// compareExpiry compares two expiry times, treating secrets that never expire as
+// expiring last.
+func compareExpiry(a, b time.Time) int {
+	switch {
+	case a.IsZero() && b.IsZero():
+		return 0
+	case a.IsZero():
+		return 1
+	case b.IsZero():
+		return -1
+	}
+
+	return a.Compare(b)
+}
+
+// formatExpiry formats the expiry of a secret for display, highlighting expired secrets.
+func formatExpiry(meta secrets.Metadata) string {
+	if meta.IsExpired(time.Now()) {
+		return formatTime(meta.ExpiresAt) + " (expired)"
+	}
+
+	return formatTime(meta.ExpiresAt)
+}
+
+// formatTime formats a timestamp for display, showing a dash for unset timestamps.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+
+	return t.Local().Format(listTimeFormat)
+}
+
+// valueOrDash returns the value, or a dash if it is empty.
+func valueOrDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
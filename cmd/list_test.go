@@ -282,3 +282,166 @@ func TestListCommand_OutputFormat(t *testing.T) {
 		t.Errorf("expected normal mode to contain header text")
 	}
 }
+
+// TestListCommand_Metadata tests that the list command shows metadata and sorts by it.
+func TestListCommand_Metadata(t *testing.T) {
+	env.Init()
+
+	secretFile := filepath.Join(t.TempDir(), "secret.txt")
+
+	if err := os.WriteFile(secretFile, []byte("metasecretcontent"), 0644); err != nil {
+		t.Fatalf("failed to write secret file: %v", err)
+	}
+
+	metaSecrets := []struct {
+		name  string
+		owner string
+	}{
+		{"listmeta1", "zeta-team"},
+		{"listmeta2", "alpha-team"},
+	}
+
+	for _, s := range metaSecrets {
+		createCmd := exec.Command(testBinary, "create", "--secret", s.name, "--file", secretFile, "--description", "Description of "+s.name, "--owner", s.owner)
+		if output, err := createCmd.CombinedOutput(); err != nil {
+			t.Fatalf("failed to create secret '%s': %v, output: %s", s.name, err, output)
+		}
+		defer exec.Command(testBinary, "delete", "--secret", s.name, "--force").Run()
+	}
+
+	cmd := exec.Command(testBinary, "list")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("expected success for list command, got error: %v", err)
+	}
+
+	outputStr := string(output)
+	for _, expected := range []string{"DESCRIPTION", "OWNER", "CREATED", "UPDATED", "ROTATED", "Description of listmeta1", "zeta-team", "alpha-team"} {
+		if !strings.Contains(outputStr, expected) {
+			t.Errorf("expected output to contain '%s', got: %s", expected, outputStr)
+		}
+	}
+
+	cmd = exec.Command(testBinary, "list", "--print", "--sort", "owner")
+	output, err = cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("expected success for list sorted by owner, got error: %v", err)
+	}
+
+	if strings.Index(string(output), "listmeta2") > strings.Index(string(output), "listmeta1") {
+		t.Errorf("expected listmeta2 to be listed before listmeta1 when sorted by owner, got: %s", output)
+	}
+
+	cmd = exec.Command(testBinary, "list", "--print", "--sort", "owner", "--reverse")
+	output, err = cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("expected success for list sorted by owner in reverse, got error: %v", err)
+	}
+
+	if strings.Index(string(output), "listmeta1") > strings.Index(string(output), "listmeta2") {
+		t.Errorf("expected listmeta1 to be listed before listmeta2 when reverse sorted by owner, got: %s", output)
+	}
+
+	cmd = exec.Command(testBinary, "list", "--sort", "invalid")
+	if _, err := cmd.CombinedOutput(); err == nil {
+		t.Errorf("expected error for invalid sort field, got none")
+	}
+}
+
+// TestListCommand_TagFilter tests filtering the list of secrets by tag.
+func TestListCommand_TagFilter(t *testing.T) {
+	env.Init()
+
+	secretFile := filepath.Join(t.TempDir(), "secret.txt")
+
+	if err := os.WriteFile(secretFile, []byte("tagsecret"), 0644); err != nil {
+		t.Fatalf("failed to write secret file: %v", err)
+	}
+
+	tagged := map[string][]string{
+		"listtag-ci":      {"--tag", "listci"},
+		"listtag-ci-prod": {"--tag", "listci", "--tag", "listprod"},
+		"listtag-none":    {},
+	}
+
+	for name, tagArgs := range tagged {
+		args := append([]string{"create", "--secret", name, "--file", secretFile}, tagArgs...)
+		if output, err := exec.Command(testBinary, args...).CombinedOutput(); err != nil {
+			t.Fatalf("failed to create secret '%s': %v, output: %s", name, err, output)
+		}
+		defer exec.Command(testBinary, "delete", "--secret", name, "--force").Run()
+	}
+
+	cmd := exec.Command(testBinary, "list", "--print", "--tag", "listci", "--not-tag", "listprod")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("expected success for list with tag filter, got error: %v", err)
+	}
+
+	if strings.TrimSpace(string(output)) != "listtag-ci" {
+		t.Errorf("expected only 'listtag-ci' to be listed, got: %s", output)
+	}
+
+	cmd = exec.Command(testBinary, "list", "--tag", "listprod")
+	output, err = cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("expected success for list with tag filter, got error: %v", err)
+	}
+
+	if !strings.Contains(string(output), "listci,listprod") || strings.Contains(string(output), "listtag-none") {
+		t.Errorf("expected only tagged secret with its tags to be listed, got: %s", output)
+	}
+
+	// Invalid tags are rejected when creating a secret
+	cmd = exec.Command(testBinary, "create", "--secret", "listtag-invalid", "--file", secretFile, "--tag", "not valid")
+	if _, err := cmd.CombinedOutput(); err == nil {
+		t.Errorf("expected error for invalid tag, got none")
+		exec.Command(testBinary, "delete", "--secret", "listtag-invalid", "--force").Run()
+	}
+}
+
+// TestListCommand_Namespace tests listing the secrets in a namespace and as a tree.
+func TestListCommand_Namespace(t *testing.T) {
+	env.Init()
+
+	secretFile := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(secretFile, []byte("namespacecontent"), 0644); err != nil {
+		t.Fatalf("failed to write secret file: %v", err)
+	}
+
+	names := []string{"testlistns/db/password", "testlistns/db/user", "testlistns/api-key", "testlistnsother"}
+	for _, name := range names {
+		if output, err := exec.Command(testBinary, "create", "--secret", name, "--file", secretFile).CombinedOutput(); err != nil {
+			t.Fatalf("failed to create secret '%s': %v, output: %s", name, err, output)
+		}
+		defer exec.Command(testBinary, "delete", "--secret", name, "--force").Run()
+	}
+
+	output, err := exec.Command(testBinary, "list", "testlistns/", "--print").Output()
+	if err != nil {
+		t.Fatalf("failed to list namespace: %v", err)
+	}
+
+	if strings.Join(strings.Fields(string(output)), ",") != "testlistns/api-key,testlistns/db/password,testlistns/db/user" {
+		t.Errorf("expected only secrets in the namespace, got: %s", output)
+	}
+
+	output, err = exec.Command(testBinary, "list", "testlistns", "--tree").Output()
+	if err != nil {
+		t.Fatalf("failed to list tree: %v", err)
+	}
+
+	for _, expected := range []string{"testlistns/", "├── ", "db/", "│   ├── ", "password", "└── ", "api-key"} {
+		if !strings.Contains(string(output), expected) {
+			t.Errorf("expected tree to contain '%s', got: %s", expected, output)
+		}
+	}
+
+	if strings.Contains(string(output), "testlistnsother") {
+		t.Errorf("expected tree to only contain the namespace, got: %s", output)
+	}
+
+	if output, err := exec.Command(testBinary, "list", "testlistnsmissing/").CombinedOutput(); err == nil {
+		t.Errorf("expected error listing an empty namespace, got output: %s", output)
+	}
+}
@@ -0,0 +1,345 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/engmtcdrm/go-pardon"
+	pp "github.com/engmtcdrm/go-prettyprint"
+	"github.com/engmtcdrm/mellon/app"
+	"github.com/engmtcdrm/mellon/env"
+	"github.com/engmtcdrm/mellon/otp"
+	"github.com/engmtcdrm/mellon/secrets"
+	"github.com/engmtcdrm/mellon/secrets/prompts"
+)
+
+func init() {
+	otpCmd.Flags().StringVarP(
+		&secretName,
+		"secret",
+		"s",
+		"",
+		"(optional) The name of the one-time password secret to show the current code of",
+	)
+	otpCmd.Flags().BoolVarP(
+		&print,
+		"print",
+		"p",
+		false,
+		"(optional) Whether to print only the code without additional information. This only works with the option -s/--secret",
+	)
+
+	otpAddCmd.Flags().StringVarP(
+		&secretName,
+		"secret",
+		"s",
+		"",
+		"(optional) The name of the secret to store the one-time password in",
+	)
+	otpAddCmd.Flags().StringVar(
+		&otpURI,
+		"uri",
+		"",
+		"(optional) The otpauth:// URI of the one-time password. If neither --uri nor --qr is provided, the URI or a base32 seed is prompted for",
+	)
+	otpAddCmd.Flags().StringVar(
+		&otpQR,
+		"qr",
+		"",
+		"(optional) A PNG, JPEG or GIF image of the QR code of the one-time password",
+	)
+	otpAddCmd.Flags().BoolVarP(
+		&cleanupFile,
+		"cleanup",
+		"c",
+		false,
+		"(optional) Whether to delete the QR code image after the one-time password is stored",
+	)
+	otpAddCmd.Flags().StringVar(
+		&otpType,
+		"type",
+		otp.TypeTOTP,
+		"(optional) The kind of one-time password, totp for time-based or hotp for counter-based codes",
+	)
+	otpAddCmd.Flags().StringVar(
+		&otpAlgorithm,
+		"algorithm",
+		otp.DefaultAlgorithm,
+		"(optional) The HMAC algorithm codes are computed with. One of: SHA1, SHA256, SHA512",
+	)
+	otpAddCmd.Flags().IntVar(
+		&otpDigits,
+		"digits",
+		otp.DefaultDigits,
+		"(optional) The number of digits of the codes",
+	)
+	otpAddCmd.Flags().IntVar(
+		&otpPeriod,
+		"period",
+		otp.DefaultPeriod,
+		"(optional) The number of seconds each time-based code is valid for",
+	)
+	otpAddCmd.Flags().Uint64Var(
+		&otpCounter,
+		"counter",
+		0,
+		"(optional) The counter of the next counter-based code",
+	)
+	otpAddCmd.Flags().StringVar(
+		&otpIssuer,
+		"issuer",
+		"",
+		"(optional) The service the one-time password belongs to, e.g. GitHub",
+	)
+	otpAddCmd.Flags().StringVar(
+		&otpAccount,
+		"account",
+		"",
+		"(optional) The account the one-time password belongs to, e.g. ops@example.com",
+	)
+
+	addAllowExpiredFlag(otpCmd, "(optional) Whether to allow showing the code of a one-time password that has expired")
+
+	otpAddCmd.MarkFlagsMutuallyExclusive("uri", "qr")
+	otpAddCmd.MarkFlagFilename("qr", "png", "jpg", "jpeg", "gif")
+	otpAddCmd.RegisterFlagCompletionFunc("type", cobra.FixedCompletions(otp.Types, cobra.ShellCompDirectiveNoFileComp))
+	otpAddCmd.RegisterFlagCompletionFunc("algorithm", cobra.FixedCompletions(otp.Algorithms, cobra.ShellCompDirectiveNoFileComp))
+	otpCmd.RegisterFlagCompletionFunc("secret", otpSecretCompletion)
+
+	otpCmd.AddCommand(otpAddCmd)
+
+	rootCmd.AddCommand(otpCmd)
+}
+
+// otpSecrets returns the secrets holding a one-time password.
+func otpSecrets() []secrets.Secret {
+	var otpSecrets []secrets.Secret
+	for _, s := range secretFiles {
+		if meta, err := s.Metadata(); err == nil && meta.IsOTP() {
+			otpSecrets = append(otpSecrets, s)
+		}
+	}
+
+	return otpSecrets
+}
+
+// otpSecretCompletion provides shell completion for the -s/--secret flag of the otp command.
+func otpSecretCompletion(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var names []string
+	for _, s := range otpSecrets() {
+		names = append(names, s.Name())
+	}
+
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// otpKeyFlags applies the parameters given through flags to the key, overriding those
+// of the URI or QR code.
+func otpKeyFlags(cmd *cobra.Command, key *otp.Key) error {
+	if cmd.Flags().Changed("type") {
+		key.Type = strings.ToLower(otpType)
+	}
+	if cmd.Flags().Changed("algorithm") {
+		key.Algorithm = strings.ToUpper(otpAlgorithm)
+	}
+	if cmd.Flags().Changed("digits") {
+		key.Digits = otpDigits
+	}
+	if cmd.Flags().Changed("period") {
+		key.Period = otpPeriod
+	}
+	if cmd.Flags().Changed("counter") {
+		key.Counter = otpCounter
+	}
+	if cmd.Flags().Changed("issuer") {
+		key.Issuer = otpIssuer
+	}
+	if cmd.Flags().Changed("account") {
+		key.Account = otpAccount
+	}
+
+	return key.Validate()
+}
+
+var otpCmd = &cobra.Command{
+	Use:   "otp",
+	Short: "Show the current code of a one-time password",
+	Long: "Show the current code of a one-time password secret added with the otp add command.\n\n" +
+		"For time-based (TOTP) passwords, the number of seconds the code remains valid is shown as well. " +
+		"For counter-based (HOTP) passwords, the counter is advanced and stored every time a code is shown.",
+	Example: fmt.Sprintf("  %s otp\n  %s otp -s github\n  %s otp -s github --print", app.Name, app.Name, app.Name),
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var selectedSecret secrets.Secret
+
+		if secretName == "" {
+			if err := promptHeader(); err != nil {
+				return err
+			}
+
+			candidates := otpSecrets()
+			if len(candidates) == 0 {
+				return fmt.Errorf("no one-time passwords found\n\nPlease run command %s to add one", pp.Greenf("%s otp add", env.Instance.ExeCmd()))
+			}
+
+			options, err := prompts.GetSecretOptions(candidates, secrets.TagFilter{}, "show the code of", env.Instance.ExeCmd())
+			if err != nil {
+				return err
+			}
+
+			promptSelect := pardon.NewSelect(&selectedSecret).
+				Options(options...).
+				Title("What one-time password do you want to show the code of?")
+
+			if err := promptSelect.Ask(); err != nil {
+				return err
+			}
+
+			fmt.Println()
+		} else {
+			secretPtr := secrets.FindSecretByName(secretName, secretFiles)
+			if secretPtr == nil {
+				return fmt.Errorf("could not show code of secret '%s': secret %w", secretName, secrets.ErrNotFound)
+			}
+			selectedSecret = *secretPtr
+		}
+
+		code, remaining, err := selectedSecret.OTPCode(time.Now())
+		if err != nil {
+			return err
+		}
+
+		if machineReadable() {
+			return printOutput(struct {
+				Name      string `json:"name"`
+				Code      string `json:"code"`
+				Remaining int    `json:"remaining_seconds,omitempty"` // Seconds a time-based code remains valid
+			}{selectedSecret.Name(), code, int(remaining.Round(time.Second).Seconds())})
+		}
+
+		if print && secretName != "" {
+			fmt.Println(code)
+			return nil
+		}
+
+		fmt.Println(pp.Bold(code))
+		if remaining > 0 {
+			fmt.Println(pp.Infof("Valid for %d more second(s)", int(remaining.Round(time.Second).Seconds())))
+		}
+
+		return nil
+	},
+}
+
+var otpAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add a one-time password",
+	Long: "Add a time-based (TOTP) or counter-based (HOTP) one-time password, e.g. the two-factor authentication seed of a shared service account.\n\n" +
+		"The one-time password is read from an otpauth:// URI given with --uri, or from an image of its QR code given with --qr. " +
+		"Otherwise, the URI or a base32 seed is prompted for. The flags --type, --algorithm, --digits, --period and --counter set the parameters of a seed and override those of a URI.",
+	Example: fmt.Sprintf(
+		"  %s otp add\n  %s otp add -s github --qr ~/Downloads/github-2fa.png --cleanup\n  %s otp add -s aws --uri 'otpauth://totp/AWS:ops?secret=JBSWY3DPEHPK3PXP&issuer=AWS'\n  %s otp add -s vpn --type hotp --digits 8",
+		app.Name, app.Name, app.Name, app.Name,
+	),
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if cleanupFile && otpQR == "" {
+			return errors.New("flag -c/--cleanup can only be used when --qr is provided")
+		}
+
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		interactive := secretName == "" || (otpURI == "" && otpQR == "")
+
+		if interactive {
+			if err := promptHeader(); err != nil {
+				return err
+			}
+		}
+
+		if secretName == "" {
+			promptQuestion := pardon.NewQuestion(&secretName).
+				Title("Enter a name for the secret:").
+				Validate(validateSecretName)
+
+			if err := promptQuestion.Ask(); err != nil {
+				return err
+			}
+
+			fmt.Println()
+		} else if err := validateSecretName(secretName); err != nil {
+			return err
+		}
+
+		if secretPtr := secrets.FindSecretByName(secretName, secretFiles); secretPtr != nil {
+			return fmt.Errorf("secret %s %w", pp.Red(secretName), secrets.ErrExists)
+		}
+
+		value := otpURI
+		switch {
+		case otpQR != "":
+			path, err := env.ExpandTilde(otpQR)
+			if err != nil {
+				return err
+			}
+
+			if value, err = otp.DecodeQR(path); err != nil {
+				return err
+			}
+		case value == "":
+			var input []byte
+
+			promptSecret := pardon.NewPassword(&input).
+				Title("Enter the otpauth:// URI or base32 seed:")
+
+			if err := promptSecret.Ask(); err != nil {
+				return err
+			}
+
+			value = string(input)
+			secrets.ClearSecret(&input)
+
+			fmt.Println()
+		}
+
+		key, err := otp.Parse(value)
+		if err != nil {
+			return err
+		}
+
+		if err := otpKeyFlags(cmd, key); err != nil {
+			return err
+		}
+
+		newSecret, err := secrets.NewSecret(env.Instance.KeyPath(), secretName, filepath.Join(env.Instance.SecretsPath(), secretName+env.Instance.SecretExt()))
+		if err != nil {
+			return fmt.Errorf("could not create secret: %w", err)
+		}
+
+		if err := newSecret.EncryptOTP(key); err != nil {
+			return fmt.Errorf("could not encrypt one-time password: %w", err)
+		}
+
+		if cleanupFile {
+			path, _ := env.ExpandTilde(otpQR)
+			if err := secrets.CleanupFile(path); err != nil {
+				return err
+			}
+		}
+
+		if interactive {
+			fmt.Println(pp.Complete("One-time password encrypted and saved"))
+			fmt.Println()
+			fmt.Printf("You can run the commmand %s to show its current code\n", pp.Greenf("%s otp -s %s", env.Instance.ExeCmd(), secretName))
+			return nil
+		}
+
+		return printResult(result{Action: "created", Secrets: []string{secretName}})
+	},
+}
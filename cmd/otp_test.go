@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+
+	"github.com/engmtcdrm/mellon/env"
+)
+
+// TestOTPCommand tests adding one-time passwords from URIs and QR codes, and showing
+// their codes.
+func TestOTPCommand(t *testing.T) {
+	env.Init()
+
+	// Seed and codes from RFC 4226, appendix D
+	hotpName := "testotphotp"
+	uri := "otpauth://hotp/Example:ops?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&issuer=Example&counter=0"
+
+	if output, err := exec.Command(testBinary, "otp", "add", "--secret", hotpName, "--uri", uri).CombinedOutput(); err != nil {
+		t.Fatalf("failed to add one-time password: %v, output: %s", err, output)
+	}
+	defer exec.Command(testBinary, "delete", "--secret", hotpName, "--force").Run()
+
+	for _, expected := range []string{"755224", "287082", "359152"} {
+		output, err := exec.Command(testBinary, "otp", "--secret", hotpName, "--print").Output()
+		if err != nil {
+			t.Fatalf("failed to show code: %v", err)
+		}
+		if code := strings.TrimSuffix(string(output), "\n"); code != expected {
+			t.Errorf("expected code %s, got: %s", expected, code)
+		}
+	}
+
+	if output, err := exec.Command(testBinary, "otp", "add", "--secret", hotpName, "--uri", uri).CombinedOutput(); err == nil {
+		t.Errorf("expected adding an existing secret to fail, got: %s", output)
+	}
+
+	totpName := "testotptotp"
+
+	matrix, err := qrcode.NewQRCodeWriter().Encode("otpauth://totp/Example:ops?secret=JBSWY3DPEHPK3PXP&issuer=Example", gozxing.BarcodeFormat_QR_CODE, 300, 300, nil)
+	if err != nil {
+		t.Fatalf("failed to encode QR code: %v", err)
+	}
+
+	qrPath := filepath.Join(t.TempDir(), "qr.png")
+	f, err := os.Create(qrPath)
+	if err != nil {
+		t.Fatalf("failed to create QR code image: %v", err)
+	}
+	if err := png.Encode(f, matrix); err != nil {
+		t.Fatalf("failed to write QR code image: %v", err)
+	}
+	f.Close()
+
+	if output, err := exec.Command(testBinary, "otp", "add", "--secret", totpName, "--qr", qrPath, "--digits", "8", "--cleanup").CombinedOutput(); err != nil {
+		t.Fatalf("failed to add one-time password from QR code: %v, output: %s", err, output)
+	}
+	defer exec.Command(testBinary, "delete", "--secret", totpName, "--force").Run()
+
+	if _, err := os.Stat(qrPath); !os.IsNotExist(err) {
+		t.Errorf("expected the QR code image to be removed, got: %v", err)
+	}
+
+	output, err := exec.Command(testBinary, "otp", "--secret", totpName).Output()
+	if err != nil {
+		t.Fatalf("failed to show code: %v", err)
+	}
+	if !strings.Contains(string(output), "more second(s)") {
+		t.Errorf("expected the remaining time to be shown, got: %s", output)
+	}
+
+	output, err = exec.Command(testBinary, "otp", "--secret", totpName, "--print").Output()
+	if code := strings.TrimSuffix(string(output), "\n"); err != nil || len(code) != 8 {
+		t.Errorf("expected a code of 8 digits, got: %q, error: %v", code, err)
+	}
+
+	if output, err := exec.Command(testBinary, "otp", "add", "--secret", "testotpinvalid", "--uri", "otpauth://totp/x?secret=not-base32!").CombinedOutput(); err == nil {
+		exec.Command(testBinary, "delete", "--secret", "testotpinvalid", "--force").Run()
+		t.Errorf("expected an invalid seed to fail, got: %s", output)
+	}
+}
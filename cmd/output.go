@@ -0,0 +1,347 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	"gopkg.in/yaml.v3"
+
+	"github.com/engmtcdrm/go-pardon"
+	pp "github.com/engmtcdrm/go-prettyprint"
+	"github.com/engmtcdrm/mellon/header"
+	"github.com/engmtcdrm/mellon/secrets"
+	"github.com/engmtcdrm/mellon/secrets/passphrase"
+)
+
+const (
+	outputJSON = "json" // Output as JSON
+	outputYAML = "yaml" // Output as YAML
+)
+
+// outputFormats are the machine-readable formats output can be written in.
+var outputFormats = []string{outputJSON, outputYAML}
+
+// Codes of the errors reported with --output-format. Programs rely on them, so
+// existing codes must never change.
+const (
+	codeError               = "error"                // Any error without a more specific code
+	codeInvalidArgument     = "invalid_argument"     // Invalid command, arguments or flags
+	codeNotFound            = "not_found"            // A secret, version, field, snapshot or file does not exist
+	codeAlreadyExists       = "already_exists"       // A secret or file already exists
+	codeExpired             = "expired"              // The secret has expired
+	codeIncorrectPassphrase = "incorrect_passphrase" // The passphrase of the encryption key or backup is incorrect
+	codePermissionDenied    = "permission_denied"    // A file could not be read or written
+	codePromptRefused       = "prompt_refused"       // Input had to be prompted for, but prompts are not possible
+	codeAborted             = "aborted"              // A prompt was aborted
+)
+
+// errorCodes maps the errors commands can fail with to their codes. The first match wins.
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{errPromptRefused, codePromptRefused},
+	{secrets.ErrNotFound, codeNotFound},
+	{secrets.ErrExists, codeAlreadyExists},
+	{secrets.ErrExpired, codeExpired},
+	{passphrase.ErrIncorrectPassphrase, codeIncorrectPassphrase},
+	{pardon.ErrUserAborted, codeAborted},
+	{fs.ErrNotExist, codeNotFound},
+	{fs.ErrExist, codeAlreadyExists},
+	{fs.ErrPermission, codePermissionDenied},
+}
+
+// errPromptRefused is returned when input has to be prompted for, but stdin is not a
+// terminal or the output is machine-readable.
+var errPromptRefused = errors.New("cannot prompt for input")
+
+// reANSI matches the escape sequences used to colour output.
+var reANSI = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// codedError is an error reported with a given code.
+type codedError struct {
+	code string
+	err  error
+}
+
+func (e codedError) Error() string {
+	return e.err.Error()
+}
+
+func (e codedError) Unwrap() error {
+	return e.err
+}
+
+// usageError marks err as caused by invalid arguments or flags.
+func usageError(err error) error {
+	return codedError{codeInvalidArgument, err}
+}
+
+// result is the outcome of a command changing secrets, output when the output is
+// machine-readable.
+type result struct {
+	Action   string   `json:"action"`             // What was done, e.g. created or deleted
+	Secrets  []string `json:"secrets"`            // Names of the secrets changed
+	Sources  []string `json:"sources,omitempty"`  // Names the secrets were renamed or copied from, in the same order
+	Skipped  []string `json:"skipped,omitempty"`  // Names of the secrets left unchanged
+	Path     string   `json:"path,omitempty"`     // File written, e.g. a backup
+	Snapshot string   `json:"snapshot,omitempty"` // Snapshot restored
+}
+
+// secretInfo is a secret along with its metadata, output when listing secrets.
+type secretInfo struct {
+	Name string `json:"name"`
+	secrets.Metadata
+}
+
+// secretValue is the value of a secret, output when viewing secrets.
+type secretValue struct {
+	Name     string `json:"name"`
+	Version  int    `json:"version,omitempty"`  // Version viewed, if not the current one
+	Field    string `json:"field,omitempty"`    // Field or query selecting the value, if any
+	Encoding string `json:"encoding,omitempty"` // Encoding of the value, if any
+	Value    string `json:"value"`
+}
+
+// newSecretValue returns the value of a secret to output. Values that are not valid
+// UTF-8, such as binary files, cannot be represented in JSON or YAML and are encoded
+// as base64.
+func newSecretValue(name string, value []byte, enc string) (secretValue, error) {
+	if enc == "" {
+		if utf8.Valid(value) {
+			return secretValue{Name: name, Value: string(value)}, nil
+		}
+		enc = "base64"
+	}
+
+	encoded, err := secrets.Encode(value, enc)
+	if err != nil {
+		return secretValue{}, err
+	}
+
+	return secretValue{Name: name, Encoding: enc, Value: encoded}, nil
+}
+
+// secretNames returns the names of the secrets.
+func secretNames(list []secrets.Secret) []string {
+	names := make([]string, 0, len(list))
+	for _, s := range list {
+		names = append(names, s.Name())
+	}
+
+	return names
+}
+
+// addOutputFlag adds the --output-format flag used to make output machine-readable.
+// It is not named --output, which already names the file some commands write to.
+func addOutputFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(
+		&outputFormat,
+		"output-format",
+		"",
+		fmt.Sprintf("(optional) Write output, including errors, in a machine-readable format instead of text. One of: %s. Not named -o/--output, as that is the file written to by view, render, resolve, export and backup", strings.Join(outputFormats, ", ")),
+	)
+	cmd.RegisterFlagCompletionFunc("output-format", cobra.FixedCompletions(outputFormats, cobra.ShellCompDirectiveNoFileComp))
+}
+
+// validateOutputFormat checks the format given through the --output-format flag.
+func validateOutputFormat() error {
+	if outputFormat != "" && !slices.Contains(outputFormats, outputFormat) {
+		return fmt.Errorf("invalid output format '%s'. Must be one of: %s", outputFormat, strings.Join(outputFormats, ", "))
+	}
+
+	return nil
+}
+
+// outputFormatArg returns the value of the --output-format flag in args, for when the
+// flags could not be parsed.
+func outputFormatArg(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+
+		if value, ok := strings.CutPrefix(arg, "--output-format="); ok {
+			return value
+		}
+
+		if arg == "--output-format" && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+
+	return ""
+}
+
+// machineReadable reports whether output is written in a machine-readable format.
+func machineReadable() bool {
+	return outputFormat != ""
+}
+
+// printOutput writes v to stdout in the machine-readable format.
+func printOutput(v any) error {
+	return encodeOutput(os.Stdout, v)
+}
+
+// encodeOutput writes v to w as JSON or YAML. YAML is converted from the JSON, so
+// both use the same names and the order of the fields is kept.
+func encodeOutput(w io.Writer, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if outputFormat != outputYAML {
+		_, err := fmt.Fprintf(w, "%s\n", data)
+		return err
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	resetYAMLStyle(&node)
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+
+	return enc.Close()
+}
+
+// resetYAMLStyle clears the JSON flow style of the node and its children, so they are
+// written in the block style of YAML.
+func resetYAMLStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		resetYAMLStyle(child)
+	}
+}
+
+// errorCode returns the stable code of err.
+func errorCode(err error) string {
+	var coded codedError
+	if errors.As(err, &coded) {
+		return coded.code
+	}
+
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			return c.code
+		}
+	}
+
+	return codeError
+}
+
+// printError writes err to stderr, as an object holding its code and message when the
+// output is machine-readable.
+func printError(cmd *cobra.Command, err error) {
+	if !machineReadable() {
+		cmd.PrintErrln(cmd.ErrPrefix(), err.Error())
+		return
+	}
+
+	type errorInfo struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+
+	message := reANSI.ReplaceAllString(err.Error(), "")
+
+	if encErr := encodeOutput(os.Stderr, map[string]errorInfo{"error": {errorCode(err), message}}); encErr != nil {
+		cmd.PrintErrln(cmd.ErrPrefix(), err.Error())
+	}
+}
+
+// markUsageErrors marks the errors of validating the arguments and flags of cmd and
+// its subcommands as usage errors.
+func markUsageErrors(cmd *cobra.Command) {
+	if args := cmd.Args; args != nil {
+		cmd.Args = func(cmd *cobra.Command, a []string) error {
+			if err := args(cmd, a); err != nil {
+				return usageError(err)
+			}
+			return nil
+		}
+	}
+
+	if preRunE := cmd.PreRunE; preRunE != nil {
+		cmd.PreRunE = func(cmd *cobra.Command, a []string) error {
+			if err := preRunE(cmd, a); err != nil {
+				return usageError(err)
+			}
+			return nil
+		}
+	}
+
+	for _, sub := range cmd.Commands() {
+		markUsageErrors(sub)
+	}
+}
+
+// requireInteractive returns an error if the output is machine-readable, as prompts
+// would be mixed into it. It is called before anything is prompted for.
+func requireInteractive() error {
+	if machineReadable() {
+		return fmt.Errorf("%w with --output-format: provide every value through flags", errPromptRefused)
+	}
+
+	return nil
+}
+
+// stdinIsTerminal reports whether stdin is a terminal prompts can be answered on.
+func stdinIsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// promptHeader prints the header shown before prompting, unless prompts are refused
+// as the output is machine-readable or stdin is not a terminal, where they would hang
+// or fail.
+func promptHeader() error {
+	if err := requireInteractive(); err != nil {
+		return err
+	}
+
+	if !stdinIsTerminal() {
+		return fmt.Errorf("%w as stdin is not a terminal: provide every value through flags", errPromptRefused)
+	}
+
+	header.PrintHeader()
+
+	return nil
+}
+
+// printResult prints res when the output is machine-readable. Otherwise nothing is
+// printed, as when commands are run with every value given through flags.
+func printResult(res result) error {
+	if machineReadable() {
+		return printOutput(res)
+	}
+
+	return nil
+}
+
+// printComplete prints the message shown when a command completed, or result when the
+// output is machine-readable.
+func printComplete(message string, res result) error {
+	if machineReadable() {
+		return printOutput(res)
+	}
+
+	fmt.Println(pp.Complete(message))
+
+	return nil
+}
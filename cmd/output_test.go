@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/engmtcdrm/mellon/env"
+	"github.com/engmtcdrm/mellon/secrets"
+)
+
+// runOutput runs the command and returns its stdout and stderr separately.
+func runOutput(args ...string) (string, string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command(testBinary, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+
+	return stdout.String(), stderr.String(), err
+}
+
+// TestOutputFormat tests creating, listing, viewing and deleting a secret with
+// --output-format, checking the output is a single JSON or YAML document.
+func TestOutputFormat(t *testing.T) {
+	env.Init()
+
+	secretName := "testoutputformat"
+
+	secretFile := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(secretFile, []byte("output value"), 0644); err != nil {
+		t.Fatalf("failed to write secret file: %v", err)
+	}
+
+	stdout, stderr, err := runOutput("create", "--secret", secretName, "--file", secretFile, "--tag", "output-test", "--output-format", "json")
+	if err != nil {
+		t.Fatalf("failed to create secret: %v, stderr: %s", err, stderr)
+	}
+	defer exec.Command(testBinary, "delete", "--secret", secretName, "--force", "--purge").Run()
+
+	var res result
+	if err := json.Unmarshal([]byte(stdout), &res); err != nil || res.Action != "created" || len(res.Secrets) != 1 || res.Secrets[0] != secretName {
+		t.Errorf("expected a created result, got: %s, error: %v", stdout, err)
+	}
+
+	stdout, stderr, err = runOutput("list", "--tag", "output-test", "--output-format", "json")
+	if err != nil {
+		t.Fatalf("failed to list secrets: %v, stderr: %s", err, stderr)
+	}
+
+	var infos []secretInfo
+	if err := json.Unmarshal([]byte(stdout), &infos); err != nil || len(infos) != 1 || infos[0].Name != secretName {
+		t.Errorf("expected the secret to be listed, got: %s, error: %v", stdout, err)
+	} else if len(infos[0].Tags) != 1 || infos[0].CreatedAt.IsZero() {
+		t.Errorf("expected the metadata of the secret to be listed, got: %s", stdout)
+	}
+
+	stdout, stderr, err = runOutput("view", "--secret", secretName, "--output-format", "json")
+	if err != nil {
+		t.Fatalf("failed to view secret: %v, stderr: %s", err, stderr)
+	}
+
+	var value secretValue
+	if err := json.Unmarshal([]byte(stdout), &value); err != nil || value.Name != secretName || value.Value != "output value" {
+		t.Errorf("expected the value of the secret, got: %s, error: %v", stdout, err)
+	}
+
+	stdout, _, err = runOutput("view", "--secret", secretName, "--encoding", "hex", "--output-format", "yaml")
+	expected := fmt.Sprintf("name: %s\nencoding: hex\nvalue: %x\n", secretName, "output value")
+	if err != nil || stdout != expected {
+		t.Errorf("expected YAML output %q, got: %q, error: %v", expected, stdout, err)
+	}
+
+	stdout, stderr, err = runOutput("delete", "--secret", secretName, "--force", "--purge", "--output-format", "json")
+	if err != nil {
+		t.Fatalf("failed to delete secret: %v, stderr: %s", err, stderr)
+	}
+
+	if err := json.Unmarshal([]byte(stdout), &res); err != nil || res.Action != "purged" {
+		t.Errorf("expected a purged result, got: %s, error: %v", stdout, err)
+	}
+}
+
+// TestOutputFormat_Errors tests that errors are written to stderr as objects holding
+// their code when --output-format is provided.
+func TestOutputFormat_Errors(t *testing.T) {
+	env.Init()
+
+	tests := []struct {
+		name string
+		args []string
+		code string
+	}{
+		{"missing secret", []string{"view", "--secret", "testoutputmissing", "--output-format", "json"}, codeNotFound},
+		{"invalid format", []string{"list", "--output-format", "xml"}, codeInvalidArgument},
+		{"unknown flag", []string{"list", "--unknown", "--output-format", "json"}, codeInvalidArgument},
+		{"invalid flags", []string{"view", "--version", "0", "--output-format", "json"}, codeInvalidArgument},
+		{"prompt refused", []string{"create", "--output-format", "json"}, codePromptRefused},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, err := runOutput(tt.args...)
+			if err == nil {
+				t.Fatalf("expected the command to fail, got: %s", stdout)
+			}
+
+			if stdout != "" {
+				t.Errorf("expected nothing on stdout, got: %s", stdout)
+			}
+
+			var out struct {
+				Error struct {
+					Code    string `json:"code"`
+					Message string `json:"message"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal([]byte(stderr), &out); err != nil {
+				t.Fatalf("expected a JSON error on stderr, got: %s, error: %v", stderr, err)
+			}
+
+			if out.Error.Code != tt.code || out.Error.Message == "" {
+				t.Errorf("expected code %s and a message, got: %s", tt.code, stderr)
+			}
+			if strings.Contains(out.Error.Message, "\x1b[") {
+				t.Errorf("expected the message without colours, got: %q", out.Error.Message)
+			}
+		})
+	}
+}
+
+// TestOutputFormat_ConfigError tests that errors occurring before the command runs,
+// such as an unreadable configuration, are reported like any other error.
+func TestOutputFormat_ConfigError(t *testing.T) {
+	env.Init()
+
+	configPath := env.Instance.ConfigPath()
+	original, err := os.ReadFile(configPath)
+	if err == nil {
+		defer os.WriteFile(configPath, original, 0600)
+	} else {
+		defer os.Remove(configPath)
+	}
+
+	if err := os.WriteFile(configPath, []byte("{not json"), 0600); err != nil {
+		t.Fatalf("failed to write configuration: %v", err)
+	}
+
+	stdout, stderr, err := runOutput("list", "--output-format", "json")
+	if err == nil {
+		t.Fatalf("expected the command to fail, got: %s", stdout)
+	}
+
+	if stdout != "" {
+		t.Errorf("expected nothing on stdout, got: %s", stdout)
+	}
+
+	var out struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(stderr), &out); err != nil {
+		t.Fatalf("expected a JSON error on stderr, got: %s, error: %v", stderr, err)
+	}
+
+	if out.Error.Code != codeError || !strings.Contains(out.Error.Message, "configuration file") {
+		t.Errorf("expected the configuration error, got: %s", stderr)
+	}
+}
+
+// TestErrorCode tests the codes errors are reported with.
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		code string
+	}{
+		{errors.New("failed"), codeError},
+		{fmt.Errorf("could not view: %w", secrets.ErrNotFound), codeNotFound},
+		{fmt.Errorf("could not rename: %w", secrets.ErrExists), codeAlreadyExists},
+		{fmt.Errorf("could not read: %w", fs.ErrPermission), codePermissionDenied},
+		{usageError(fmt.Errorf("bad flag: %w", secrets.ErrNotFound)), codeInvalidArgument},
+		{codedError{codeExpired, errors.New("2 secret(s) expired")}, codeExpired},
+	}
+
+	for _, tt := range tests {
+		if code := errorCode(tt.err); code != tt.code {
+			t.Errorf("expected code %s for %q, got: %s", tt.code, tt.err, code)
+		}
+	}
+}
+
+// TestPrompts_NotTerminal tests that commands fail fast instead of prompting when stdin
+// is not a terminal.
+func TestPrompts_NotTerminal(t *testing.T) {
+	env.Init()
+
+	for _, args := range [][]string{
+		{"view"},
+		{"delete"},
+		{"edit"},
+		{"history"},
+		{"rekey"},
+		{"passphrase", "add"},
+	} {
+		cmd := exec.Command(testBinary, args...)
+		cmd.Stdin = strings.NewReader("y\n")
+		output, err := cmd.CombinedOutput()
+		if err == nil || !strings.Contains(string(output), "stdin is not a terminal") {
+			t.Errorf("expected %v to refuse to prompt, got: %s, error: %v", args, output, err)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/engmtcdrm/go-pardon"
+	pp "github.com/engmtcdrm/go-prettyprint"
+	"github.com/engmtcdrm/mellon/app"
+	"github.com/engmtcdrm/mellon/env"
+	"github.com/engmtcdrm/mellon/header"
+	"github.com/engmtcdrm/mellon/secrets"
+)
+
+// passphraseEnv is the environment variable that can hold the passphrase for the
+// encryption key, so scripts can unlock it without being prompted.
+const passphraseEnv = "MELLON_PASSPHRASE"
+
+func init() {
+	passphraseCmd.AddCommand(passphraseAddCmd)
+	passphraseCmd.AddCommand(passphraseChangeCmd)
+	passphraseCmd.AddCommand(passphraseRemoveCmd)
+
+	rootCmd.AddCommand(passphraseCmd)
+}
+
+// askPassphrase returns the passphrase for the encryption key. It is read from the
+// environment variable MELLON_PASSPHRASE if set, otherwise the user is prompted.
+// The secrets package remembers the passphrase once it has unlocked the key, so the
+// user is prompted again only after entering a wrong one.
+func askPassphrase() ([]byte, error) {
+	if p, ok := os.LookupEnv(passphraseEnv); ok {
+		return []byte(p), nil
+	}
+
+	if machineReadable() {
+		return nil, fmt.Errorf("%w with --output-format: set %s to the passphrase for the encryption key", errPromptRefused, passphraseEnv)
+	}
+
+	if !stdinIsTerminal() {
+		return nil, fmt.Errorf("%w as stdin is not a terminal: set %s to the passphrase for the encryption key", errPromptRefused, passphraseEnv)
+	}
+
+	var pass []byte
+	promptPass := pardon.NewPassword(&pass).
+		Title("Enter the passphrase for the encryption key:")
+
+	if err := promptPass.Ask(); err != nil {
+		return nil, err
+	}
+
+	fmt.Println()
+
+	return pass, nil
+}
+
+// askNewPassphrase prompts for a new passphrase twice and makes sure both entries match.
+func askNewPassphrase() ([]byte, error) {
+	return promptNewPassphrase("Enter the new passphrase:", "Confirm the new passphrase:")
+}
+
+// promptNewPassphrase prompts for a passphrase with title, then again with
+// confirmTitle, and makes sure both entries match.
+func promptNewPassphrase(title string, confirmTitle string) ([]byte, error) {
+	var pass []byte
+	promptPass := pardon.NewPassword(&pass).
+		Title(title).
+		Validate(func(b []byte) error {
+			if len(b) == 0 {
+				return errors.New("passphrase cannot be empty")
+			}
+			return nil
+		})
+
+	if err := promptPass.Ask(); err != nil {
+		return nil, err
+	}
+
+	fmt.Println()
+
+	var confirmPass []byte
+	promptConfirm := pardon.NewPassword(&confirmPass).
+		Title(confirmTitle)
+
+	if err := promptConfirm.Ask(); err != nil {
+		return nil, err
+	}
+
+	fmt.Println()
+
+	if !bytes.Equal(pass, confirmPass) {
+		secrets.ClearSecret(&pass)
+		secrets.ClearSecret(&confirmPass)
+		return nil, errors.New("passphrases do not match")
+	}
+
+	secrets.ClearSecret(&confirmPass)
+
+	return pass, nil
+}
+
+var passphraseCmd = &cobra.Command{
+	Use:   "passphrase",
+	Short: "Manage the passphrase protecting the encryption key",
+	Long: fmt.Sprintf(
+		"Manage the passphrase protecting the encryption key.\n\nWhen the encryption key is protected by a passphrase, it has to be entered before secrets can be created, viewed or updated. The passphrase can also be provided through the environment variable %s.",
+		passphraseEnv,
+	),
+	Example: fmt.Sprintf("  %s passphrase add\n  %s passphrase change\n  %s passphrase remove", app.Name, app.Name, app.Name),
+}
+
+var passphraseAddCmd = &cobra.Command{
+	Use:     "add",
+	Short:   "Protect the encryption key with a passphrase",
+	Long:    "Protect the encryption key with a passphrase",
+	Example: fmt.Sprintf("  %s passphrase add", app.Name),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := promptHeader(); err != nil {
+			return err
+		}
+
+		locked, err := secrets.IsKeyLocked(env.Instance.KeyPath())
+		if err != nil {
+			return err
+		}
+
+		if locked {
+			return fmt.Errorf("encryption key is already protected by a passphrase\n\nUse command %s to change it", pp.Greenf("%s passphrase change", env.Instance.ExeCmd()))
+		}
+
+		pass, err := askNewPassphrase()
+		if err != nil {
+			return err
+		}
+		defer secrets.ClearSecret(&pass)
+
+		if err := secrets.LockKey(env.Instance.KeyPath(), pass); err != nil {
+			return fmt.Errorf("could not add passphrase: %w", err)
+		}
+
+		return printComplete("Encryption key protected with passphrase", result{Action: "passphrase_added", Secrets: []string{}})
+	},
+}
+
+var passphraseChangeCmd = &cobra.Command{
+	Use:     "change",
+	Short:   "Change the passphrase protecting the encryption key",
+	Long:    "Change the passphrase protecting the encryption key",
+	Example: fmt.Sprintf("  %s passphrase change", app.Name),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := promptHeader(); err != nil {
+			return err
+		}
+
+		if err := requireLockedKey(); err != nil {
+			return err
+		}
+
+		oldPass, err := askPassphrase()
+		if err != nil {
+			return err
+		}
+
+		newPass, err := askNewPassphrase()
+		if err != nil {
+			return err
+		}
+		defer secrets.ClearSecret(&newPass)
+
+		if err := secrets.ChangeKeyPassphrase(env.Instance.KeyPath(), oldPass, newPass); err != nil {
+			return fmt.Errorf("could not change passphrase: %w", err)
+		}
+
+		return printComplete("Passphrase changed", result{Action: "passphrase_changed", Secrets: []string{}})
+	},
+}
+
+var passphraseRemoveCmd = &cobra.Command{
+	Use:     "remove",
+	Short:   "Remove the passphrase protecting the encryption key",
+	Long:    "Remove the passphrase protecting the encryption key",
+	Example: fmt.Sprintf("  %s passphrase remove", app.Name),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !machineReadable() && stdinIsTerminal() {
+			header.PrintHeader()
+		}
+
+		if err := requireLockedKey(); err != nil {
+			return err
+		}
+
+		pass, err := askPassphrase()
+		if err != nil {
+			return err
+		}
+
+		if err := secrets.UnlockKey(env.Instance.KeyPath(), pass); err != nil {
+			return fmt.Errorf("could not remove passphrase: %w", err)
+		}
+
+		return printComplete("Passphrase removed from encryption key", result{Action: "passphrase_removed", Secrets: []string{}})
+	},
+}
+
+// requireLockedKey returns an error if the encryption key is not protected by a passphrase.
+func requireLockedKey() error {
+	locked, err := secrets.IsKeyLocked(env.Instance.KeyPath())
+	if err != nil {
+		return err
+	}
+
+	if !locked {
+		return fmt.Errorf("encryption key is not protected by a passphrase\n\nUse command %s to add one", pp.Greenf("%s passphrase add", env.Instance.ExeCmd()))
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/engmtcdrm/mellon/env"
+	"github.com/engmtcdrm/mellon/secrets"
+)
+
+// TestPassphraseCommand_AddChangeRemove tests protecting the key with a passphrase,
+// using it to view a secret, changing it and removing it again. Passphrases can only
+// be entered at a terminal, so they are set through the secrets package instead.
+func TestPassphraseCommand_AddChangeRemove(t *testing.T) {
+	env.Init()
+
+	secretFile := filepath.Join(t.TempDir(), "secret.txt")
+	secretName := "testpassphrase"
+	secretContent := "passphrasesecretcontent"
+	secretOut := filepath.Join(env.Instance.SecretsPath(), secretName+env.Instance.SecretExt())
+
+	// Clean up before test
+	os.Remove(secretOut)
+	defer os.Remove(secretOut)
+
+	// Make sure the key is left unprotected for other tests
+	defer func() {
+		for _, p := range []string{"first-passphrase", "second-passphrase"} {
+			if locked, _ := secrets.IsKeyLocked(env.Instance.KeyPath()); locked {
+				secrets.UnlockKey(env.Instance.KeyPath(), []byte(p))
+			}
+		}
+	}()
+
+	if err := os.WriteFile(secretFile, []byte(secretContent), 0644); err != nil {
+		t.Fatalf("failed to write secret file: %v", err)
+	}
+
+	createCmd := exec.Command(testBinary, "create", "--secret", secretName, "--file", secretFile)
+	if output, err := createCmd.CombinedOutput(); err != nil {
+		t.Fatalf("failed to create initial secret: %v, output: %s", err, output)
+	}
+
+	// Removing a passphrase that was never added fails
+	cmd := exec.Command(testBinary, "passphrase", "remove")
+	if _, err := cmd.CombinedOutput(); err == nil {
+		t.Errorf("expected error removing passphrase from unprotected key, got none")
+	}
+
+	// Passphrases are never read from stdin when it is not a terminal
+	cmd = exec.Command(testBinary, "passphrase", "add")
+	cmd.Stdin = strings.NewReader("first-passphrase\nfirst-passphrase\n")
+	if output, err := cmd.CombinedOutput(); err == nil || !strings.Contains(string(output), "stdin is not a terminal") {
+		t.Errorf("expected adding a passphrase to refuse to prompt, got: %s, error: %v", output, err)
+	}
+
+	if err := secrets.LockKey(env.Instance.KeyPath(), []byte("first-passphrase")); err != nil {
+		t.Fatalf("failed to add passphrase: %v", err)
+	}
+
+	if locked, err := secrets.IsKeyLocked(env.Instance.KeyPath()); err != nil || !locked {
+		t.Fatalf("expected key to be protected by a passphrase")
+	}
+
+	// View with the passphrase from the environment
+	cmd = exec.Command(testBinary, "view", "--secret", secretName)
+	cmd.Env = append(os.Environ(), passphraseEnv+"=first-passphrase")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("expected success viewing secret with passphrase, got error: %v, output: %s", err, output)
+	}
+
+	if string(output) != secretContent {
+		t.Errorf("expected secret content '%s', got '%s'", secretContent, output)
+	}
+
+	// View with an incorrect passphrase
+	cmd = exec.Command(testBinary, "view", "--secret", secretName)
+	cmd.Env = append(os.Environ(), passphraseEnv+"=wrong-passphrase")
+	if _, err := cmd.CombinedOutput(); err == nil {
+		t.Errorf("expected error viewing secret with incorrect passphrase, got none")
+	}
+
+	cmd = exec.Command(testBinary, "passphrase", "change")
+	cmd.Stdin = strings.NewReader("first-passphrase\nsecond-passphrase\nsecond-passphrase\n")
+	if output, err := cmd.CombinedOutput(); err == nil || !strings.Contains(string(output), "stdin is not a terminal") {
+		t.Errorf("expected changing the passphrase to refuse to prompt, got: %s, error: %v", output, err)
+	}
+
+	if err := secrets.ChangeKeyPassphrase(env.Instance.KeyPath(), []byte("first-passphrase"), []byte("second-passphrase")); err != nil {
+		t.Fatalf("failed to change passphrase: %v", err)
+	}
+
+	// Without the passphrase in the environment, view refuses to prompt for it
+	cmd = exec.Command(testBinary, "view", "--secret", secretName)
+	cmd.Stdin = strings.NewReader("second-passphrase\n")
+	output, err = cmd.CombinedOutput()
+	if err == nil || !strings.Contains(string(output), passphraseEnv) {
+		t.Errorf("expected view to ask for %s instead of prompting, got: %s, error: %v", passphraseEnv, output, err)
+	}
+
+	cmd = exec.Command(testBinary, "view", "--secret", secretName)
+	cmd.Env = append(os.Environ(), passphraseEnv+"=second-passphrase")
+	output, err = cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("expected success viewing secret with changed passphrase, got error: %v, output: %s", err, output)
+	}
+
+	if string(output) != secretContent {
+		t.Errorf("expected secret content '%s', got '%s'", secretContent, output)
+	}
+
+	cmd = exec.Command(testBinary, "passphrase", "remove")
+	cmd.Env = append(os.Environ(), passphraseEnv+"=second-passphrase")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("expected success removing passphrase, got error: %v, output: %s", err, output)
+	}
+
+	if locked, err := secrets.IsKeyLocked(env.Instance.KeyPath()); err != nil || locked {
+		t.Fatalf("expected key to no longer be protected by a passphrase")
+	}
+
+	cmd = exec.Command(testBinary, "view", "--secret", secretName)
+	output, err = cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("expected success viewing secret after removing passphrase, got error: %v, output: %s", err, output)
+	}
+
+	if string(output) != secretContent {
+		t.Errorf("expected secret content '%s', got '%s'", secretContent, output)
+	}
+}
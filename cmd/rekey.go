@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/engmtcdrm/go-pardon"
+	pp "github.com/engmtcdrm/go-prettyprint"
+	"github.com/engmtcdrm/mellon/app"
+	"github.com/engmtcdrm/mellon/env"
+	"github.com/engmtcdrm/mellon/secrets"
+)
+
+func init() {
+	rekeyCmd.Flags().BoolVarP(
+		&forceRekey,
+		"force",
+		"f",
+		false,
+		"(optional) Whether to rekey without confirmation",
+	)
+
+	rootCmd.AddCommand(rekeyCmd)
+}
+
+var rekeyCmd = &cobra.Command{
+	Use:     "rekey",
+	Short:   "Rotate the encryption key",
+	Long:    "Rotate the encryption key.\n\nA new encryption key is generated and every secret is re-encrypted with it, along with the secrets in the trash and in the snapshots. If any secret fails to re-encrypt, all changes are rolled back and the existing key is kept.",
+	Example: fmt.Sprintf("  %s rekey\n  %s rekey --force", app.Name, app.Name),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !forceRekey {
+			if err := promptHeader(); err != nil {
+				return err
+			}
+
+			confirmRekey := false
+			promptConfirm := pardon.NewConfirm(&confirmRekey).
+				Title(fmt.Sprintf("Are you sure you want to rotate the encryption key and re-encrypt %d secret(s)?", len(secretFiles)))
+
+			if err := promptConfirm.Ask(); err != nil {
+				return err
+			}
+
+			fmt.Println()
+
+			if !confirmRekey {
+				fmt.Println(pp.Fail("Aborted rotating the encryption key"))
+				return nil
+			}
+		}
+
+		// Only removing the files replaced can fail once the new key is in place
+		if err := secrets.Rekey(env.Instance.KeyPath(), secretFiles); errors.Is(err, secrets.ErrRekeyCleanup) {
+			warnRekeyCleanup(err)
+		} else if err != nil {
+			return fmt.Errorf("could not rotate encryption key, no changes were made: %w", err)
+		}
+
+		if !forceRekey {
+			fmt.Println(pp.Complete("Encryption key rotated and secrets re-encrypted"))
+		}
+
+		return printResult(result{Action: "rekeyed", Secrets: secretNames(secretFiles)})
+	},
+}
+
+// warnRekeyCleanup reports that a rekey finished, but the files it replaced could not
+// all be removed. Their removal is retried the next time mellon runs.
+func warnRekeyCleanup(err error) {
+	fmt.Fprintln(os.Stderr, pp.Alertf("%s\n\nTheir removal is retried the next time %s runs", err, app.Name))
+}
@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/engmtcdrm/mellon/env"
+)
+
+// TestRekeyCommand_ReencryptsSecrets tests that secrets can still be viewed after a rekey.
+func TestRekeyCommand_ReencryptsSecrets(t *testing.T) {
+	env.Init()
+
+	secretFile := filepath.Join(t.TempDir(), "secret.txt")
+	secretName := "testrekey"
+	secretContent := "rekeysecretcontent"
+	secretOut := filepath.Join(env.Instance.SecretsPath(), secretName+env.Instance.SecretExt())
+
+	// Clean up before test
+	os.Remove(secretOut)
+	defer os.Remove(secretOut)
+
+	if err := os.WriteFile(secretFile, []byte(secretContent), 0644); err != nil {
+		t.Fatalf("failed to write secret file: %v", err)
+	}
+
+	createCmd := exec.Command(testBinary, "create", "--secret", secretName, "--file", secretFile)
+	if output, err := createCmd.CombinedOutput(); err != nil {
+		t.Fatalf("failed to create initial secret: %v, output: %s", err, output)
+	}
+
+	oldKey, err := os.ReadFile(env.Instance.KeyPath())
+	if err != nil {
+		t.Fatalf("failed to read key file: %v", err)
+	}
+
+	oldSecret, err := os.ReadFile(secretOut)
+	if err != nil {
+		t.Fatalf("failed to read secret file: %v", err)
+	}
+
+	cmd := exec.Command(testBinary, "rekey", "--force")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("expected success for rekey, got error: %v, output: %s", err, output)
+	}
+
+	newKey, err := os.ReadFile(env.Instance.KeyPath())
+	if err != nil {
+		t.Fatalf("failed to read key file: %v", err)
+	}
+
+	if bytes.Equal(oldKey, newKey) {
+		t.Errorf("expected key file to change after rekey")
+	}
+
+	newSecret, err := os.ReadFile(secretOut)
+	if err != nil {
+		t.Fatalf("failed to read secret file: %v", err)
+	}
+
+	if bytes.Equal(oldSecret, newSecret) {
+		t.Errorf("expected secret file to be re-encrypted after rekey")
+	}
+
+	if _, err := os.Stat(env.Instance.KeyPath() + ".old"); !os.IsNotExist(err) {
+		t.Errorf("expected previous key to be removed after rekey")
+	}
+
+	viewCmd := exec.Command(testBinary, "view", "--secret", secretName)
+	output, err := viewCmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("expected success viewing secret after rekey, got error: %v, output: %s", err, output)
+	}
+
+	if string(output) != secretContent {
+		t.Errorf("expected secret content '%s' after rekey, got '%s'", secretContent, output)
+	}
+}
+
+// TestRekeyCommand_RollbackOnFailure tests that a failed rekey leaves the key and secrets untouched.
+func TestRekeyCommand_RollbackOnFailure(t *testing.T) {
+	env.Init()
+
+	secretFile := filepath.Join(t.TempDir(), "secret.txt")
+	secretName := "testrekeyrollback"
+	secretContent := "rollbacksecretcontent"
+	secretOut := filepath.Join(env.Instance.SecretsPath(), secretName+env.Instance.SecretExt())
+	corruptOut := filepath.Join(env.Instance.SecretsPath(), "testrekeycorrupt"+env.Instance.SecretExt())
+
+	// Clean up before test
+	os.Remove(secretOut)
+	defer os.Remove(secretOut)
+	defer os.Remove(corruptOut)
+
+	if err := os.WriteFile(secretFile, []byte(secretContent), 0644); err != nil {
+		t.Fatalf("failed to write secret file: %v", err)
+	}
+
+	createCmd := exec.Command(testBinary, "create", "--secret", secretName, "--file", secretFile)
+	if output, err := createCmd.CombinedOutput(); err != nil {
+		t.Fatalf("failed to create initial secret: %v, output: %s", err, output)
+	}
+
+	// A secret that cannot be decrypted forces the rekey to fail
+	garbage := make([]byte, 4096)
+	rand.Read(garbage)
+	if err := os.WriteFile(corruptOut, garbage, 0600); err != nil {
+		t.Fatalf("failed to write corrupt secret: %v", err)
+	}
+
+	oldKey, err := os.ReadFile(env.Instance.KeyPath())
+	if err != nil {
+		t.Fatalf("failed to read key file: %v", err)
+	}
+
+	cmd := exec.Command(testBinary, "rekey", "--force")
+	if _, err := cmd.CombinedOutput(); err == nil {
+		t.Fatalf("expected error for rekey with corrupt secret, got none")
+	}
+
+	newKey, err := os.ReadFile(env.Instance.KeyPath())
+	if err != nil {
+		t.Fatalf("failed to read key file: %v", err)
+	}
+
+	if !bytes.Equal(oldKey, newKey) {
+		t.Errorf("expected key file to be unchanged after failed rekey")
+	}
+
+	for _, p := range []string{env.Instance.KeyPath() + ".new", secretOut + ".new"} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("expected staged file '%s' to be removed after failed rekey", p)
+		}
+	}
+
+	viewCmd := exec.Command(testBinary, "view", "--secret", secretName)
+	output, err := viewCmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("expected success viewing secret after failed rekey, got error: %v, output: %s", err, output)
+	}
+
+	if string(output) != secretContent {
+		t.Errorf("expected secret content '%s' after failed rekey, got '%s'", secretContent, output)
+	}
+}
+
+// TestRekeyCommand_Trash tests that a deleted secret can still be restored and viewed,
+// along with its history, after a rekey.
+func TestRekeyCommand_Trash(t *testing.T) {
+	env.Init()
+
+	secretFile := filepath.Join(t.TempDir(), "secret.txt")
+	secretName := "testrekeytrash"
+
+	for _, content := range []string{"first", "second"} {
+		if err := os.WriteFile(secretFile, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write secret file: %v", err)
+		}
+
+		command := "update"
+		if content == "first" {
+			command = "create"
+		}
+
+		if output, err := exec.Command(testBinary, command, "--secret", secretName, "--file", secretFile).CombinedOutput(); err != nil {
+			t.Fatalf("failed to %s secret: %v, output: %s", command, err, output)
+		}
+	}
+	defer exec.Command(testBinary, "delete", "--secret", secretName, "--force", "--purge").Run()
+
+	if output, err := exec.Command(testBinary, "delete", "--secret", secretName, "--force").CombinedOutput(); err != nil {
+		t.Fatalf("failed to delete secret: %v, output: %s", err, output)
+	}
+
+	if output, err := exec.Command(testBinary, "rekey", "--force").CombinedOutput(); err != nil {
+		t.Fatalf("expected success for rekey, got error: %v, output: %s", err, output)
+	}
+
+	if output, err := exec.Command(testBinary, "restore", "--secret", secretName).CombinedOutput(); err != nil {
+		t.Fatalf("failed to restore secret from the trash: %v, output: %s", err, output)
+	}
+
+	output, err := exec.Command(testBinary, "view", "--secret", secretName).CombinedOutput()
+	if err != nil || string(output) != "second" {
+		t.Errorf("expected the restored secret after rekey, got: %s, error: %v", output, err)
+	}
+
+	output, err = exec.Command(testBinary, "view", "--secret", secretName, "--version", "1").CombinedOutput()
+	if err != nil || string(output) != "first" {
+		t.Errorf("expected the history of the restored secret after rekey, got: %s, error: %v", output, err)
+	}
+}
+
+// TestRekeyCommand_Snapshots tests that a secret deleted before a rekey can still be
+// brought back from a snapshot and viewed after the rekey.
+func TestRekeyCommand_Snapshots(t *testing.T) {
+	env.Init()
+
+	secretName := "testrekeysnapshots"
+
+	if output, err := exec.Command(testBinary, "create", "--secret", secretName, "--field", fieldFile(t, "token", "snap")).CombinedOutput(); err != nil {
+		t.Fatalf("failed to create secret: %v, output: %s", err, output)
+	}
+	defer exec.Command(testBinary, "delete", "--secret", secretName, "--force", "--purge").Run()
+
+	if output, err := exec.Command(testBinary, "delete", "--secret", secretName, "--force").CombinedOutput(); err != nil {
+		t.Fatalf("failed to delete secret: %v, output: %s", err, output)
+	}
+
+	// Taken by the delete, listed first as the most recent
+	output, err := exec.Command(testBinary, "snapshots", "list", "--print").Output()
+	ids := strings.Fields(string(output))
+	if err != nil || len(ids) == 0 {
+		t.Fatalf("expected a snapshot to be taken before deleting, got: %s, error: %v", output, err)
+	}
+
+	if output, err := exec.Command(testBinary, "rekey", "--force").CombinedOutput(); err != nil {
+		t.Fatalf("expected success for rekey, got error: %v, output: %s", err, output)
+	}
+
+	if output, err := exec.Command(testBinary, "snapshots", "restore", ids[0], "--force").CombinedOutput(); err != nil {
+		t.Fatalf("failed to restore snapshot: %v, output: %s", err, output)
+	}
+
+	output, err = exec.Command(testBinary, "view", "--secret", secretName, "--field", "token").CombinedOutput()
+	if err != nil || string(output) != "snap" {
+		t.Errorf("expected the secret restored from the snapshot after rekey, got: %s, error: %v", output, err)
+	}
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	pp "github.com/engmtcdrm/go-prettyprint"
+	"github.com/engmtcdrm/mellon/app"
+	"github.com/engmtcdrm/mellon/env"
+	"github.com/engmtcdrm/mellon/secrets"
+)
+
+func init() {
+	rootCmd.AddCommand(renameCmd)
+}
+
+// relocation is a secret to rename or copy along with its new name.
+type relocation struct {
+	secret  secrets.Secret
+	newName string
+}
+
+// planRelocations works out the new name of every secret to rename or copy from src
+// to dst. A src ending with a slash selects every secret in that namespace, which is
+// then moved under the dst namespace. A single secret with a dst ending with a slash
+// keeps its base name in the dst namespace.
+func planRelocations(src string, dst string) ([]relocation, error) {
+	if !strings.HasSuffix(src, "/") {
+		secretPtr := secrets.FindSecretByName(src, secretFiles)
+		if secretPtr == nil {
+			return nil, fmt.Errorf("secret '%s' %w. To select a namespace, add a trailing slash, e.g. %s/", src, secrets.ErrNotFound, src)
+		}
+
+		if strings.HasSuffix(dst, "/") {
+			dst += path.Base(src)
+		}
+
+		return []relocation{{secret: *secretPtr, newName: dst}}, nil
+	}
+
+	dst = strings.TrimSuffix(dst, "/")
+	if dst == "" {
+		return nil, errors.New("the destination namespace cannot be empty")
+	}
+
+	var plan []relocation
+	for _, secret := range secretFiles {
+		if rest, ok := strings.CutPrefix(secret.Name(), src); ok {
+			plan = append(plan, relocation{secret: secret, newName: dst + "/" + rest})
+		}
+	}
+
+	if len(plan) == 0 {
+		return nil, fmt.Errorf("no secrets found in namespace '%s'", src)
+	}
+
+	// Check every new name before touching anything
+	for _, r := range plan {
+		if err := secrets.ValidateName(r.newName); err != nil {
+			return nil, fmt.Errorf("%w. The secret name would be '%s'", err, r.newName)
+		}
+
+		if secrets.FindSecretByName(r.newName, secretFiles) != nil {
+			return nil, fmt.Errorf("secret '%s' %w", r.newName, secrets.ErrExists)
+		}
+	}
+
+	return plan, nil
+}
+
+// renameAll renames every secret in plan. If one of them cannot be renamed, the
+// secrets already renamed are moved back, so a namespace is never left half renamed.
+func renameAll(plan []relocation) (err error) {
+	var undo []relocation // Renamed secrets along with their original names
+	defer func() {
+		if err == nil {
+			return
+		}
+
+		for i := len(undo) - 1; i >= 0; i-- {
+			if _, undoErr := secrets.RenameSecret(env.Instance.SecretsPath(), undo[i].secret, undo[i].newName); undoErr != nil {
+				err = fmt.Errorf("%w\n\nCould not move secret '%s' back to '%s': %w", err, undo[i].secret.Name(), undo[i].newName, undoErr)
+			}
+		}
+	}()
+
+	for _, r := range plan {
+		renamed, err := secrets.RenameSecret(env.Instance.SecretsPath(), r.secret, r.newName)
+		if renamed != nil {
+			undo = append(undo, relocation{secret: *renamed, newName: r.secret.Name()})
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sourceCompletion completes secret names for the first argument only.
+func sourceCompletion(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	return secretFlagCompletion(cmd, args, toComplete)
+}
+
+var renameCmd = &cobra.Command{
+	Use:               "rename <secret> <new-name>",
+	Aliases:           []string{"mv"},
+	Short:             "Rename a secret",
+	Long:              "Rename a secret.\n\nThe encrypted secret is moved along with its metadata and history, without decrypting it. To rename every secret in a namespace, end both names with a slash. If any of them cannot be renamed, the ones already renamed are moved back.",
+	Example:           fmt.Sprintf("  %s rename db-password db/password\n  %s mv prod/ production/", app.Name, app.Name),
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: sourceCompletion,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		plan, err := planRelocations(args[0], args[1])
+		if err != nil {
+			return err
+		}
+
+		if err := renameAll(plan); err != nil {
+			return err
+		}
+
+		res := result{Action: "renamed", Secrets: []string{}, Sources: []string{}}
+
+		for _, r := range plan {
+			res.Secrets = append(res.Secrets, r.newName)
+			res.Sources = append(res.Sources, r.secret.Name())
+
+			if !machineReadable() {
+				fmt.Println(pp.Completef("Renamed %s to %s", r.secret.Name(), pp.Green(r.newName)))
+			}
+		}
+
+		return printResult(res)
+	},
+}
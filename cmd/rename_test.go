@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/engmtcdrm/mellon/env"
+)
+
+// TestRenameCopyCommands tests renaming and copying secrets and namespaces.
+func TestRenameCopyCommands(t *testing.T) {
+	env.Init()
+
+	secretFile := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(secretFile, []byte("renamecontent"), 0644); err != nil {
+		t.Fatalf("failed to write secret file: %v", err)
+	}
+
+	for _, name := range []string{"testrename/ns/a", "testrename/ns/b"} {
+		if output, err := exec.Command(testBinary, "create", "--secret", name, "--file", secretFile).CombinedOutput(); err != nil {
+			t.Fatalf("failed to create secret '%s': %v, output: %s", name, err, output)
+		}
+	}
+
+	defer func() {
+		for _, name := range []string{"testrename/ns/a", "testrename/ns/b", "testrename/moved/a", "testrename/moved/b", "testrename/copy", "testrename/renamed"} {
+			exec.Command(testBinary, "delete", "--secret", name, "--force").Run()
+		}
+	}()
+
+	if output, err := exec.Command(testBinary, "mv", "testrename/ns/", "testrename/moved/").CombinedOutput(); err != nil {
+		t.Fatalf("failed to rename namespace: %v, output: %s", err, output)
+	}
+
+	if _, err := os.Stat(filepath.Join(env.Instance.SecretsPath(), "testrename", "ns")); !os.IsNotExist(err) {
+		t.Errorf("expected empty namespace directory to be removed, got: %v", err)
+	}
+
+	output, err := exec.Command(testBinary, "list", "--print").Output()
+	if err != nil {
+		t.Fatalf("failed to list secrets: %v", err)
+	}
+
+	if !strings.Contains(string(output), "testrename/moved/a") || !strings.Contains(string(output), "testrename/moved/b") || strings.Contains(string(output), "testrename/ns/") {
+		t.Errorf("expected secrets to be moved to the new namespace, got: %s", output)
+	}
+
+	if output, err := exec.Command(testBinary, "cp", "testrename/moved/a", "testrename/copy").CombinedOutput(); err != nil {
+		t.Fatalf("failed to copy secret: %v, output: %s", err, output)
+	}
+
+	if output, err := exec.Command(testBinary, "rename", "testrename/moved/b", "testrename/renamed").CombinedOutput(); err != nil {
+		t.Fatalf("failed to rename secret: %v, output: %s", err, output)
+	}
+
+	for _, name := range []string{"testrename/moved/a", "testrename/copy", "testrename/renamed"} {
+		output, err := exec.Command(testBinary, "view", "--secret", name).Output()
+		if err != nil || string(output) != "renamecontent" {
+			t.Errorf("expected secret '%s' to hold the original content, got: %s, error: %v", name, output, err)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"rename", "testrename/copy", "testrename/moved/a"},
+		{"rename", "testrename/missing", "testrename/other"},
+		{"copy", "testrename/missing/", "testrename/other/"},
+		{"copy", "testrename/copy", "invalid name"},
+		{"rename", "testrename/copy"},
+	} {
+		if output, err := exec.Command(testBinary, args...).CombinedOutput(); err == nil {
+			t.Errorf("expected error for %v, got output: %s", args, output)
+		}
+	}
+}
+
+// TestRenameCommand_Rollback tests that a namespace rename failing partway moves the
+// secrets already renamed back.
+func TestRenameCommand_Rollback(t *testing.T) {
+	env.Init()
+
+	secretFile := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(secretFile, []byte("rollbackcontent"), 0644); err != nil {
+		t.Fatalf("failed to write secret file: %v", err)
+	}
+
+	names := []string{"testrenameundo/ns/a", "testrenameundo/ns/z/x"}
+	for _, name := range names {
+		if output, err := exec.Command(testBinary, "create", "--secret", name, "--file", secretFile).CombinedOutput(); err != nil {
+			t.Fatalf("failed to create secret '%s': %v, output: %s", name, err, output)
+		}
+	}
+
+	// A file where a namespace has to be created makes the second rename fail
+	blocker := filepath.Join(env.Instance.SecretsPath(), "testrenameundo", "moved", "z")
+	if err := os.MkdirAll(filepath.Dir(blocker), 0700); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(blocker, nil, 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	defer func() {
+		os.Remove(blocker)
+		for _, name := range append(names, "testrenameundo/moved/a", "testrenameundo/moved/z/x") {
+			exec.Command(testBinary, "delete", "--secret", name, "--force").Run()
+		}
+	}()
+
+	if output, err := exec.Command(testBinary, "mv", "testrenameundo/ns/", "testrenameundo/moved/").CombinedOutput(); err == nil {
+		t.Fatalf("expected the rename to fail, got: %s", output)
+	}
+
+	output, err := exec.Command(testBinary, "list", "--print").Output()
+	if err != nil {
+		t.Fatalf("failed to list secrets: %v", err)
+	}
+
+	if strings.Contains(string(output), "testrenameundo/moved/") {
+		t.Errorf("expected no secret to be left renamed, got: %s", output)
+	}
+
+	for _, name := range names {
+		output, err := exec.Command(testBinary, "view", "--secret", name).Output()
+		if err != nil || string(output) != "rollbackcontent" {
+			t.Errorf("expected secret '%s' to be moved back, got: %q, error: %v", name, output, err)
+		}
+	}
+}
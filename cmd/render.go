@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cobra"
+
+	"github.com/engmtcdrm/mellon/app"
+	"github.com/engmtcdrm/mellon/env"
+	"github.com/engmtcdrm/mellon/secrets"
+)
+
+func init() {
+	renderCmd.Flags().StringVarP(
+		&templateFile,
+		"template",
+		"t",
+		"",
+		"The Go text/template file to render",
+	)
+	renderCmd.Flags().StringVarP(
+		&output,
+		"output",
+		"o",
+		"",
+		"(optional) File to write the rendered template to. Defaults to outputting to stdout",
+	)
+	addAllowExpiredFlag(renderCmd, "(optional) Whether to allow rendering secrets that have expired")
+
+	renderCmd.MarkFlagRequired("template")
+	renderCmd.MarkFlagFilename("template")
+	renderCmd.MarkFlagFilename("output")
+
+	rootCmd.AddCommand(renderCmd)
+}
+
+// templateResolver looks up the secrets used by a template, remembering every
+// reference that could not be resolved so they can be reported together.
+type templateResolver struct {
+	values     map[string][]byte // Decrypted secrets by name
+	unresolved []string          // References that could not be resolved
+	errs       []error           // Why the references could not be resolved
+}
+
+// secret returns the value of the named secret.
+func (r *templateResolver) secret(name string) string {
+	value, ok := r.lookup(name)
+	if !ok {
+		return ""
+	}
+
+	return string(value)
+}
+
+// field returns the value of a field of the named structured secret.
+func (r *templateResolver) field(name string, field string) string {
+	value, ok := r.lookup(name)
+	if !ok {
+		return ""
+	}
+
+	fieldValue, err := secrets.Field(value, field)
+	if err != nil {
+		r.fail(name+"#"+field, err)
+		return ""
+	}
+
+	return fieldValue
+}
+
+// lookup decrypts the named secret once and caches its value.
+func (r *templateResolver) lookup(name string) ([]byte, bool) {
+	if value, ok := r.values[name]; ok {
+		return value, true
+	}
+
+	secretPtr := secrets.FindSecretByName(name, secretFiles)
+	if secretPtr == nil {
+		r.fail(name, fmt.Errorf("secret %w", secrets.ErrNotFound))
+		return nil, false
+	}
+
+	value, err := secretPtr.Decrypt()
+	if err != nil {
+		r.fail(name, err)
+		return nil, false
+	}
+
+	r.values[name] = value
+
+	return value, true
+}
+
+// fail records a reference that could not be resolved, once.
+func (r *templateResolver) fail(ref string, err error) {
+	if slices.Contains(r.unresolved, ref) {
+		return
+	}
+
+	r.unresolved = append(r.unresolved, ref)
+	r.errs = append(r.errs, fmt.Errorf("%s: %w", ref, err))
+}
+
+// clear wipes every decrypted secret from memory.
+func (r *templateResolver) clear() {
+	for name, value := range r.values {
+		secrets.ClearSecret(&value)
+		delete(r.values, name)
+	}
+}
+
+// funcs returns the functions available in templates.
+func (r *templateResolver) funcs() template.FuncMap {
+	encodeFunc := func(encoding string) func(string) (string, error) {
+		return func(s string) (string, error) {
+			return secrets.Encode([]byte(s), encoding)
+		}
+	}
+
+	return template.FuncMap{
+		"secret":    r.secret,
+		"field":     r.field,
+		"base64":    encodeFunc("base64"),
+		"hex":       encodeFunc("hex"),
+		"urlencode": encodeFunc("url"),
+	}
+}
+
+// renderTemplate renders the template file with the secrets it references. Nothing is
+// returned if any reference cannot be resolved.
+func renderTemplate(path string) ([]byte, error) {
+	path, err := env.ExpandTilde(path)
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read template '%s': %w", path, err)
+	}
+
+	resolver := &templateResolver{values: map[string][]byte{}}
+	defer resolver.clear()
+
+	tmpl, err := template.New(filepath.Base(path)).Funcs(resolver.funcs()).Parse(string(text))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse template: %w", err)
+	}
+
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, nil); err != nil {
+		return nil, fmt.Errorf("could not render template: %w", err)
+	}
+
+	if len(resolver.unresolved) > 0 {
+		clearBuffer(&rendered)
+		return nil, fmt.Errorf("could not render template, %d reference(s) could not be resolved: %s\n%w", len(resolver.unresolved), strings.Join(resolver.unresolved, ", "), errors.Join(resolver.errs...))
+	}
+
+	return rendered.Bytes(), nil
+}
+
+// clearBuffer overwrites the contents of a buffer with zeros.
+func clearBuffer(b *bytes.Buffer) {
+	data := b.Bytes()
+	secrets.ClearSecret(&data)
+	b.Reset()
+}
+
+var renderCmd = &cobra.Command{
+	Use:   "render",
+	Short: "Render a template with secrets",
+	Long: "Render a Go text/template with secrets.\n\n" +
+		"Templates can use the functions:\n" +
+		"  secret \"name\"           the value of a secret\n" +
+		"  field \"name\" \"field\"    the value of a field of a structured secret\n" +
+		"  base64, hex, urlencode  encode a value, e.g. {{ secret \"name\" | base64 }}\n\n" +
+		"If any secret cannot be resolved, nothing is written and every unresolved secret is reported.",
+	Example: fmt.Sprintf("  %s render -t application.yml.tmpl -o application.yml\n  %s render -t pgpass.tmpl -o ~/.pgpass", app.Name, app.Name),
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rendered, err := renderTemplate(templateFile)
+		if err != nil {
+			return err
+		}
+		defer secrets.ClearSecret(&rendered)
+
+		if output == "" {
+			fmt.Print(string(rendered))
+			return nil
+		}
+
+		if err := writeOutputFile(output, rendered, "rendered template"); err != nil {
+			return err
+		}
+
+		return printResult(result{Action: "written", Secrets: []string{}, Path: output})
+	},
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/engmtcdrm/mellon/env"
+)
+
+// TestRenderCommand tests rendering templates with secrets.
+func TestRenderCommand(t *testing.T) {
+	env.Init()
+
+	tmpDir := t.TempDir()
+	secretFile := filepath.Join(tmpDir, "secret.txt")
+	if err := os.WriteFile(secretFile, []byte("p@ss word"), 0644); err != nil {
+		t.Fatalf("failed to write secret file: %v", err)
+	}
+
+	if output, err := exec.Command(testBinary, "create", "--secret", "testrender/password", "--file", secretFile).CombinedOutput(); err != nil {
+		t.Fatalf("failed to create secret: %v, output: %s", err, output)
+	}
+	defer exec.Command(testBinary, "delete", "--secret", "testrender/password", "--force").Run()
+
+	if output, err := exec.Command(testBinary, "create", "--secret", "testrender/db", "--field", fieldFile(t, "host", "db.example.com"), "--field", fieldFile(t, "user", "app")).CombinedOutput(); err != nil {
+		t.Fatalf("failed to create structured secret: %v, output: %s", err, output)
+	}
+	defer exec.Command(testBinary, "delete", "--secret", "testrender/db", "--force").Run()
+
+	tmplFile := filepath.Join(tmpDir, "pgpass.tmpl")
+	tmpl := `{{ field "testrender/db" "host" }}:5432:*:{{ field "testrender/db" "user" }}:{{ secret "testrender/password" }}
+url={{ secret "testrender/password" | urlencode }} b64={{ secret "testrender/password" | base64 }}
+`
+	if err := os.WriteFile(tmplFile, []byte(tmpl), 0644); err != nil {
+		t.Fatalf("failed to write template: %v", err)
+	}
+
+	outFile := filepath.Join(tmpDir, "out", "pgpass")
+	if output, err := exec.Command(testBinary, "render", "-t", tmplFile, "-o", outFile).CombinedOutput(); err != nil {
+		t.Fatalf("failed to render template: %v, output: %s", err, output)
+	}
+
+	rendered, err := os.ReadFile(outFile)
+	if err != nil {
+		t.Fatalf("failed to read rendered file: %v", err)
+	}
+
+	expected := "db.example.com:5432:*:app:p@ss word\nurl=p%40ss%20word b64=cEBzcyB3b3Jk\n"
+	if string(rendered) != expected {
+		t.Errorf("expected rendered template:\n%s\ngot:\n%s", expected, rendered)
+	}
+
+	info, err := os.Stat(outFile)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("expected rendered file to have mode 0600, got: %v, error: %v", info.Mode().Perm(), err)
+	}
+
+	// Every unresolved secret is reported and nothing is written
+	missingTmpl := filepath.Join(tmpDir, "missing.tmpl")
+	if err := os.WriteFile(missingTmpl, []byte(`{{ secret "testrender/password" }} {{ secret "testrender/missing-a" }} {{ field "testrender/db" "missing" }} {{ secret "testrender/missing-b" }}`), 0644); err != nil {
+		t.Fatalf("failed to write template: %v", err)
+	}
+
+	missingOut := filepath.Join(tmpDir, "missing.out")
+	output, err := exec.Command(testBinary, "render", "-t", missingTmpl, "-o", missingOut).CombinedOutput()
+	if err == nil {
+		t.Errorf("expected error rendering template with unresolved secrets")
+	}
+
+	for _, ref := range []string{"testrender/missing-a", "testrender/missing-b", "testrender/db#missing"} {
+		if !strings.Contains(string(output), ref) {
+			t.Errorf("expected '%s' to be reported as unresolved, got: %s", ref, output)
+		}
+	}
+
+	if _, err := os.Stat(missingOut); !os.IsNotExist(err) {
+		t.Errorf("expected no output file for a failed render, got: %v", err)
+	}
+}
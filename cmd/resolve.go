@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/engmtcdrm/mellon/app"
+	"github.com/engmtcdrm/mellon/env"
+	"github.com/engmtcdrm/mellon/secrets"
+)
+
+func init() {
+	resolveCmd.Flags().StringVarP(
+		&output,
+		"output",
+		"o",
+		"",
+		"(optional) File to write the resolved contents to. Defaults to outputting to stdout",
+	)
+	addAllowExpiredFlag(resolveCmd, "(optional) Whether to allow resolving references to secrets that have expired")
+
+	resolveCmd.MarkFlagFilename("output")
+
+	rootCmd.AddCommand(resolveCmd)
+}
+
+// referenceResolver resolves mellon:// references, decrypting each referenced value once.
+type referenceResolver struct {
+	values map[secrets.Reference][]byte // Resolved values by reference
+}
+
+// newReferenceResolver returns a resolver without any resolved values.
+func newReferenceResolver() *referenceResolver {
+	return &referenceResolver{values: map[secrets.Reference][]byte{}}
+}
+
+// resolve returns the value of the secret, or field of a structured secret, the
+// reference points to.
+func (r *referenceResolver) resolve(ref secrets.Reference) ([]byte, error) {
+	if value, ok := r.values[ref]; ok {
+		return value, nil
+	}
+
+	value, err := resolveSecretValue(ref.Name, ref.Field)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", ref, err)
+	}
+
+	r.values[ref] = value
+
+	return value, nil
+}
+
+// resolveEnv replaces the value of every variable in environ that is a mellon://
+// reference. Every reference is tried so all problems are reported at once.
+func (r *referenceResolver) resolveEnv(environ []string) ([]string, error) {
+	resolved := make([]string, 0, len(environ))
+	var errs []error
+
+	for _, pair := range environ {
+		name, value, _ := strings.Cut(pair, "=")
+
+		ref, ok := secrets.ParseReference(value)
+		if !ok {
+			resolved = append(resolved, pair)
+			continue
+		}
+
+		secret, err := r.resolve(ref)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+
+		resolved = append(resolved, name+"="+string(secret))
+	}
+
+	return resolved, errors.Join(errs...)
+}
+
+// clear wipes every resolved value from memory.
+func (r *referenceResolver) clear() {
+	for ref, value := range r.values {
+		secrets.ClearSecret(&value)
+		delete(r.values, ref)
+	}
+}
+
+// readInput reads the named file, or stdin if the name is empty or -.
+func readInput(name string) ([]byte, error) {
+	if name == "" || name == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("could not read stdin: %w", err)
+		}
+		return data, nil
+	}
+
+	path, err := env.ExpandTilde(name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read file '%s': %w", path, err)
+	}
+
+	return data, nil
+}
+
+var resolveCmd = &cobra.Command{
+	Use:   "resolve [file]",
+	Short: "Resolve secret references in a file",
+	Long: "Resolve secret references in a file.\n\n" +
+		"Every reference such as mellon://prod/db or mellon://prod/db#password is replaced by the value of the secret, or of the field of a structured secret. " +
+		"When no file, or -, is given, stdin is read instead.\n\n" +
+		"If any reference cannot be resolved, nothing is written and every unresolved reference is reported.",
+	Example: fmt.Sprintf(
+		"  %s resolve .env.template -o .env\n  cat config.yml | %s resolve > /run/app/config.yml",
+		app.Name, app.Name,
+	),
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var input string
+		if len(args) > 0 {
+			input = args[0]
+		}
+
+		data, err := readInput(input)
+		if err != nil {
+			return err
+		}
+
+		resolver := newReferenceResolver()
+		defer resolver.clear()
+
+		resolved, errs := secrets.ReplaceReferences(data, resolver.resolve)
+		if len(errs) > 0 {
+			secrets.ClearSecret(&resolved)
+			return fmt.Errorf("could not resolve %d reference(s):\n%w", len(errs), errors.Join(errs...))
+		}
+		defer secrets.ClearSecret(&resolved)
+
+		if output == "" {
+			fmt.Print(string(resolved))
+			return nil
+		}
+
+		if err := writeOutputFile(output, resolved, "resolved contents"); err != nil {
+			return err
+		}
+
+		return printResult(result{Action: "written", Secrets: []string{}, Path: output})
+	},
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/engmtcdrm/mellon/env"
+)
+
+// TestResolveCommand tests resolving secret references in files, streams and the
+// environment of commands.
+func TestResolveCommand(t *testing.T) {
+	env.Init()
+
+	tmpDir := t.TempDir()
+	secretFile := filepath.Join(tmpDir, "secret.txt")
+	if err := os.WriteFile(secretFile, []byte("resolvetoken"), 0644); err != nil {
+		t.Fatalf("failed to write secret file: %v", err)
+	}
+
+	if output, err := exec.Command(testBinary, "create", "--secret", "testresolve/token", "--file", secretFile).CombinedOutput(); err != nil {
+		t.Fatalf("failed to create secret: %v, output: %s", err, output)
+	}
+	defer exec.Command(testBinary, "delete", "--secret", "testresolve/token", "--force").Run()
+
+	if output, err := exec.Command(testBinary, "create", "--secret", "testresolve/db", "--field", fieldFile(t, "user", "app"), "--field", fieldFile(t, "password", "resolvepass")).CombinedOutput(); err != nil {
+		t.Fatalf("failed to create structured secret: %v, output: %s", err, output)
+	}
+	defer exec.Command(testBinary, "delete", "--secret", "testresolve/db", "--force").Run()
+
+	inputFile := filepath.Join(tmpDir, ".env.template")
+	input := "TOKEN=mellon://testresolve/token\nDSN=postgres://mellon://testresolve/db#user:mellon://testresolve/db#password@db\nPLAIN=value\n"
+	if err := os.WriteFile(inputFile, []byte(input), 0644); err != nil {
+		t.Fatalf("failed to write input file: %v", err)
+	}
+
+	expected := "TOKEN=resolvetoken\nDSN=postgres://app:resolvepass@db\nPLAIN=value\n"
+
+	outFile := filepath.Join(tmpDir, "out", ".env")
+	if output, err := exec.Command(testBinary, "resolve", inputFile, "-o", outFile).CombinedOutput(); err != nil {
+		t.Fatalf("failed to resolve file: %v, output: %s", err, output)
+	}
+
+	resolved, err := os.ReadFile(outFile)
+	if err != nil {
+		t.Fatalf("failed to read resolved file: %v", err)
+	}
+
+	if string(resolved) != expected {
+		t.Errorf("expected resolved file:\n%s\ngot:\n%s", expected, resolved)
+	}
+
+	if info, err := os.Stat(outFile); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("expected resolved file to have mode 0600, got: %v, error: %v", info.Mode().Perm(), err)
+	}
+
+	// Streams are read from stdin
+	cmd := exec.Command(testBinary, "resolve")
+	cmd.Stdin = strings.NewReader(input)
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("failed to resolve stdin: %v", err)
+	}
+
+	if string(output) != expected {
+		t.Errorf("expected resolved stream:\n%s\ngot:\n%s", expected, output)
+	}
+
+	// Every unknown reference is reported and nothing is written
+	cmd = exec.Command(testBinary, "resolve", "-o", filepath.Join(tmpDir, "missing.out"))
+	cmd.Stdin = strings.NewReader("A=mellon://testresolve/missing-a\nB=mellon://testresolve/token\nC=mellon://testresolve/db#missing\n")
+	output, err = cmd.CombinedOutput()
+	if err == nil {
+		t.Errorf("expected error resolving unknown references")
+	}
+
+	for _, ref := range []string{"mellon://testresolve/missing-a", "mellon://testresolve/db#missing"} {
+		if !strings.Contains(string(output), ref) {
+			t.Errorf("expected '%s' to be reported as unresolved, got: %s", ref, output)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpDir, "missing.out")); !os.IsNotExist(err) {
+		t.Errorf("expected no output file for a failed resolve, got: %v", err)
+	}
+
+	// Inherited environment variables holding references are resolved for exec
+	cmd = exec.Command(testBinary, "exec", "--resolve-env", "--", "sh", "-c", `printf '%s %s %s' "$TOKEN" "$DB_PASS" "$PLAIN"`)
+	cmd.Env = append(os.Environ(), "TOKEN=mellon://testresolve/token", "DB_PASS=mellon://testresolve/db#password", "PLAIN=mellon://x y")
+	output, err = cmd.Output()
+	if err != nil {
+		t.Fatalf("failed to exec with resolved environment: %v", err)
+	}
+
+	if string(output) != "resolvetoken resolvepass mellon://x y" {
+		t.Errorf("expected references in the environment to be resolved, got: %s", output)
+	}
+
+	// Without --resolve-env references are passed through as is
+	cmd = exec.Command(testBinary, "exec", "--", "sh", "-c", `printf '%s' "$TOKEN"`)
+	cmd.Env = append(os.Environ(), "TOKEN=mellon://testresolve/token")
+	if output, err = cmd.Output(); err != nil || string(output) != "mellon://testresolve/token" {
+		t.Errorf("expected references to be left untouched without --resolve-env, got: %s, error: %v", output, err)
+	}
+
+	cmd = exec.Command(testBinary, "exec", "--resolve-env", "--", "sh", "-c", "echo ran")
+	cmd.Env = append(os.Environ(), "A=mellon://testresolve/missing-a", "B=mellon://testresolve/missing-b")
+	output, err = cmd.CombinedOutput()
+	if err == nil || strings.Contains(string(output), "ran") {
+		t.Errorf("expected command not to run with unknown references, got: %s", output)
+	}
+
+	if !strings.Contains(string(output), "testresolve/missing-a") || !strings.Contains(string(output), "testresolve/missing-b") {
+		t.Errorf("expected all unknown references to be reported, got: %s", output)
+	}
+}
@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	pp "github.com/engmtcdrm/go-prettyprint"
+	"github.com/engmtcdrm/mellon/app"
+	"github.com/engmtcdrm/mellon/env"
+	"github.com/engmtcdrm/mellon/header"
+	"github.com/engmtcdrm/mellon/secrets"
+)
+
+// restoreModes are the ways restoring a backup treats secrets that already exist.
+var restoreModes = []string{"merge", "overwrite"}
+
+func init() {
+	restoreCmd.Flags().StringVarP(
+		&secretName,
+		"secret",
+		"s",
+		"",
+		"(optional) The name of a deleted secret to restore from the trash instead of restoring a backup",
+	)
+	restoreCmd.Flags().StringVar(
+		&restoreMode,
+		"mode",
+		"merge",
+		"(optional) How secrets that already exist are treated. merge keeps them, overwrite replaces them with the ones in the backup",
+	)
+	restoreCmd.Flags().BoolVar(
+		&dryRun,
+		"dry-run",
+		false,
+		"(optional) Show what would be restored without changing anything",
+	)
+
+	restoreCmd.RegisterFlagCompletionFunc("secret", trashCompletion)
+	restoreCmd.RegisterFlagCompletionFunc("mode", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return restoreModes, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	rootCmd.AddCommand(restoreCmd)
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore [file]",
+	Short: "Restore secrets from a backup or the trash",
+	Long: "Restore secrets from a backup created with the backup command, or a deleted secret from the trash with --secret.\n\n" +
+		"By default, secrets that already exist are kept and only the missing ones are restored. " +
+		"Use --mode overwrite to replace existing secrets, along with their history, with the ones in the backup, and --dry-run to see what would change first.\n\n" +
+		"If there is no encryption key yet, the key in the backup is restored. Otherwise, secrets from a backup made with a different key are re-encrypted with the current key.\n\n" +
+		"A secret restored from the trash gets back its metadata and history. It is never restored over a secret that exists under the same name.",
+	Example: fmt.Sprintf(
+		"  %s restore %s-backup-20250101-120000.backup\n  %s restore secrets.backup --dry-run\n  %s restore secrets.backup --mode overwrite\n  %s restore -s my_secret",
+		app.Name, app.Name, app.Name, app.Name, app.Name,
+	),
+	Args: cobra.MaximumNArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if secretName != "" {
+			if len(args) > 0 {
+				return fmt.Errorf("cannot restore a backup file and a secret from the trash at the same time")
+			}
+			if cmd.Flags().Changed("mode") || cmd.Flags().Changed("dry-run") {
+				return fmt.Errorf("flags --mode and --dry-run only apply when restoring a backup")
+			}
+			return nil
+		}
+
+		if len(args) == 0 {
+			return fmt.Errorf("a backup file or --secret is required")
+		}
+
+		if !slices.Contains(restoreModes, restoreMode) {
+			return fmt.Errorf("invalid mode '%s'. Must be one of: %s", restoreMode, strings.Join(restoreModes, ", "))
+		}
+
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !machineReadable() {
+			header.PrintHeader()
+		}
+
+		if secretName != "" {
+			secret, err := secrets.RestoreTrashed(env.Instance.KeyPath(), env.Instance.SecretsPath(), secretName)
+			if errors.Is(err, secrets.ErrHistoryDropped) {
+				fmt.Fprintln(os.Stderr, pp.Alertf("%s", err))
+			} else if err != nil {
+				return err
+			}
+
+			return printComplete(fmt.Sprintf("Restored %s from the trash", pp.Green(secret.Name())), result{Action: "restored", Secrets: []string{secret.Name()}})
+		}
+
+		backup, err := openBackup(args[0])
+		if err != nil {
+			return err
+		}
+
+		replace := restoreMode == "overwrite"
+
+		var actions []secrets.RestoreAction
+		if dryRun {
+			actions = backup.Plan(secretFiles, replace)
+		} else {
+			if err := takeSnapshot("restore"); err != nil {
+				return err
+			}
+
+			actions, err = backup.Restore(env.Instance.KeyPath(), env.Instance.SecretsPath(), secretFiles, replace)
+			if errors.Is(err, secrets.ErrRestoreCleanup) {
+				fmt.Fprintln(os.Stderr, pp.Alertf("%s", err))
+			} else if err != nil {
+				return fmt.Errorf("could not restore backup: %w", err)
+			}
+		}
+
+		res := result{Action: "restored", Secrets: []string{}, Path: args[0]}
+		if dryRun {
+			res.Action = "planned"
+		}
+
+		for _, a := range actions {
+			if a.Action == secrets.RestoreSkip {
+				res.Skipped = append(res.Skipped, a.Name)
+			} else {
+				res.Secrets = append(res.Secrets, a.Name)
+			}
+		}
+
+		if machineReadable() {
+			return printOutput(res)
+		}
+
+		restored := 0
+		for _, a := range actions {
+			switch a.Action {
+			case secrets.RestoreSkip:
+				fmt.Println(pp.Infof("Skipped %s, it already exists", a.Name))
+				continue
+			case secrets.RestoreOverwrite:
+				if dryRun {
+					fmt.Println(pp.Infof("Would overwrite %s", pp.Green(a.Name)))
+				} else {
+					fmt.Println(pp.Completef("Overwrote %s", pp.Green(a.Name)))
+				}
+			default:
+				if dryRun {
+					fmt.Println(pp.Infof("Would restore %s", pp.Green(a.Name)))
+				} else {
+					fmt.Println(pp.Completef("Restored %s", pp.Green(a.Name)))
+				}
+			}
+			restored++
+		}
+
+		fmt.Println()
+		if dryRun {
+			fmt.Printf("Would restore %d of %d secret(s) from '%s'\n", restored, len(actions), args[0])
+		} else {
+			fmt.Printf("Restored %d of %d secret(s) from '%s'\n", restored, len(actions), args[0])
+		}
+
+		return nil
+	},
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/engmtcdrm/go-pardon"
+	pp "github.com/engmtcdrm/go-prettyprint"
+	"github.com/engmtcdrm/mellon/app"
+	"github.com/engmtcdrm/mellon/secrets"
+)
+
+func init() {
+	rollbackCmd.Flags().StringVarP(
+		&secretName,
+		"secret",
+		"s",
+		"",
+		"The name of the secret to roll back",
+	)
+	rollbackCmd.Flags().IntVar(
+		&rollbackTo,
+		"to",
+		0,
+		"The version to roll back to. Use the history command to list the versions of a secret",
+	)
+	rollbackCmd.Flags().BoolVarP(
+		&forceRollback,
+		"force",
+		"f",
+		false,
+		"(optional) Whether to roll back without confirmation",
+	)
+
+	rollbackCmd.MarkFlagRequired("secret")
+	rollbackCmd.MarkFlagRequired("to")
+	rollbackCmd.RegisterFlagCompletionFunc("secret", secretFlagCompletion)
+
+	rootCmd.AddCommand(rollbackCmd)
+}
+
+func validateRollbackFlags(cmd *cobra.Command, args []string) error {
+	if rollbackTo < 1 {
+		return errors.New("flag --to must be 1 or greater")
+	}
+
+	return nil
+}
+
+var rollbackCmd = &cobra.Command{
+	Use:     "rollback",
+	Short:   "Roll back a secret to a previous version",
+	Long:    "Roll back a secret to a previous version.\n\nThe version being replaced is kept in the history, so a rollback can itself be rolled back.",
+	Example: fmt.Sprintf("  %s rollback -s awesome-secret --to 3\n  %s rollback -s awesome-secret --to 3 --force", app.Name, app.Name),
+	PreRunE: validateRollbackFlags,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		secretPtr := secrets.FindSecretByName(secretName, secretFiles)
+		if secretPtr == nil {
+			return fmt.Errorf("could not roll back secret '%s': secret %w", secretName, secrets.ErrNotFound)
+		}
+
+		if !forceRollback {
+			if err := promptHeader(); err != nil {
+				return err
+			}
+
+			confirmRollback := false
+			promptConfirm := pardon.NewConfirm(&confirmRollback).
+				Title(fmt.Sprintf("Are you sure you want to roll back %s to version %d?", pp.Yellow(secretName), rollbackTo))
+
+			if err := promptConfirm.Ask(); err != nil {
+				return err
+			}
+
+			fmt.Println()
+
+			if !confirmRollback {
+				fmt.Println(pp.Fail("Aborted rolling back secret"))
+				return nil
+			}
+		}
+
+		if err := secretPtr.Rollback(rollbackTo); err != nil {
+			return err
+		}
+
+		if !forceRollback {
+			fmt.Println(pp.Completef("Secret rolled back to version %d", rollbackTo))
+		}
+
+		return printResult(result{Action: "rolled_back", Secrets: []string{secretPtr.Name()}})
+	},
+}
@@ -2,12 +2,13 @@ package cmd
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"os"
 
 	"github.com/spf13/cobra"
 
 	"github.com/engmtcdrm/mellon/app"
+	"github.com/engmtcdrm/mellon/config"
 	"github.com/engmtcdrm/mellon/env"
 	"github.com/engmtcdrm/mellon/secrets"
 )
@@ -19,15 +20,88 @@ var (
 		Long:    app.LongDesc,
 		Example: app.Name,
 		Version: getSemVer(app.Version),
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateOutputFormat(); err != nil {
+				return usageError(err)
+			}
+
+			return nil
+		},
 	}
 
-	secretName  string // The name of the secret to create/view/update/delete
-	secretFile  string // The file containing the plain text secret to encrypt
-	cleanupFile bool   // Whether to delete the raw secret file after encryption
-	forceDelete bool   // Whether to force overwrite an existing secret file (only used with delete command)
-	deleteAll   bool   // Whether to delete all secrets (only used with delete command)
-	output      string // The file to write decrypted secret to (only used with view command)
-	print       bool   // Whether to print only the names of the secrets without additional information (only used with list command)
+	secretName    string   // The name of the secret to create/view/update/delete/restore
+	secretFile    string   // The file containing the plain text secret to encrypt
+	cleanupFile   bool     // Whether to delete the raw secret file after encryption
+	forceDelete   bool     // Whether to force overwrite an existing secret file (only used with delete command)
+	deleteAll     bool     // Whether to delete all secrets (only used with delete command)
+	purgeDelete   bool     // Whether to delete secrets permanently instead of moving them to the trash (only used with delete command)
+	forceRekey    bool     // Whether to rekey without confirmation (only used with rekey command)
+	forceRollback bool     // Whether to roll back without confirmation (only used with rollback command)
+	forceSnapshot bool     // Whether to restore a snapshot without confirmation (only used with snapshots restore command)
+	output        string   // The file to write decrypted secret to (only used with view, render, resolve, export and backup commands)
+	print         bool     // Whether to print only the names of the secrets without additional information (only used with list command)
+	description   string   // The description of the secret (only used with create and update commands)
+	owner         string   // The owner of the secret (only used with create and update commands)
+	sortBy        string   // The field to sort secrets by (only used with list command)
+	tags          []string // Tags to add to the secret (only used with create and update commands)
+	untags        []string // Tags to remove from the secret (only used with update command)
+	filterTags    []string // Tags a secret must have to be selected (only used with list, view and delete commands)
+	excludeTags   []string // Tags a secret must not have to be selected (only used with list, view and delete commands)
+	expiresIn     string   // How long the secret is valid after each rotation (only used with create and update commands)
+	expiresAt     string   // When the secret expires (only used with create and update commands)
+	noExpiry      bool     // Whether to remove the expiry of the secret (only used with update command)
+	allowExpiry   bool     // Whether to allow decrypting expired secrets (only used with view, exec, render, resolve, export, edit and otp commands)
+	within        string   // How far ahead to look for expiring secrets (only used with expired command)
+	reverseSort   bool     // Whether to reverse the sort order (only used with list command)
+	version       int      // The version of the secret to view (only used with view command)
+	rollbackTo    int      // The version of the secret to roll back to (only used with rollback command)
+	secretFields  []string // Fields of a structured secret to set (only used with create and update commands)
+	unsetFields   []string // Fields of a structured secret to remove (only used with update command)
+	viewField     string   // The field of the secret to view (only used with view command)
+	viewQuery     string   // The JSONPath-style query selecting the value to view (only used with view command)
+	rawSecret     bool     // Whether to store the secret exactly as given without trimming whitespace (only used with create and update commands)
+	encoding      string   // The encoding to output the secret in (only used with view command)
+	tree          bool     // Whether to show the secrets as a tree of namespaces (only used with list command)
+	recursive     bool     // Whether to delete every secret in a namespace (only used with delete command)
+	envMappings   []string // Environment variables to set from secrets (only used with exec command)
+	envFile       string   // The file mapping environment variables to secrets (only used with exec command)
+	templateFile  string   // The template to render (only used with render command)
+	resolveEnv    bool     // Whether to resolve inherited environment variables holding secret references (only used with exec command)
+	importFormat  string   // The format of the file to import (only used with import command)
+	exportFormat  string   // The format to export secrets in (only used with export command)
+	prefix        string   // The namespace to import secrets into or export secrets from (only used with import and export commands)
+	skipExisting  bool     // Whether to skip secrets that already exist (only used with import command)
+	overwrite     bool     // Whether to overwrite secrets that already exist (only used with import command)
+	keyCase       string   // How names of secrets are turned into keys (only used with export command)
+	keyMappings   []string // Keys of exported secrets given explicitly (only used with export command)
+	k8sName       string   // The name of the Kubernetes Secret (only used with export command)
+	k8sNamespace  string   // The Kubernetes namespace of the Secret (only used with export command)
+	restoreMode   string   // How secrets that already exist are treated when restoring (only used with restore command)
+	dryRun        bool     // Whether to show what would change without changing anything (only used with restore command)
+	olderThan     string   // How long ago secrets must have been deleted to be removed from the trash (only used with trash empty command)
+	forceEmpty    bool     // Whether to empty the trash without confirmation (only used with trash empty command)
+	generate      bool     // Whether to generate the secret instead of reading or prompting for it (only used with create and update commands)
+	generateType  string   // The kind of value to generate (only used with generate, create and update commands)
+	genLength     int      // The number of characters or random bytes to generate (only used with generate, create and update commands)
+	genWords      int      // The number of words of generated passphrases (only used with generate, create and update commands)
+	genSeparator  string   // The separator between the words of generated passphrases (only used with generate, create and update commands)
+	genClasses    []string // The character classes of generated passwords (only used with generate, create and update commands)
+	genExclude    string   // The characters never used in generated passwords (only used with generate, create and update commands)
+	noAmbiguous   bool     // Whether to leave easily confused characters out of generated passwords (only used with generate, create and update commands)
+	readStdin     bool     // Whether to read the secret from stdin (only used with create and update commands)
+	inputFD       int      // The file descriptor to read the secret from (only used with create and update commands)
+	otpURI        string   // The otpauth:// URI of the one-time password to add (only used with otp add command)
+	otpQR         string   // The image of the QR code of the one-time password to add (only used with otp add command)
+	otpType       string   // The kind of one-time password, totp or hotp (only used with otp add command)
+	otpAlgorithm  string   // The HMAC algorithm of the one-time password (only used with otp add command)
+	otpDigits     int      // The number of digits of the one-time password codes (only used with otp add command)
+	otpPeriod     int      // The seconds each one-time password code is valid for (only used with otp add command)
+	otpCounter    uint64   // The counter of the next one-time password code (only used with otp add command)
+	otpIssuer     string   // The service the one-time password belongs to (only used with otp add command)
+	otpAccount    string   // The account the one-time password belongs to (only used with otp add command)
+	outputFormat  string   // The machine-readable format to write output in, json or yaml
+
+	cfg config.Config // User configuration of the app
 
 	secretFiles []secrets.Secret // List of secrets available in the app
 
@@ -41,13 +115,38 @@ func init() {
 
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
 
+	addOutputFlag(rootCmd)
+
 	cobra.OnInitialize(configInit)
 }
 
-// Execute executes the root command.
+// Execute executes the root command. Errors are printed to stderr, as an object with
+// a stable code when the output is machine-readable.
 func Execute() error {
 	rootCmd.SilenceUsage = true
-	return rootCmd.ExecuteContext(context.Background())
+	rootCmd.SilenceErrors = true
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return usageError(err)
+	})
+	markUsageErrors(rootCmd)
+
+	cmd, err := rootCmd.ExecuteContextC(context.Background())
+	if err != nil {
+		// Flags are not parsed at all when one of them is invalid
+		if !machineReadable() {
+			outputFormat = outputFormatArg(os.Args[1:])
+		}
+		printError(cmd, err)
+	}
+
+	return err
+}
+
+// exitWithError prints err the way Execute does and exits, for errors that occur
+// before the command runs.
+func exitWithError(err error) {
+	printError(rootCmd, err)
+	os.Exit(1)
 }
 
 func configInit() {
@@ -57,6 +156,26 @@ func configInit() {
 	mkdir(env.Instance.AppHomeDir(), dirMode)
 	mkdir(env.Instance.SecretsPath(), dirMode)
 	secureFiles(env.Instance.AppHomeDir(), dirMode, secretMode)
+	secrets.SetPassphraseFunc(askPassphrase)
+	secrets.SetAllowExpired(allowExpiry)
+
+	cfg, err = config.Load(env.Instance.ConfigPath())
+	if err != nil {
+		exitWithError(err)
+	}
+
+	secrets.SetHistory(env.Instance.HistoryPath(), cfg.HistoryRetention)
+	secrets.SetSnapshots(env.Instance.SnapshotsPath(), cfg.SnapshotRetention, cfg.SnapshotMaxAgeDuration())
+	secrets.SetTrash(env.Instance.TrashPath())
+	secrets.SetShredPasses(cfg.ShredPasses)
+	secrets.SetShredWarnFunc(warnShred)
+
+	// A rekey that was interrupted is rolled back or finished before any secret is read
+	if err := secrets.RecoverRekey(env.Instance.KeyPath()); errors.Is(err, secrets.ErrRekeyCleanup) {
+		warnRekeyCleanup(err)
+	} else if err != nil {
+		exitWithError(err)
+	}
 
 	secretFiles, err = secrets.GetSecretFiles(
 		env.Instance.KeyPath(),
@@ -64,7 +183,6 @@ func configInit() {
 		env.Instance.SecretExt(),
 	)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		exitWithError(err)
 	}
 }
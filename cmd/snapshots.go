@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/engmtcdrm/go-pardon"
+	pp "github.com/engmtcdrm/go-prettyprint"
+	"github.com/engmtcdrm/mellon/app"
+	"github.com/engmtcdrm/mellon/env"
+	"github.com/engmtcdrm/mellon/header"
+	"github.com/engmtcdrm/mellon/secrets"
+)
+
+func init() {
+	snapshotsListCmd.Flags().BoolVarP(
+		&print,
+		"print",
+		"p",
+		false,
+		"(optional) Whether to print only the IDs of the snapshots without additional information",
+	)
+	snapshotsRestoreCmd.Flags().BoolVarP(
+		&forceSnapshot,
+		"force",
+		"f",
+		false,
+		"(optional) Whether to restore the snapshot without confirmation",
+	)
+
+	snapshotsCmd.AddCommand(snapshotsListCmd)
+	snapshotsCmd.AddCommand(snapshotsRestoreCmd)
+
+	rootCmd.AddCommand(snapshotsCmd)
+}
+
+// takeSnapshot keeps a snapshot of the secrets before a destructive operation, so it
+// can be undone with snapshots restore.
+func takeSnapshot(reason string) error {
+	if _, err := secrets.TakeSnapshot(env.Instance.SecretsPath(), reason); err != nil {
+		return fmt.Errorf("could not take snapshot before %s, no changes were made: %w", reason, err)
+	}
+
+	return nil
+}
+
+// snapshotCompletion completes the IDs of the snapshots, newest first.
+func snapshotCompletion(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	snapshots, err := secrets.Snapshots()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	ids := make([]string, 0, len(snapshots))
+	for _, s := range slices.Backward(snapshots) {
+		ids = append(ids, s.ID)
+	}
+
+	return ids, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+}
+
+var snapshotsCmd = &cobra.Command{
+	Use:   "snapshots",
+	Short: "Manage automatic snapshots of the secrets",
+	Long: fmt.Sprintf(
+		"Manage automatic snapshots of the secrets.\n\n"+
+			"A snapshot of every secret and their history is taken automatically before secrets are deleted, updated, imported or restored. "+
+			"Snapshots do not hold the encryption key: rotating the key re-encrypts the secrets in the snapshots too. "+
+			"The number of snapshots kept and for how long are set with the settings %s and %s.",
+		pp.Green("snapshots.retention"), pp.Green("snapshots.max-age"),
+	),
+	Example: fmt.Sprintf("  %s snapshots list\n  %s snapshots restore 20250101-120000", app.Name, app.Name),
+}
+
+var snapshotsListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List the snapshots",
+	Long:    "List the snapshots, newest first",
+	Example: fmt.Sprintf("  %s snapshots list\n  %s snapshots list --print", app.Name, app.Name),
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		snapshots, err := secrets.Snapshots()
+		if err != nil {
+			return err
+		}
+
+		// Newest snapshots first
+		slices.Reverse(snapshots)
+
+		if machineReadable() {
+			if snapshots == nil {
+				snapshots = []secrets.Snapshot{}
+			}
+			return printOutput(snapshots)
+		}
+
+		if print {
+			for _, s := range snapshots {
+				fmt.Println(s.ID)
+			}
+			return nil
+		}
+
+		header.PrintHeader()
+
+		if len(snapshots) == 0 {
+			fmt.Println(pp.Info("No snapshots found"))
+			return nil
+		}
+
+		rows := make([][]string, 0, len(snapshots))
+		for _, s := range snapshots {
+			rows = append(rows, []string{s.ID, formatTime(s.CreatedAt), s.Reason, strconv.Itoa(s.Secrets)})
+		}
+
+		printTable([]string{"ID", "TAKEN", "BEFORE", "SECRETS"}, rows, pp.Yellow)
+		fmt.Println()
+
+		return nil
+	},
+}
+
+var snapshotsRestoreCmd = &cobra.Command{
+	Use:               "restore <id>",
+	Short:             "Restore a snapshot",
+	Long:              "Restore a snapshot, replacing every secret and their history with the ones in the snapshot.\n\nA snapshot of the secrets being replaced is taken first, so restoring a snapshot can itself be undone.",
+	Example:           fmt.Sprintf("  %s snapshots restore 20250101-120000\n  %s snapshots restore 20250101-120000 --force", app.Name, app.Name),
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: snapshotCompletion,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		snapshot, err := secrets.FindSnapshot(args[0])
+		if err != nil {
+			return err
+		}
+
+		if !forceSnapshot {
+			if err := promptHeader(); err != nil {
+				return err
+			}
+
+			confirmRestore := false
+			promptConfirm := pardon.NewConfirm(&confirmRestore).
+				Title(fmt.Sprintf("Are you sure you want to replace all %d secret(s) with the %d secret(s) of snapshot %s?", len(secretFiles), snapshot.Secrets, pp.Yellow(snapshot.ID)))
+
+			if err := promptConfirm.Ask(); err != nil {
+				return err
+			}
+
+			fmt.Println()
+
+			if !confirmRestore {
+				fmt.Println(pp.Fail("Aborted restoring snapshot"))
+				return nil
+			}
+		}
+
+		if err := secrets.RestoreSnapshot(env.Instance.SecretsPath(), snapshot.ID); err != nil {
+			return err
+		}
+
+		if !forceSnapshot {
+			fmt.Println(pp.Completef("Snapshot %s restored", snapshot.ID))
+		}
+
+		return printResult(result{Action: "restored", Secrets: []string{}, Snapshot: snapshot.ID})
+	},
+}
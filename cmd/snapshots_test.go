@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/engmtcdrm/mellon/env"
+)
+
+// TestSnapshotsCommand tests that deleting a secret takes a snapshot it can be
+// restored from.
+func TestSnapshotsCommand(t *testing.T) {
+	env.Init()
+
+	secretName := "testsnapshots"
+
+	if output, err := exec.Command(testBinary, "create", "--secret", secretName, "--field", fieldFile(t, "token", "snap")).CombinedOutput(); err != nil {
+		t.Fatalf("failed to create secret: %v, output: %s", err, output)
+	}
+	defer exec.Command(testBinary, "delete", "--secret", secretName, "--force").Run()
+
+	if output, err := exec.Command(testBinary, "delete", "--secret", secretName, "--force").CombinedOutput(); err != nil {
+		t.Fatalf("failed to delete secret: %v, output: %s", err, output)
+	}
+
+	output, err := exec.Command(testBinary, "snapshots", "list", "--print").Output()
+	if err != nil {
+		t.Fatalf("failed to list snapshots: %v", err)
+	}
+
+	ids := strings.Fields(string(output))
+	if len(ids) == 0 {
+		t.Fatalf("expected a snapshot to be taken before deleting")
+	}
+
+	output, err = exec.Command(testBinary, "snapshots", "list").Output()
+	if err != nil || !strings.Contains(string(output), ids[0]) || !strings.Contains(string(output), "delete") {
+		t.Errorf("expected snapshot %s taken before delete to be listed, got: %s, error: %v", ids[0], output, err)
+	}
+
+	if output, err := exec.Command(testBinary, "snapshots", "restore", ids[0], "--force").CombinedOutput(); err != nil {
+		t.Fatalf("failed to restore snapshot: %v, output: %s", err, output)
+	}
+
+	output, err = exec.Command(testBinary, "view", "--secret", secretName, "--field", "token").CombinedOutput()
+	if err != nil || string(output) != "snap" {
+		t.Errorf("expected deleted secret to be restored, got: %s, error: %v", output, err)
+	}
+
+	if output, err := exec.Command(testBinary, "snapshots", "restore", "missing", "--force").CombinedOutput(); err == nil {
+		t.Errorf("expected restoring an unknown snapshot to fail, got: %s", output)
+	}
+}
+
+// TestSnapshotsCommand_Purge tests that a purged secret cannot be brought back by
+// restoring a snapshot.
+func TestSnapshotsCommand_Purge(t *testing.T) {
+	env.Init()
+
+	secretName := "testsnapshotspurge"
+	otherName := "testsnapshotspurgeother"
+
+	for _, name := range []string{secretName, otherName} {
+		if output, err := exec.Command(testBinary, "create", "--secret", name, "--field", fieldFile(t, "token", "purge")).CombinedOutput(); err != nil {
+			t.Fatalf("failed to create secret: %v, output: %s", err, output)
+		}
+		defer exec.Command(testBinary, "delete", "--secret", name, "--force", "--purge").Run()
+	}
+
+	// Takes a snapshot holding the secret purged next
+	if output, err := exec.Command(testBinary, "delete", "--secret", otherName, "--force").CombinedOutput(); err != nil {
+		t.Fatalf("failed to delete secret: %v, output: %s", err, output)
+	}
+
+	if output, err := exec.Command(testBinary, "delete", "--secret", secretName, "--force", "--purge").CombinedOutput(); err != nil {
+		t.Fatalf("failed to purge secret: %v, output: %s", err, output)
+	}
+
+	output, err := exec.Command(testBinary, "snapshots", "list", "--print").Output()
+	if err != nil {
+		t.Fatalf("failed to list snapshots: %v", err)
+	}
+
+	// Purging takes no snapshot, so the most recent one, listed first, was taken before it
+	ids := strings.Fields(string(output))
+	if len(ids) == 0 {
+		t.Fatalf("expected a snapshot to be taken before deleting")
+	}
+
+	if output, err := exec.Command(testBinary, "snapshots", "restore", ids[0], "--force").CombinedOutput(); err != nil {
+		t.Fatalf("failed to restore snapshot: %v, output: %s", err, output)
+	}
+
+	if output, err := exec.Command(testBinary, "view", "--secret", secretName).CombinedOutput(); err == nil {
+		t.Errorf("expected purged secret not to be restored, got: %s", output)
+	}
+
+	if output, err := exec.Command(testBinary, "view", "--secret", otherName, "--field", "token").CombinedOutput(); err != nil || string(output) != "purge" {
+		t.Errorf("expected deleted secret to be restored, got: %s, error: %v", output, err)
+	}
+}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/engmtcdrm/go-pardon"
+	pp "github.com/engmtcdrm/go-prettyprint"
+	"github.com/engmtcdrm/mellon/app"
+	"github.com/engmtcdrm/mellon/header"
+	"github.com/engmtcdrm/mellon/secrets"
+)
+
+func init() {
+	trashListCmd.Flags().BoolVarP(
+		&print,
+		"print",
+		"p",
+		false,
+		"(optional) Whether to print only the names of the deleted secrets without additional information",
+	)
+	trashEmptyCmd.Flags().StringVar(
+		&olderThan,
+		"older-than",
+		"",
+		"(optional) Only remove secrets deleted longer ago than this, e.g. 30d. Defaults to removing every secret in the trash",
+	)
+	trashEmptyCmd.Flags().BoolVarP(
+		&forceEmpty,
+		"force",
+		"f",
+		false,
+		"(optional) Whether to empty the trash without confirmation",
+	)
+
+	trashCmd.AddCommand(trashListCmd)
+	trashCmd.AddCommand(trashEmptyCmd)
+
+	rootCmd.AddCommand(trashCmd)
+}
+
+// trashCompletion completes the names of the secrets in the trash.
+func trashCompletion(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	trashed, err := secrets.TrashedSecrets()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	var names []string
+	for _, t := range trashed {
+		if !slices.Contains(names, t.Name) {
+			names = append(names, t.Name)
+		}
+	}
+
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+var trashCmd = &cobra.Command{
+	Use:   "trash",
+	Short: "Manage deleted secrets",
+	Long: "Manage deleted secrets.\n\n" +
+		"Deleted secrets are moved to the trash along with their metadata and history, recording their name and when they were deleted. " +
+		"They can be restored with the restore command until the trash is emptied.",
+	Example: fmt.Sprintf("  %s trash list\n  %s trash empty --older-than 30d", app.Name, app.Name),
+}
+
+var trashListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List the secrets in the trash",
+	Long:    "List the secrets in the trash, most recently deleted first",
+	Example: fmt.Sprintf("  %s trash list\n  %s trash list --print", app.Name, app.Name),
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		trashed, err := secrets.TrashedSecrets()
+		if err != nil {
+			return err
+		}
+
+		// Most recently deleted first
+		slices.Reverse(trashed)
+
+		if machineReadable() {
+			if trashed == nil {
+				trashed = []secrets.TrashedSecret{}
+			}
+			return printOutput(trashed)
+		}
+
+		if print {
+			for _, t := range trashed {
+				fmt.Println(t.Name)
+			}
+			return nil
+		}
+
+		header.PrintHeader()
+
+		if len(trashed) == 0 {
+			fmt.Println(pp.Info("The trash is empty"))
+			return nil
+		}
+
+		rows := make([][]string, 0, len(trashed))
+		for _, t := range trashed {
+			rows = append(rows, []string{t.Name, formatTime(t.DeletedAt)})
+		}
+
+		printTable([]string{"NAME", "DELETED"}, rows, pp.Yellow)
+		fmt.Println()
+
+		return nil
+	},
+}
+
+var trashEmptyCmd = &cobra.Command{
+	Use:     "empty",
+	Short:   "Permanently delete the secrets in the trash",
+	Long:    "Permanently delete the secrets in the trash, or only those deleted longer ago than --older-than.\n\nThe copies of the secrets kept in the snapshots taken before they were deleted are shredded as well, so they cannot be restored.",
+	Example: fmt.Sprintf("  %s trash empty\n  %s trash empty --older-than 30d --force", app.Name, app.Name),
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var age time.Duration
+		if olderThan != "" {
+			var err error
+			if age, err = secrets.ParseDuration(olderThan); err != nil {
+				return fmt.Errorf("invalid value for flag --older-than: %w", err)
+			}
+		}
+
+		if !forceEmpty {
+			if err := promptHeader(); err != nil {
+				return err
+			}
+
+			what := "every secret in the trash"
+			if age > 0 {
+				what = fmt.Sprintf("the secrets deleted more than %s ago", secrets.FormatDuration(age))
+			}
+
+			confirmEmpty := false
+			promptConfirm := pardon.NewConfirm(&confirmEmpty).
+				Title(fmt.Sprintf("Are you sure you want to permanently delete %s? %s", what, pp.Red("There is no going back.")))
+
+			if err := promptConfirm.Ask(); err != nil {
+				return err
+			}
+
+			fmt.Println()
+
+			if !confirmEmpty {
+				fmt.Println(pp.Fail("Aborted emptying the trash"))
+				return nil
+			}
+		}
+
+		removed, err := secrets.EmptyTrash(age, time.Now())
+		if err != nil {
+			return err
+		}
+
+		if !forceEmpty {
+			fmt.Println(pp.Completef("Permanently deleted %d secret(s) from the trash", len(removed)))
+		}
+
+		names := make([]string, 0, len(removed))
+		for _, t := range removed {
+			names = append(names, t.Name)
+		}
+
+		return printResult(result{Action: "purged", Secrets: names})
+	},
+}
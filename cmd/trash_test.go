@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/engmtcdrm/mellon/env"
+)
+
+// TestTrashCommand tests that deleted secrets go to the trash and can be restored,
+// unless they are purged.
+func TestTrashCommand(t *testing.T) {
+	env.Init()
+
+	secretName := "testtrash"
+
+	if output, err := exec.Command(testBinary, "create", "--secret", secretName, "--field", fieldFile(t, "token", "trashed")).CombinedOutput(); err != nil {
+		t.Fatalf("failed to create secret: %v, output: %s", err, output)
+	}
+	defer exec.Command(testBinary, "delete", "--secret", secretName, "--force", "--purge").Run()
+
+	if output, err := exec.Command(testBinary, "delete", "--secret", secretName, "--force").CombinedOutput(); err != nil {
+		t.Fatalf("failed to delete secret: %v, output: %s", err, output)
+	}
+
+	output, err := exec.Command(testBinary, "trash", "list", "--print").Output()
+	if err != nil || !strings.Contains(string(output), secretName) {
+		t.Fatalf("expected deleted secret to be in the trash, got: %s, error: %v", output, err)
+	}
+
+	if output, err := exec.Command(testBinary, "restore", "--secret", secretName).CombinedOutput(); err != nil {
+		t.Fatalf("failed to restore secret from the trash: %v, output: %s", err, output)
+	}
+
+	output, err = exec.Command(testBinary, "view", "--secret", secretName, "--field", "token").CombinedOutput()
+	if err != nil || string(output) != "trashed" {
+		t.Errorf("expected secret to be restored from the trash, got: %s, error: %v", output, err)
+	}
+
+	if output, err := exec.Command(testBinary, "restore", "--secret", secretName).CombinedOutput(); err == nil {
+		t.Errorf("expected restoring a secret that is not in the trash to fail, got: %s", output)
+	}
+
+	if output, err := exec.Command(testBinary, "restore", "--secret", secretName, "--dry-run").CombinedOutput(); err == nil {
+		t.Errorf("expected --dry-run to be rejected with --secret, got: %s", output)
+	}
+
+	if output, err := exec.Command(testBinary, "delete", "--secret", secretName, "--force", "--purge").CombinedOutput(); err != nil {
+		t.Fatalf("failed to purge secret: %v, output: %s", err, output)
+	}
+
+	output, err = exec.Command(testBinary, "trash", "list", "--print").Output()
+	if err != nil || strings.Contains(string(output), secretName) {
+		t.Errorf("expected purged secret not to be in the trash, got: %s, error: %v", output, err)
+	}
+
+	if output, err := exec.Command(testBinary, "trash", "empty", "--older-than", "bogus", "--force").CombinedOutput(); err == nil {
+		t.Errorf("expected an invalid --older-than to fail, got: %s", output)
+	}
+
+	if output, err := exec.Command(testBinary, "trash", "empty", "--older-than", "30d", "--force").CombinedOutput(); err != nil {
+		t.Errorf("failed to empty the trash: %v, output: %s", err, output)
+	}
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"maps"
 
 	"github.com/spf13/cobra"
 
@@ -37,8 +38,61 @@ func init() {
 		"(optional) Whether to delete the unencrypted secret file after encryption. Defaults to false",
 	)
 
+	updateCmd.Flags().StringVar(
+		&description,
+		"description",
+		"",
+		"(optional) A description of the secret. If provided with -s/--secret but without -f/--file, only the metadata is updated",
+	)
+	updateCmd.Flags().StringVar(
+		&owner,
+		"owner",
+		"",
+		"(optional) The owner of the secret. If provided with -s/--secret but without -f/--file, only the metadata is updated",
+	)
+	updateCmd.Flags().StringArrayVar(
+		&tags,
+		"tag",
+		nil,
+		"(optional) A tag to add to the secret. Can be repeated to add multiple tags. If provided with -s/--secret but without -f/--file, only the metadata is updated",
+	)
+	updateCmd.Flags().StringArrayVar(
+		&untags,
+		"untag",
+		nil,
+		"(optional) A tag to remove from the secret. Can be repeated to remove multiple tags. If provided with -s/--secret but without -f/--file, only the metadata is updated",
+	)
+
+	addExpiryFlags(updateCmd)
+	updateCmd.Flags().BoolVar(
+		&noExpiry,
+		"no-expiry",
+		false,
+		"(optional) Remove the expiry of the secret",
+	)
+
+	addFieldFlag(updateCmd)
+	addRawFlag(updateCmd, "(optional) Whether to store the secret exactly as given, e.g. for binary files or PEM files that need a trailing newline. Defaults to how the secret was stored before. Use --raw=false to trim leading and trailing whitespace again")
+	updateCmd.Flags().StringArrayVar(
+		&unsetFields,
+		"unset-field",
+		nil,
+		"(optional) A field to remove from a structured secret. Can be repeated to remove multiple fields",
+	)
+
+	addGenerateFlags(updateCmd)
+	addInputFlags(updateCmd)
+
+	updateCmd.MarkFlagsMutuallyExclusive("unset-field", "file")
+	updateCmd.MarkFlagsMutuallyExclusive("unset-field", "generate")
+	updateCmd.MarkFlagsMutuallyExclusive("unset-field", "stdin")
+	updateCmd.MarkFlagsMutuallyExclusive("unset-field", "fd")
+	updateCmd.MarkFlagsMutuallyExclusive("no-expiry", "expires-in")
+	updateCmd.MarkFlagsMutuallyExclusive("no-expiry", "expires-at")
 	updateCmd.MarkFlagFilename("file")
 	updateCmd.RegisterFlagCompletionFunc("secret", secretFlagCompletion)
+	updateCmd.RegisterFlagCompletionFunc("tag", tagFlagCompletion)
+	updateCmd.RegisterFlagCompletionFunc("untag", tagFlagCompletion)
 
 	rootCmd.AddCommand(updateCmd)
 }
@@ -46,29 +100,80 @@ func init() {
 var updateCmd = &cobra.Command{
 	Use:     "update",
 	Short:   "Update a secret",
-	Long:    "Update a secret",
-	Example: fmt.Sprintf("  %s update", app.Name),
+	Long:    "Update a secret.\n\nThe new value is read from a file with -f/--file, from stdin with --stdin or from a file descriptor with --fd, generated with --generate, or prompted for. Prompts are refused when stdin is not a terminal.",
+	Example: fmt.Sprintf("  %s update\n  %s update -s my_secret -f /path/to/secret.txt\n  %s update -s my_secret --owner ops --tag prod --untag staging\n  %s update -s db --field password --unset-field port\n  %s update -s db/password --generate --length 32\n  vault-cli read token | %s update -s ci/token --stdin", app.Name, app.Name, app.Name, app.Name, app.Name, app.Name),
 	PreRunE: validateUpdateCreateFlags,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		var selectedSecret secrets.Secret
 
-		if secretName != "" && secretFile != "" {
+		if len(secretFields) > 0 || len(unsetFields) > 0 {
+			return updateFieldsSecret(cmd)
+		}
+
+		if secretName != "" && (secretFile != "" || generate || readsInput(cmd)) {
 			secretPtr := secrets.FindSecretByName(secretName, secretFiles)
 			if secretPtr == nil {
-				return fmt.Errorf("could not update secret '%s': does not exist", secretName)
+				return fmt.Errorf("could not update secret '%s': %w", secretName, secrets.ErrNotFound)
 			}
 			selectedSecret = *secretPtr
-			if err := selectedSecret.EncryptFromFile(secretFile, cleanupFile); err != nil {
-				return fmt.Errorf("could not encrypt secret from file '%s': %w", secretFile, err)
+			if err := requireSingleValue(selectedSecret); err != nil {
+				return err
+			}
+			raw, err := rawMode(cmd, selectedSecret)
+			if err != nil {
+				return err
+			}
+			switch {
+			case generate:
+				if err := updateGenerated(&selectedSecret, raw); err != nil {
+					return err
+				}
+			case readsInput(cmd):
+				if err := updateFromInput(&selectedSecret, raw); err != nil {
+					return err
+				}
+			default:
+				if err := takeSnapshot("update"); err != nil {
+					return err
+				}
+				if err := selectedSecret.EncryptFromFile(secretFile, cleanupFile, raw); err != nil {
+					return fmt.Errorf("could not encrypt secret from file '%s': %w", secretFile, err)
+				}
 			}
 
-			return nil
+			if err := applyMetadataFlags(cmd, &selectedSecret); err != nil {
+				return err
+			}
+
+			return printResult(result{Action: "updated", Secrets: []string{selectedSecret.Name()}})
+		}
+
+		// Only the metadata is updated when no new secret is provided
+		if secretName != "" && metadataFlagsChanged(cmd) {
+			secretPtr := secrets.FindSecretByName(secretName, secretFiles)
+			if secretPtr == nil {
+				return fmt.Errorf("could not update secret '%s': %w", secretName, secrets.ErrNotFound)
+			}
+
+			if err := takeSnapshot("update"); err != nil {
+				return err
+			}
+
+			if err := applyMetadataFlags(cmd, secretPtr); err != nil {
+				return err
+			}
+
+			return printResult(result{Action: "updated", Secrets: []string{secretPtr.Name()}})
+		}
+
+		if err := requireTerminal(); err != nil {
+			return err
 		}
 
 		header.PrintHeader()
 
 		if secretName == "" {
-			options, err := prompts.GetSecretOptions(secretFiles, "update", env.Instance.ExeCmd())
+			options, err := prompts.GetSecretOptions(secretFiles, secrets.TagFilter{}, "update", env.Instance.ExeCmd())
 			if err != nil {
 				return err
 			}
@@ -85,12 +190,25 @@ var updateCmd = &cobra.Command{
 		} else {
 			secretPtr := secrets.FindSecretByName(secretName, secretFiles)
 			if secretPtr == nil {
-				return fmt.Errorf("secret %s does not exist!\n\nUse command %s to create the secret", pp.Red(secretName), pp.Greenf("%s create", env.Instance.ExeCmd()))
+				return fmt.Errorf("secret %s %w!\n\nUse command %s to create the secret", pp.Red(secretName), secrets.ErrNotFound, pp.Greenf("%s create", env.Instance.ExeCmd()))
 			}
 			selectedSecret = *secretPtr
 		}
 
-		if secretFile == "" {
+		if err := requireSingleValue(selectedSecret); err != nil {
+			return err
+		}
+
+		raw, err := rawMode(cmd, selectedSecret)
+		if err != nil {
+			return err
+		}
+
+		if generate {
+			if err := updateGenerated(&selectedSecret, raw); err != nil {
+				return err
+			}
+		} else if secretFile == "" {
 			var secret []byte
 
 			promptSecret := pardon.NewPassword(&secret).
@@ -100,17 +218,30 @@ var updateCmd = &cobra.Command{
 				return err
 			}
 
-			if err := selectedSecret.Encrypt(secret); err != nil {
+			if err := takeSnapshot("update"); err != nil {
+				secrets.ClearSecret(&secret)
+				return err
+			}
+
+			if err := selectedSecret.Encrypt(secret, raw); err != nil {
 				return fmt.Errorf("could not encrypt secret: %w", err)
 			}
 
 			fmt.Println()
 		} else {
-			if err := selectedSecret.EncryptFromFile(secretFile, cleanupFile); err != nil {
+			if err := takeSnapshot("update"); err != nil {
+				return err
+			}
+
+			if err := selectedSecret.EncryptFromFile(secretFile, cleanupFile, raw); err != nil {
 				return fmt.Errorf("could not encrypt secret from file '%s': %w", secretFile, err)
 			}
 		}
 
+		if err := applyMetadataFlags(cmd, &selectedSecret); err != nil {
+			return err
+		}
+
 		fmt.Println(pp.Complete("Secret encrypted and saved"))
 		fmt.Println()
 		fmt.Printf("You can run the commmand %s to view the unencrypted secret\n", pp.Greenf("%s view -s %s", env.Instance.ExeCmd(), selectedSecret.Name()))
@@ -118,3 +249,122 @@ var updateCmd = &cobra.Command{
 		return nil
 	},
 }
+
+// updateFieldsSecret sets and removes fields of a structured secret as provided through
+// the --field and --unset-field flags. Prompts are only shown to select the secret or
+// for field values.
+func updateFieldsSecret(cmd *cobra.Command) error {
+	var selectedSecret secrets.Secret
+
+	interactive := secretName == "" || fieldsNeedPrompt()
+
+	if interactive {
+		if err := requireTerminal(); err != nil {
+			return err
+		}
+
+		header.PrintHeader()
+	}
+
+	if secretName == "" {
+		options, err := prompts.GetSecretOptions(secretFiles, secrets.TagFilter{}, "update", env.Instance.ExeCmd())
+		if err != nil {
+			return err
+		}
+
+		promptSelect := pardon.NewSelect(&selectedSecret).
+			Title("What secret do you want to update?").
+			Options(options...)
+
+		if err := promptSelect.Ask(); err != nil {
+			return err
+		}
+
+		fmt.Println()
+	} else {
+		secretPtr := secrets.FindSecretByName(secretName, secretFiles)
+		if secretPtr == nil {
+			return fmt.Errorf("could not update secret '%s': %w", secretName, secrets.ErrNotFound)
+		}
+		selectedSecret = *secretPtr
+	}
+
+	fields, err := selectedSecret.DecryptFields()
+	if err != nil {
+		return err
+	}
+
+	values, err := readFieldFlags()
+	if err != nil {
+		return err
+	}
+
+	maps.Copy(fields, values)
+
+	for _, name := range unsetFields {
+		if _, ok := fields[name]; !ok {
+			return fmt.Errorf("could not remove field '%s' from secret '%s': field %w", name, selectedSecret.Name(), secrets.ErrNotFound)
+		}
+		delete(fields, name)
+	}
+
+	if err := takeSnapshot("update"); err != nil {
+		return err
+	}
+
+	if err := selectedSecret.EncryptFields(fields); err != nil {
+		return fmt.Errorf("could not encrypt secret: %w", err)
+	}
+
+	if err := applyMetadataFlags(cmd, &selectedSecret); err != nil {
+		return err
+	}
+
+	if interactive {
+		fmt.Println(pp.Complete("Secret encrypted and saved"))
+		return nil
+	}
+
+	return printResult(result{Action: "updated", Secrets: []string{selectedSecret.Name()}})
+}
+
+// updateGenerated replaces the value of the secret with a generated one. The value is
+// generated before the snapshot is taken, so nothing changes if it cannot be.
+func updateGenerated(secret *secrets.Secret, raw bool) error {
+	value, err := generateValue()
+	if err != nil {
+		return err
+	}
+
+	if err := takeSnapshot("update"); err != nil {
+		secrets.ClearSecret(&value)
+		return err
+	}
+
+	if err := secret.Encrypt(value, raw); err != nil {
+		return fmt.Errorf("could not encrypt generated secret: %w", err)
+	}
+
+	return nil
+}
+
+// updateFromInput replaces the value of the secret with the one read through the
+// --stdin or --fd flag. The value is read before the snapshot is taken, so nothing
+// changes if it cannot be.
+func updateFromInput(secret *secrets.Secret, raw bool) error {
+	value, err := readSecretInput()
+	if err != nil {
+		return err
+	}
+
+	if err := takeSnapshot("update"); err != nil {
+		secrets.ClearSecret(&value)
+		return err
+	}
+
+	if err := secret.Encrypt(value, raw); err != nil {
+		return fmt.Errorf("could not encrypt secret: %w", err)
+	}
+
+	return nil
+}
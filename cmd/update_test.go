@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/engmtcdrm/mellon/env"
@@ -468,3 +469,88 @@ func TestUpdateCommand_ValidSecretNames(t *testing.T) {
 		})
 	}
 }
+
+// TestUpdateCommand_MetadataOnly tests updating only the metadata of a secret.
+func TestUpdateCommand_MetadataOnly(t *testing.T) {
+	env.Init()
+
+	secretFile := filepath.Join(t.TempDir(), "secret.txt")
+	secretName := "testupdatemeta"
+	secretContent := "updatemetacontent"
+
+	if err := os.WriteFile(secretFile, []byte(secretContent), 0644); err != nil {
+		t.Fatalf("failed to write secret file: %v", err)
+	}
+
+	createCmd := exec.Command(testBinary, "create", "--secret", secretName, "--file", secretFile, "--owner", "original-owner")
+	if output, err := createCmd.CombinedOutput(); err != nil {
+		t.Fatalf("failed to create initial secret: %v, output: %s", err, output)
+	}
+	defer exec.Command(testBinary, "delete", "--secret", secretName, "--force").Run()
+
+	cmd := exec.Command(testBinary, "update", "--secret", secretName, "--owner", "new-owner", "--description", "Updated description")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("expected success updating metadata, got error: %v, output: %s", err, output)
+	}
+
+	listCmd := exec.Command(testBinary, "list")
+	output, err := listCmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("expected success for list command, got error: %v", err)
+	}
+
+	if !strings.Contains(string(output), "new-owner") || strings.Contains(string(output), "original-owner") {
+		t.Errorf("expected owner to be updated, got: %s", output)
+	}
+
+	if !strings.Contains(string(output), "Updated description") {
+		t.Errorf("expected description to be updated, got: %s", output)
+	}
+
+	// The secret value is left untouched
+	viewCmd := exec.Command(testBinary, "view", "--secret", secretName)
+	output, err = viewCmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("expected success viewing secret, got error: %v", err)
+	}
+
+	if string(output) != secretContent {
+		t.Errorf("expected secret content '%s', got '%s'", secretContent, output)
+	}
+}
+
+// TestUpdateCommand_Stdin tests updating a secret from stdin, and that prompts are
+// refused when stdin is not a terminal.
+func TestUpdateCommand_Stdin(t *testing.T) {
+	env.Init()
+
+	secretName := "testupdatestdin"
+
+	cmd := exec.Command(testBinary, "create", "--secret", secretName, "--stdin")
+	cmd.Stdin = strings.NewReader("original")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("failed to create secret: %v, output: %s", err, output)
+	}
+	defer exec.Command(testBinary, "delete", "--secret", secretName, "--force").Run()
+
+	cmd = exec.Command(testBinary, "update", "--secret", secretName, "--stdin")
+	cmd.Stdin = strings.NewReader("updated\n")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("failed to update secret from stdin: %v, output: %s", err, output)
+	}
+
+	output, err := exec.Command(testBinary, "view", "--secret", secretName).Output()
+	if err != nil || string(output) != "updated" {
+		t.Errorf("expected the secret read from stdin, got: %q, error: %v", output, err)
+	}
+
+	if output, err := exec.Command(testBinary, "update", "--secret", secretName, "--stdin", "--generate").CombinedOutput(); err == nil {
+		t.Errorf("expected --stdin with --generate to fail, got: %s", output)
+	}
+
+	cmd = exec.Command(testBinary, "update", "--secret", secretName)
+	cmd.Stdin = strings.NewReader("prompted\n")
+	if output, err := cmd.CombinedOutput(); err == nil || !strings.Contains(string(output), "stdin is not a terminal") {
+		t.Errorf("expected update to refuse to prompt, got: %s, error: %v", output, err)
+	}
+}
@@ -1,11 +1,19 @@
 package cmd
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strings"
 
+	"github.com/engmtcdrm/go-pardon"
+	pp "github.com/engmtcdrm/go-prettyprint"
+	"github.com/engmtcdrm/mellon/env"
 	"github.com/engmtcdrm/mellon/secrets"
 	"github.com/spf13/cobra"
 )
@@ -16,9 +24,386 @@ func validateUpdateCreateFlags(cmd *cobra.Command, args []string) error {
 		return errors.New("flag -c/--cleanup can only be used when -s/--secret and -f/--file are provided")
 	}
 
+	if (readStdin || cmd.Flags().Changed("fd")) && secretName == "" {
+		return errors.New("flags --stdin and --fd can only be used when -s/--secret is provided")
+	}
+
+	if cmd.Flags().Changed("fd") && inputFD < 0 {
+		return fmt.Errorf("invalid file descriptor %d", inputFD)
+	}
+
+	if err := validateGeneratorFlags(cmd); err != nil {
+		return err
+	}
+
+	for _, t := range slices.Concat(tags, untags) {
+		if err := secrets.ValidateTag(t); err != nil {
+			return fmt.Errorf("%w. The tag provided was '%s'", err, t)
+		}
+	}
+
+	if expiresIn != "" {
+		if ttl, err := secrets.ParseDuration(expiresIn); err != nil {
+			return err
+		} else if ttl <= 0 {
+			return errors.New("flag --expires-in must be greater than zero")
+		}
+	}
+
+	if expiresAt != "" {
+		if _, err := secrets.ParseTime(expiresAt); err != nil {
+			return err
+		}
+	}
+
+	for _, spec := range slices.Concat(secretFields, unsetFields) {
+		name, _, _ := strings.Cut(spec, "=")
+		if err := secrets.ValidateFieldName(name); err != nil {
+			return fmt.Errorf("%w. The field provided was '%s'", err, name)
+		}
+	}
+
+	return validateFieldValues()
+}
+
+// validateFieldValues returns an error if a value provided through the --field flag is
+// given on the command line, where it would be visible to other users in the process
+// list and kept in the shell history, or if more than one value is read from stdin.
+func validateFieldValues() error {
+	fromStdin := 0
+
+	for _, spec := range secretFields {
+		name, value, hasValue := strings.Cut(spec, "=")
+
+		switch {
+		case !hasValue || strings.HasPrefix(value, "@"):
+		case value == "-":
+			fromStdin++
+		default:
+			return fmt.Errorf("the value of field '%s' cannot be given on the command line, where other users can see it. Use %s to read it from a file, %s to read it from stdin or %s to be prompted for it", name, pp.Green(name+"=@file"), pp.Green(name+"=-"), pp.Green(name))
+		}
+	}
+
+	if fromStdin > 1 {
+		return errors.New("only one field can be read from stdin")
+	}
+
+	return nil
+}
+
+// addFieldFlag adds the --field flag used to set the fields of a structured secret.
+func addFieldFlag(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(
+		&secretFields,
+		"field",
+		nil,
+		"(optional) A field of a structured secret as name=@file to read the value from a file, name=- to read it from stdin, or name to be prompted for the value. Values cannot be given on the command line, where other users could see them. Can be repeated to set multiple fields",
+	)
+
+	cmd.MarkFlagsMutuallyExclusive("field", "file")
+}
+
+// addInputFlags adds the --stdin and --fd flags used to read a secret without it
+// being prompted for or written to a file.
+func addInputFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(
+		&readStdin,
+		"stdin",
+		false,
+		"(optional) Whether to read the secret from stdin, e.g. piped from another command, so it is never written to a file",
+	)
+	cmd.Flags().IntVar(
+		&inputFD,
+		"fd",
+		0,
+		"(optional) The file descriptor to read the secret from, e.g. 3 with 3< <(some-command), so it is never written to a file",
+	)
+
+	cmd.MarkFlagsMutuallyExclusive("stdin", "fd")
+	for _, name := range []string{"file", "generate", "field"} {
+		cmd.MarkFlagsMutuallyExclusive("stdin", name)
+		cmd.MarkFlagsMutuallyExclusive("fd", name)
+	}
+}
+
+// readsInput reports whether the secret is read through the --stdin or --fd flag.
+func readsInput(cmd *cobra.Command) bool {
+	return readStdin || cmd.Flags().Changed("fd")
+}
+
+// readSecretInput reads the secret from stdin or the file descriptor given through the
+// --stdin or --fd flag.
+func readSecretInput() ([]byte, error) {
+	source, f := "stdin", os.Stdin
+	if !readStdin {
+		source = fmt.Sprintf("file descriptor %d", inputFD)
+		f = os.NewFile(uintptr(inputFD), source)
+		defer f.Close()
+	}
+
+	secret, err := io.ReadAll(f)
+	if err != nil {
+		secrets.ClearSecret(&secret)
+		return nil, fmt.Errorf("could not read secret from %s: %w", source, err)
+	}
+
+	if len(bytes.TrimSpace(secret)) == 0 {
+		return nil, fmt.Errorf("could not read secret from %s: secret is empty", source)
+	}
+
+	return secret, nil
+}
+
+// requireTerminal returns an error if stdin is not a terminal or the output is
+// machine-readable, so prompts are never shown when mellon is run from a script or a
+// pipe, where they would hang or fail.
+func requireTerminal() error {
+	if err := requireInteractive(); err != nil {
+		return err
+	}
+
+	if stdinIsTerminal() {
+		return nil
+	}
+
+	return fmt.Errorf(
+		"%w as stdin is not a terminal\n\nProvide the secret with %s and one of %s, %s, %s or %s",
+		errPromptRefused, pp.Green("-s/--secret"), pp.Green("--stdin"), pp.Green("--fd"), pp.Green("-f/--file"), pp.Green("--generate"),
+	)
+}
+
+// addRawFlag adds the --raw flag used to store a secret exactly as given.
+func addRawFlag(cmd *cobra.Command, usage string) {
+	cmd.Flags().BoolVar(
+		&rawSecret,
+		"raw",
+		false,
+		usage,
+	)
+}
+
+// addAllowExpiredFlag adds the flag allowing cmd to decrypt secrets that have expired.
+// Errors of cmd caused by an expired secret get a hint on using the flag.
+func addAllowExpiredFlag(cmd *cobra.Command, usage string) {
+	cmd.Flags().BoolVar(
+		&allowExpiry,
+		"allow-expired",
+		false,
+		usage,
+	)
+
+	runE := cmd.RunE
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		return expiredError(runE(cmd, args))
+	}
+}
+
+// expiredError adds a hint on how to use a secret anyway when it has expired.
+func expiredError(err error) error {
+	if errors.Is(err, secrets.ErrExpired) {
+		return fmt.Errorf("%w\n\nUse flag %s to use it anyway", err, pp.Green("--allow-expired"))
+	}
+
+	return err
+}
+
+// rawMode reports whether the secret should be stored exactly as given. Without the
+// --raw flag, the choice recorded with the secret is kept.
+func rawMode(cmd *cobra.Command, secret secrets.Secret) (bool, error) {
+	if cmd.Flags().Changed("raw") {
+		return rawSecret, nil
+	}
+
+	meta, err := secret.Metadata()
+	if err != nil {
+		return false, err
+	}
+
+	return meta.Raw, nil
+}
+
+// fieldsNeedPrompt reports whether any field provided through the --field flag has
+// to be prompted for.
+func fieldsNeedPrompt() bool {
+	return slices.ContainsFunc(secretFields, func(spec string) bool {
+		return !strings.Contains(spec, "=")
+	})
+}
+
+// readFieldFlags reads the values of the fields provided through the --field flag.
+// Values are read from a file with name=@file, from stdin with name=-, or prompted for
+// when only the name is given. Values read from a file or stdin are trimmed of leading
+// and trailing whitespace, unless the --raw flag is provided.
+func readFieldFlags() (map[string]string, error) {
+	values := make(map[string]string, len(secretFields))
+
+	for _, spec := range secretFields {
+		name, value, hasValue := strings.Cut(spec, "=")
+
+		switch {
+		case !hasValue:
+			var secret []byte
+
+			promptSecret := pardon.NewPassword(&secret).
+				Title(fmt.Sprintf("Enter a value for field %s:", pp.Yellow(name)))
+
+			if err := promptSecret.Ask(); err != nil {
+				return nil, err
+			}
+
+			fmt.Println()
+
+			value = string(secret)
+			secrets.ClearSecret(&secret)
+		case value == "-":
+			data, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				secrets.ClearSecret(&data)
+				return nil, fmt.Errorf("could not read value of field '%s' from stdin: %w", name, err)
+			}
+
+			value = string(data)
+			if !rawSecret {
+				value = strings.TrimSpace(value)
+			}
+			secrets.ClearSecret(&data)
+		case strings.HasPrefix(value, "@"):
+			path, err := env.ExpandTilde(strings.TrimPrefix(value, "@"))
+			if err != nil {
+				return nil, err
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("could not read value of field '%s' from file '%s': %w", name, path, err)
+			}
+
+			value = string(data)
+			if !rawSecret {
+				value = strings.TrimSpace(value)
+			}
+			secrets.ClearSecret(&data)
+		}
+
+		values[name] = value
+	}
+
+	return values, nil
+}
+
+// requireSingleValue returns an error if the secret holds fields, as those have to be
+// updated with the --field flag instead of replacing the whole value.
+func requireSingleValue(secret secrets.Secret) error {
+	meta, err := secret.Metadata()
+	if err != nil {
+		return err
+	}
+
+	if meta.IsStructured() {
+		return fmt.Errorf("secret '%s' has fields\n\nUse flag %s to update them", secret.Name(), pp.Green("--field"))
+	}
+
 	return nil
 }
 
+// addExpiryFlags adds the --expires-in and --expires-at flags used to set when a secret expires.
+func addExpiryFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(
+		&expiresIn,
+		"expires-in",
+		"",
+		"(optional) How long the secret is valid, e.g. 90d, 2w or 12h. The expiry is renewed every time the secret is updated",
+	)
+	cmd.Flags().StringVar(
+		&expiresAt,
+		"expires-at",
+		"",
+		"(optional) When the secret expires, e.g. 2030-01-31, \"2030-01-31 17:00\" or an RFC 3339 timestamp",
+	)
+
+	cmd.MarkFlagsMutuallyExclusive("expires-in", "expires-at")
+}
+
+// tagFilter returns the tag filter provided through the --tag and --not-tag flags.
+func tagFilter() secrets.TagFilter {
+	return secrets.TagFilter{Include: filterTags, Exclude: excludeTags}
+}
+
+// addTagFilterFlags adds the --tag and --not-tag flags used to select secrets by their tags.
+func addTagFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(
+		&filterTags,
+		"tag",
+		nil,
+		"(optional) Only select secrets with this tag. Can be repeated to require multiple tags",
+	)
+	cmd.Flags().StringArrayVar(
+		&excludeTags,
+		"not-tag",
+		nil,
+		"(optional) Only select secrets without this tag. Can be repeated to exclude multiple tags",
+	)
+
+	cmd.RegisterFlagCompletionFunc("tag", tagFlagCompletion)
+	cmd.RegisterFlagCompletionFunc("not-tag", tagFlagCompletion)
+}
+
+// metadataFlagsChanged reports whether any flag setting secret metadata was provided.
+func metadataFlagsChanged(cmd *cobra.Command) bool {
+	for _, name := range []string{"description", "owner", "tag", "untag", "expires-in", "expires-at", "no-expiry"} {
+		if cmd.Flags().Lookup(name) != nil && cmd.Flags().Changed(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// applyMetadataFlags records the metadata provided through flags on the secret.
+func applyMetadataFlags(cmd *cobra.Command, secret *secrets.Secret) error {
+	if !metadataFlagsChanged(cmd) {
+		return nil
+	}
+
+	meta, err := secret.Metadata()
+	if err != nil {
+		return err
+	}
+
+	if cmd.Flags().Changed("description") {
+		meta.Description = description
+	}
+
+	if cmd.Flags().Changed("owner") {
+		meta.Owner = owner
+	}
+
+	meta.AddTags(tags...)
+	meta.RemoveTags(untags...)
+
+	if expiresIn != "" {
+		ttl, err := secrets.ParseDuration(expiresIn)
+		if err != nil {
+			return err
+		}
+		meta.SetExpiry(ttl)
+	}
+
+	if expiresAt != "" {
+		t, err := secrets.ParseTime(expiresAt)
+		if err != nil {
+			return err
+		}
+		meta.SetExpiresAt(t)
+	}
+
+	if noExpiry {
+		meta.SetExpiry(0)
+	}
+
+	return secret.SaveMetadata(meta)
+}
+
 // validateSecretName checks if the provided secret name is valid.
 func validateSecretName(name string) error {
 	if name == "" {
@@ -30,7 +415,7 @@ func validateSecretName(name string) error {
 	}
 
 	if secretPtr := secrets.FindSecretByName(name, secretFiles); secretPtr != nil {
-		return errors.New("secret with that name already exists")
+		return fmt.Errorf("secret with that name %w", secrets.ErrExists)
 	}
 
 	return nil
@@ -53,6 +438,43 @@ func mkdir(path string, dirMode os.FileMode) {
 	}
 }
 
+// writeOutputFile writes data to the output file with the secret file mode, creating
+// the output directory if needed. An existing file is made private as well.
+func writeOutputFile(path string, data []byte, what string) error {
+	outputDir := filepath.Dir(path)
+	if _, err := os.Stat(outputDir); os.IsNotExist(err) {
+		err = os.MkdirAll(outputDir, dirMode)
+		if err != nil {
+			return fmt.Errorf("failed to create output directory for output file '%s'", path)
+		}
+	}
+
+	if err := os.WriteFile(path, data, secretMode); err != nil {
+		return fmt.Errorf("failed to write %s to output file '%s'", what, path)
+	}
+
+	// An existing output file keeps its mode when written, so make sure it is private
+	if err := os.Chmod(path, secretMode); err != nil {
+		return fmt.Errorf("failed to set permissions of output file '%s'", path)
+	}
+
+	return nil
+}
+
+// warnShred reports that files deleted on the filesystem described by reason may still
+// be recoverable, as overwriting them does not destroy their original contents.
+func warnShred(reason string) {
+	warning := pp.Alertf("Deleted files may still be recoverable: %s", reason)
+
+	// Kept out of machine-readable output
+	if machineReadable() {
+		fmt.Fprintln(os.Stderr, warning)
+		return
+	}
+
+	fmt.Println(warning)
+}
+
 // secureFiles walks through the given path and sets the permissions
 // for directories and files to the specified modes.
 func secureFiles(path string, dirMode os.FileMode, secretMode os.FileMode) {
@@ -97,3 +519,34 @@ func secretFlagCompletion(cmd *cobra.Command, args []string, toComplete string)
 	}
 	return secretNames, cobra.ShellCompDirectiveNoFileComp
 }
+
+// namespaceCompletion provides shell completion for arguments taking a namespace.
+func namespaceCompletion(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	return secrets.Namespaces(secretFiles), cobra.ShellCompDirectiveNoFileComp
+}
+
+// tagFlagCompletion provides shell completion for flags taking a tag.
+func tagFlagCompletion(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var tagNames []string
+
+	for _, secret := range secretFiles {
+		meta, err := secret.Metadata()
+		if err != nil {
+			continue
+		}
+
+		for _, t := range meta.Tags {
+			if !slices.Contains(tagNames, t) {
+				tagNames = append(tagNames, t)
+			}
+		}
+	}
+
+	slices.Sort(tagNames)
+
+	return tagNames, cobra.ShellCompDirectiveNoFileComp
+}
@@ -1,10 +1,14 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -12,7 +16,6 @@ import (
 	pp "github.com/engmtcdrm/go-prettyprint"
 	"github.com/engmtcdrm/mellon/app"
 	"github.com/engmtcdrm/mellon/env"
-	"github.com/engmtcdrm/mellon/header"
 	"github.com/engmtcdrm/mellon/secrets"
 	"github.com/engmtcdrm/mellon/secrets/prompts"
 )
@@ -33,7 +36,42 @@ func init() {
 		"(optional) File to write decrypted secret to. Defaults to outputting to stdout. This only works with the option -s/--secret",
 	)
 
+	viewCmd.Flags().IntVar(
+		&version,
+		"version",
+		0,
+		"(optional) The version of the secret to view. Defaults to the current version. This only works with the option -s/--secret",
+	)
+
+	viewCmd.Flags().StringVar(
+		&viewField,
+		"field",
+		"",
+		"(optional) The field of a structured secret to view. Also works for secrets holding a JSON object",
+	)
+	viewCmd.Flags().StringVar(
+		&viewQuery,
+		"query",
+		"",
+		"(optional) A JSONPath-style query selecting the value to view, e.g. $.hosts[0].port",
+	)
+
+	viewCmd.Flags().StringVar(
+		&encoding,
+		"encoding",
+		"",
+		"(optional) Encode the secret for embedding in other tools. Valid encodings are base64, hex and url",
+	)
+
+	addAllowExpiredFlag(viewCmd, "(optional) Whether to allow viewing a secret that has expired")
+
+	addTagFilterFlags(viewCmd)
+
+	viewCmd.MarkFlagsMutuallyExclusive("field", "query")
+	viewCmd.MarkFlagsMutuallyExclusive("secret", "tag")
+	viewCmd.MarkFlagsMutuallyExclusive("secret", "not-tag")
 	viewCmd.RegisterFlagCompletionFunc("secret", secretFlagCompletion)
+	viewCmd.RegisterFlagCompletionFunc("encoding", cobra.FixedCompletions(secrets.Encodings, cobra.ShellCompDirectiveNoFileComp))
 
 	rootCmd.AddCommand(viewCmd)
 }
@@ -43,14 +81,131 @@ func validateViewFlags(cmd *cobra.Command, args []string) error {
 		return errors.New("flag -o/--output can only be used when -s/--secret is provided")
 	}
 
+	if encoding != "" && !slices.Contains(secrets.Encodings, encoding) {
+		return fmt.Errorf("invalid encoding '%s'. Valid encodings are: %s", encoding, strings.Join(secrets.Encodings, ", "))
+	}
+
+	if cmd.Flags().Changed("version") {
+		if secretName == "" {
+			return errors.New("flag --version can only be used when -s/--secret is provided")
+		}
+
+		if version < 1 {
+			return errors.New("flag --version must be 1 or greater")
+		}
+	}
+
 	return nil
 }
 
+// selectValue returns the part of the decrypted secret selected with the --field or
+// --query flags, or the whole secret if neither is provided. Structured secrets viewed
+// as a whole are indented for readability, going by the version provided through the
+// --version flag when there is one.
+func selectValue(secret secrets.Secret, data []byte) ([]byte, error) {
+	switch {
+	case viewField != "":
+		value, err := secrets.Field(data, viewField)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read field of secret '%s': %w", secret.Name(), err)
+		}
+		return []byte(value), nil
+	case viewQuery != "":
+		value, err := secrets.Query(data, viewQuery)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query secret '%s': %w", secret.Name(), err)
+		}
+		return []byte(value), nil
+	}
+
+	var meta secrets.Metadata
+	var err error
+
+	if version == 0 {
+		meta, err = secret.Metadata()
+	} else {
+		meta, err = secret.VersionMetadata(version)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if meta.IsStructured() {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, data, "", "  "); err == nil {
+			return indented.Bytes(), nil
+		}
+	}
+
+	return data, nil
+}
+
+// encodeValue encodes the secret with the encoding provided through the --encoding
+// flag, or returns it unchanged if no encoding is provided.
+func encodeValue(data []byte) ([]byte, error) {
+	if encoding == "" {
+		return data, nil
+	}
+
+	value, err := secrets.Encode(data, encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(value), nil
+}
+
+// viewedValue returns the value selected from the decrypted secret for machine-readable
+// output, along with how it was selected and encoded.
+func viewedValue(secret secrets.Secret, data []byte) (secretValue, error) {
+	data, err := selectValue(secret, data)
+	if err != nil {
+		return secretValue{}, err
+	}
+
+	value, err := newSecretValue(secret.Name(), data, encoding)
+	if err != nil {
+		return secretValue{}, err
+	}
+
+	value.Version = version
+	value.Field = viewField + viewQuery
+
+	return value, nil
+}
+
+// viewAll outputs the values of every secret selected with the --tag and --not-tag
+// flags, instead of prompting for one, when the output is machine-readable.
+func viewAll() error {
+	selected, err := secrets.FilterSecrets(secretFiles, tagFilter())
+	if err != nil {
+		return err
+	}
+
+	values := make([]secretValue, 0, len(selected))
+	for _, s := range selected {
+		data, err := s.Decrypt()
+		if err != nil {
+			return err
+		}
+
+		value, err := viewedValue(s, data)
+		secrets.ClearSecret(&data)
+		if err != nil {
+			return err
+		}
+
+		values = append(values, value)
+	}
+
+	return printOutput(values)
+}
+
 var viewCmd = &cobra.Command{
 	Use:     "view",
 	Short:   "View a secret",
-	Long:    "View a secret",
-	Example: fmt.Sprintf("  %s view\n  %s view -s awesome-secret", app.Name, app.Name),
+	Long:    "View a secret.\n\nWith --output-format, the secret is output as an object holding its name and value. Without -s/--secret, every secret selected with --tag and --not-tag is output instead of prompting for one.",
+	Example: fmt.Sprintf("  %s view\n  %s view -s awesome-secret\n  %s view -s awesome-secret --version 3\n  %s view -s db --field password\n  %s view -s tls-key --encoding base64\n  %s view -s db --query '$.hosts[0].port'\n  %s view --tag ci", app.Name, app.Name, app.Name, app.Name, app.Name, app.Name, app.Name),
 	PreRunE: validateViewFlags,
 	// ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 	// 	var secretNames []string
@@ -62,10 +217,16 @@ var viewCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		var selectedSecretFile secrets.Secret
 
+		if secretName == "" && machineReadable() {
+			return viewAll()
+		}
+
 		if secretName == "" {
-			header.PrintHeader()
+			if err := promptHeader(); err != nil {
+				return err
+			}
 
-			options, err := prompts.GetSecretOptions(secretFiles, "view", env.Instance.ExeCmd())
+			options, err := prompts.GetSecretOptions(secretFiles, tagFilter(), "view", env.Instance.ExeCmd())
 			if err != nil {
 				return err
 			}
@@ -80,7 +241,15 @@ var viewCmd = &cobra.Command{
 
 			secret, err := selectedSecretFile.Decrypt()
 			if err != nil {
-				return errors.New("failed to decrypt secret. Encrypted secret may be corrupted")
+				return err
+			}
+
+			if secret, err = selectValue(selectedSecretFile, secret); err != nil {
+				return err
+			}
+
+			if secret, err = encodeValue(secret); err != nil {
+				return err
 			}
 
 			fmt.Println()
@@ -93,14 +262,39 @@ var viewCmd = &cobra.Command{
 
 		secretPtr := secrets.FindSecretByName(secretName, secretFiles)
 		if secretPtr == nil {
-			return fmt.Errorf("failed to read secret '%s': secret does not exist", secretName)
+			return fmt.Errorf("failed to read secret '%s': secret %w", secretName, secrets.ErrNotFound)
 		}
 
 		selectedSecretFile = *secretPtr
 
-		secret, err := selectedSecretFile.Decrypt()
+		var secret []byte
+		var err error
+
+		if version == 0 {
+			secret, err = selectedSecretFile.Decrypt()
+		} else {
+			secret, err = selectedSecretFile.DecryptVersion(version)
+		}
 		if err != nil {
-			return fmt.Errorf("failed to decrypt secret '%s'. Encrypted secret may be corrupted", secretName)
+			return err
+		}
+
+		if machineReadable() && output == "" {
+			value, err := viewedValue(selectedSecretFile, secret)
+			secrets.ClearSecret(&secret)
+			if err != nil {
+				return err
+			}
+
+			return printOutput(value)
+		}
+
+		if secret, err = selectValue(selectedSecretFile, secret); err != nil {
+			return err
+		}
+
+		if secret, err = encodeValue(secret); err != nil {
+			return err
 		}
 
 		if output == "" {
@@ -121,6 +315,10 @@ var viewCmd = &cobra.Command{
 		}
 		secret = nil
 
+		if output != "" {
+			return printResult(result{Action: "written", Secrets: []string{selectedSecretFile.Name()}, Path: output})
+		}
+
 		return nil
 	},
 }
@@ -253,3 +253,162 @@ func TestViewCommand_NoFlags(t *testing.T) {
 	// Test view command without any flags (should enter interactive mode, but we skip this)
 	t.Skip("Skipping interactive test: view without flags")
 }
+
+// TestViewCommand_Fields tests creating, updating and viewing fields of a structured secret.
+func TestViewCommand_Fields(t *testing.T) {
+	env.Init()
+
+	secretName := "testviewfields"
+	passwordFile := filepath.Join(t.TempDir(), "password.txt")
+
+	if err := os.WriteFile(passwordFile, []byte("hunter2\n"), 0644); err != nil {
+		t.Fatalf("failed to write password file: %v", err)
+	}
+
+	cmd := exec.Command(testBinary, "create", "--secret", secretName, "--field", fieldFile(t, "host", "db.example.com"), "--field", fieldFile(t, "port", "5432"), "--field", "password=@"+passwordFile)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("failed to create structured secret: %v, output: %s", err, output)
+	}
+	defer exec.Command(testBinary, "delete", "--secret", secretName, "--force").Run()
+
+	for field, expected := range map[string]string{"host": "db.example.com", "port": "5432", "password": "hunter2"} {
+		output, err := exec.Command(testBinary, "view", "--secret", secretName, "--field", field).Output()
+		if err != nil {
+			t.Fatalf("failed to view field '%s': %v", field, err)
+		}
+
+		if string(output) != expected {
+			t.Errorf("expected field '%s' to be '%s', got: '%s'", field, expected, output)
+		}
+	}
+
+	output, err := exec.Command(testBinary, "view", "--secret", secretName, "--query", "$.host").Output()
+	if err != nil || string(output) != "db.example.com" {
+		t.Errorf("expected query to return host, got: '%s', error: %v", output, err)
+	}
+
+	cmd = exec.Command(testBinary, "update", "--secret", secretName, "--field", fieldFile(t, "user", "app"), "--unset-field", "port")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("failed to update fields: %v, output: %s", err, output)
+	}
+
+	output, err = exec.Command(testBinary, "view", "--secret", secretName).Output()
+	if err != nil {
+		t.Fatalf("failed to view structured secret: %v", err)
+	}
+
+	if !strings.Contains(string(output), `"user": "app"`) || strings.Contains(string(output), "port") {
+		t.Errorf("expected updated fields, got: %s", output)
+	}
+
+	// The whole value of a structured secret cannot be replaced
+	cmd = exec.Command(testBinary, "update", "--secret", secretName, "--file", passwordFile)
+	if output, err := cmd.CombinedOutput(); err == nil {
+		t.Errorf("expected error replacing value of structured secret, got output: %s", output)
+	}
+
+	for _, args := range [][]string{
+		{"view", "--secret", secretName, "--field", "port"},
+		{"view", "--secret", secretName, "--field", "host", "--query", "$.host"},
+		{"update", "--secret", secretName, "--unset-field", "missing"},
+		{"create", "--secret", secretName + "-bad", "--field", "bad.name=x"},
+	} {
+		if output, err := exec.Command(testBinary, args...).CombinedOutput(); err == nil {
+			t.Errorf("expected error for %v, got output: %s", args, output)
+		}
+	}
+}
+
+// TestViewCommand_VersionIndent tests that a previous version is indented only if it
+// was structured itself.
+func TestViewCommand_VersionIndent(t *testing.T) {
+	env.Init()
+
+	secretName := "testviewversionindent"
+	tmpDir := t.TempDir()
+	secretFile := filepath.Join(tmpDir, "secret.json")
+	bitwardenFile := filepath.Join(tmpDir, "bitwarden.json")
+
+	if err := os.WriteFile(secretFile, []byte(`{"a":1}`), 0644); err != nil {
+		t.Fatalf("failed to write secret file: %v", err)
+	}
+
+	bitwarden := `{"encrypted": false, "items": [{"type": 1, "name": "` + secretName + `", "login": {"username": "app", "password": "hunter2"}}]}`
+	if err := os.WriteFile(bitwardenFile, []byte(bitwarden), 0644); err != nil {
+		t.Fatalf("failed to write import file: %v", err)
+	}
+
+	if output, err := exec.Command(testBinary, "create", "--secret", secretName, "--file", secretFile).CombinedOutput(); err != nil {
+		t.Fatalf("failed to create secret: %v, output: %s", err, output)
+	}
+	defer exec.Command(testBinary, "delete", "--secret", secretName, "--force").Run()
+
+	// Importing a login over the secret makes it structured
+	if output, err := exec.Command(testBinary, "import", "--format", "bitwarden", bitwardenFile, "--overwrite").CombinedOutput(); err != nil {
+		t.Fatalf("failed to import login: %v, output: %s", err, output)
+	}
+
+	output, err := exec.Command(testBinary, "view", "--secret", secretName).Output()
+	if err != nil || !strings.Contains(string(output), `"username": "app"`) {
+		t.Errorf("expected structured secret to be indented, got: %s, error: %v", output, err)
+	}
+
+	output, err = exec.Command(testBinary, "view", "--secret", secretName, "--version", "1").Output()
+	if err != nil || string(output) != `{"a":1}` {
+		t.Errorf("expected previous version to be viewed as it was, got: %s, error: %v", output, err)
+	}
+}
+
+// TestViewCommand_Raw tests that raw secrets are viewed byte for byte and can be encoded.
+func TestViewCommand_Raw(t *testing.T) {
+	env.Init()
+
+	secretName := "testviewraw"
+	secretFile := filepath.Join(t.TempDir(), "key.pem")
+	secretContent := "-----BEGIN KEY-----\n\x00\xfe\xff\n-----END KEY-----\n"
+
+	if err := os.WriteFile(secretFile, []byte(secretContent), 0644); err != nil {
+		t.Fatalf("failed to write secret file: %v", err)
+	}
+
+	cmd := exec.Command(testBinary, "create", "--secret", secretName, "--file", secretFile, "--raw")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("failed to create raw secret: %v, output: %s", err, output)
+	}
+	defer exec.Command(testBinary, "delete", "--secret", secretName, "--force").Run()
+
+	output, err := exec.Command(testBinary, "view", "--secret", secretName).Output()
+	if err != nil || string(output) != secretContent {
+		t.Errorf("expected raw secret byte for byte, got: %q, error: %v", output, err)
+	}
+
+	output, err = exec.Command(testBinary, "view", "--secret", secretName, "--encoding", "hex").Output()
+	if err != nil || !strings.HasPrefix(string(output), "2d2d2d2d2d424547494e") || !strings.HasSuffix(string(output), "0a") {
+		t.Errorf("expected hex encoded secret, got: %s, error: %v", output, err)
+	}
+
+	// Updating keeps the secret raw unless told otherwise
+	cmd = exec.Command(testBinary, "update", "--secret", secretName, "--file", secretFile)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("failed to update raw secret: %v, output: %s", err, output)
+	}
+
+	output, _ = exec.Command(testBinary, "view", "--secret", secretName).Output()
+	if string(output) != secretContent {
+		t.Errorf("expected updated secret to stay raw, got: %q", output)
+	}
+
+	cmd = exec.Command(testBinary, "update", "--secret", secretName, "--file", secretFile, "--raw=false")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("failed to update secret: %v, output: %s", err, output)
+	}
+
+	output, _ = exec.Command(testBinary, "view", "--secret", secretName).Output()
+	if string(output) != strings.TrimSpace(secretContent) {
+		t.Errorf("expected secret to be trimmed with --raw=false, got: %q", output)
+	}
+
+	if output, err := exec.Command(testBinary, "view", "--secret", secretName, "--encoding", "rot13").CombinedOutput(); err == nil {
+		t.Errorf("expected error for invalid encoding, got output: %s", output)
+	}
+}
@@ -0,0 +1,217 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/engmtcdrm/mellon/secrets"
+)
+
+// Config holds the user configurable settings of the app.
+type Config struct {
+	HistoryRetention  int    `json:"history_retention"`  // Number of previous versions kept for each secret
+	SnapshotRetention int    `json:"snapshot_retention"` // Number of automatic snapshots kept
+	SnapshotMaxAge    string `json:"snapshot_max_age"`   // How long automatic snapshots are kept, e.g. 30d
+	ShredPasses       int    `json:"shred_passes"`       // Number of times deleted files are overwritten
+}
+
+// setting describes a single configuration setting that can be read and changed by name.
+type setting struct {
+	key         string
+	description string
+	get         func(c *Config) string
+	set         func(c *Config, value string) error
+}
+
+var settings = []setting{
+	{
+		key:         "history.retention",
+		description: "Number of previous versions kept for each secret. 0 disables history",
+		get:         func(c *Config) string { return strconv.Itoa(c.HistoryRetention) },
+		set: func(c *Config, value string) error {
+			n, err := parseNonNegativeInt(value)
+			c.HistoryRetention = n
+			return err
+		},
+	},
+	{
+		key:         "snapshots.retention",
+		description: "Number of automatic snapshots kept. 0 disables snapshots",
+		get:         func(c *Config) string { return strconv.Itoa(c.SnapshotRetention) },
+		set: func(c *Config, value string) error {
+			n, err := parseNonNegativeInt(value)
+			c.SnapshotRetention = n
+			return err
+		},
+	},
+	{
+		key:         "snapshots.max-age",
+		description: "How long automatic snapshots are kept, e.g. 30d. 0 keeps them regardless of age",
+		get:         func(c *Config) string { return c.SnapshotMaxAge },
+		set: func(c *Config, value string) error {
+			if _, err := parseMaxAge(value); err != nil {
+				return err
+			}
+			c.SnapshotMaxAge = value
+			return nil
+		},
+	},
+	{
+		key:         "shred.passes",
+		description: "Number of times files are overwritten with random data before they are deleted. 0 only truncates and renames them",
+		get:         func(c *Config) string { return strconv.Itoa(c.ShredPasses) },
+		set: func(c *Config, value string) error {
+			n, err := parseNonNegativeInt(value)
+			c.ShredPasses = n
+			return err
+		},
+	},
+}
+
+// Default returns the default configuration.
+func Default() Config {
+	return Config{
+		HistoryRetention:  10,
+		SnapshotRetention: 10,
+		SnapshotMaxAge:    "30d",
+		ShredPasses:       3,
+	}
+}
+
+// Load reads the configuration file at path. Settings missing from the file, or a
+// missing file altogether, fall back to the defaults.
+func Load(path string) (Config, error) {
+	c := Default()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return c, nil
+		}
+		return c, fmt.Errorf("could not read configuration file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &c); err != nil {
+		return Default(), fmt.Errorf("could not parse configuration file '%s': %w", path, err)
+	}
+
+	return c, nil
+}
+
+// Save writes the configuration to the file at path.
+func (c Config) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("could not write configuration file: %w", err)
+	}
+
+	return nil
+}
+
+// SnapshotMaxAgeDuration returns how long automatic snapshots are kept, or 0 if they
+// are kept regardless of age. An invalid value falls back to the default.
+func (c Config) SnapshotMaxAgeDuration() time.Duration {
+	d, err := parseMaxAge(c.SnapshotMaxAge)
+	if err != nil {
+		d, _ = parseMaxAge(Default().SnapshotMaxAge)
+	}
+
+	return d
+}
+
+// Keys returns the names of all settings.
+func Keys() []string {
+	keys := make([]string, 0, len(settings))
+	for _, s := range settings {
+		keys = append(keys, s.key)
+	}
+
+	return keys
+}
+
+// Describe returns the description of a setting.
+func Describe(key string) (string, error) {
+	s, err := lookup(key)
+	if err != nil {
+		return "", err
+	}
+
+	return s.description, nil
+}
+
+// Get returns the value of a setting.
+func (c *Config) Get(key string) (string, error) {
+	s, err := lookup(key)
+	if err != nil {
+		return "", err
+	}
+
+	return s.get(c), nil
+}
+
+// Set changes the value of a setting.
+func (c *Config) Set(key string, value string) error {
+	s, err := lookup(key)
+	if err != nil {
+		return err
+	}
+
+	updated := *c
+	if err := s.set(&updated, value); err != nil {
+		return fmt.Errorf("invalid value '%s' for setting '%s': %w", value, key, err)
+	}
+	*c = updated
+
+	return nil
+}
+
+// lookup finds a setting by its key.
+func lookup(key string) (setting, error) {
+	for _, s := range settings {
+		if s.key == key {
+			return s, nil
+		}
+	}
+
+	return setting{}, fmt.Errorf("unknown setting '%s'", key)
+}
+
+// parseNonNegativeInt parses a whole number that is zero or greater.
+func parseNonNegativeInt(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, errors.New("must be a whole number")
+	}
+
+	if n < 0 {
+		return 0, errors.New("must be zero or greater")
+	}
+
+	return n, nil
+}
+
+// parseMaxAge parses a duration such as 30d, where 0 means no limit.
+func parseMaxAge(value string) (time.Duration, error) {
+	if value == "0" {
+		return 0, nil
+	}
+
+	d, err := secrets.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+
+	if d <= 0 {
+		return 0, errors.New("must be greater than zero, or 0 for no limit")
+	}
+
+	return d, nil
+}
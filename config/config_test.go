@@ -0,0 +1,78 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	// A missing file yields the defaults
+	c, err := Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, Default(), c)
+
+	c.HistoryRetention = 3
+	assert.NoError(t, c.Save(path))
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	loaded, err := Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, loaded.HistoryRetention)
+
+	// Settings missing from the file keep their defaults
+	assert.NoError(t, os.WriteFile(path, []byte("{}"), 0600))
+	loaded, err = Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, Default(), loaded)
+
+	assert.NoError(t, os.WriteFile(path, []byte("not json"), 0600))
+	_, err = Load(path)
+	assert.Error(t, err)
+}
+
+func TestGetSet(t *testing.T) {
+	c := Default()
+
+	for _, key := range Keys() {
+		_, err := c.Get(key)
+		assert.NoError(t, err, key)
+
+		desc, err := Describe(key)
+		assert.NoError(t, err, key)
+		assert.NotEmpty(t, desc, key)
+	}
+
+	assert.NoError(t, c.Set("history.retention", "5"))
+	value, err := c.Get("history.retention")
+	assert.NoError(t, err)
+	assert.Equal(t, "5", value)
+
+	// Invalid values leave the setting unchanged
+	assert.Error(t, c.Set("history.retention", "-1"))
+	assert.Error(t, c.Set("history.retention", "many"))
+	assert.Equal(t, 5, c.HistoryRetention)
+
+	assert.NoError(t, c.Set("snapshots.max-age", "2w"))
+	assert.Equal(t, 14*24*time.Hour, c.SnapshotMaxAgeDuration())
+	assert.NoError(t, c.Set("snapshots.max-age", "0"))
+	assert.Equal(t, time.Duration(0), c.SnapshotMaxAgeDuration())
+	assert.Error(t, c.Set("snapshots.max-age", "forever"))
+	assert.Equal(t, "0", c.SnapshotMaxAge)
+
+	assert.NoError(t, c.Set("shred.passes", "1"))
+	assert.Equal(t, 1, c.ShredPasses)
+	assert.Error(t, c.Set("shred.passes", "-1"))
+
+	_, err = c.Get("unknown")
+	assert.Error(t, err)
+	assert.Error(t, c.Set("unknown", "1"))
+}
@@ -18,12 +18,16 @@ var (
 )
 
 type Env struct {
-	home        string // User's home directory.
-	appHomeDir  string // The directory in the user's home directory where the app stores its data.
-	keyPath     string // The path to the encryption key file.
-	secretsPath string // The path to the directory where secrets are stored.
-	secretExt   string // The file extension for secret files.
-	exeCmd      string // The command to run the executable. If the executable is in the PATH environment variable, this will be the executable name.
+	home          string // User's home directory.
+	appHomeDir    string // The directory in the user's home directory where the app stores its data.
+	keyPath       string // The path to the encryption key file.
+	secretsPath   string // The path to the directory where secrets are stored.
+	secretExt     string // The file extension for secret files.
+	exeCmd        string // The command to run the executable. If the executable is in the PATH environment variable, this will be the executable name.
+	configPath    string // The path to the configuration file.
+	historyPath   string // The path to the directory where previous versions of secrets are stored.
+	snapshotsPath string // The path to the directory where automatic snapshots are stored.
+	trashPath     string // The path to the directory where deleted secrets are kept.
 }
 
 // Home returns the home directory of the user.
@@ -56,6 +60,26 @@ func (e *Env) ExeCmd() string {
 	return e.exeCmd
 }
 
+// ConfigPath returns the configuration file path.
+func (e *Env) ConfigPath() string {
+	return e.configPath
+}
+
+// HistoryPath returns the path where previous versions of secrets are stored.
+func (e *Env) HistoryPath() string {
+	return e.historyPath
+}
+
+// SnapshotsPath returns the path where automatic snapshots are stored.
+func (e *Env) SnapshotsPath() string {
+	return e.snapshotsPath
+}
+
+// TrashPath returns the path where deleted secrets are kept.
+func (e *Env) TrashPath() string {
+	return e.trashPath
+}
+
 // Init initializes the environment variables.
 func Init() {
 	once.Do(func() {
@@ -64,25 +88,43 @@ func Init() {
 			panic(err)
 		}
 
-		Instance = &Env{
-			home:       home,
-			appHomeDir: filepath.Join(home, app.DotName),
-			secretExt:  secretExt,
-		}
+		setHome(home)
+	})
+}
 
-		executablePath, err := os.Executable()
-		if err != nil {
-			panic(err)
-		}
+// SetHome initializes the environment variables for the home directory home instead of
+// the home directory of the user, e.g. to keep tests away from the user's secrets.
+func SetHome(home string) {
+	// Keep Init from initializing them again for the user's home directory
+	once.Do(func() {})
 
-		// If executable is in path, use the base name, i.e. the executable name
-		if IsInPath(executablePath) {
-			Instance.exeCmd = filepath.Base(executablePath)
-		} else {
-			Instance.exeCmd = executablePath
-		}
+	setHome(home)
+}
 
-		Instance.keyPath = filepath.Join(Instance.appHomeDir, ".key")
-		Instance.secretsPath = filepath.Join(Instance.appHomeDir, Instance.secretExt)
-	})
+// setHome initializes the environment variables for the home directory home.
+func setHome(home string) {
+	Instance = &Env{
+		home:       home,
+		appHomeDir: filepath.Join(home, app.DotName),
+		secretExt:  secretExt,
+	}
+
+	executablePath, err := os.Executable()
+	if err != nil {
+		panic(err)
+	}
+
+	// If executable is in path, use the base name, i.e. the executable name
+	if IsInPath(executablePath) {
+		Instance.exeCmd = filepath.Base(executablePath)
+	} else {
+		Instance.exeCmd = executablePath
+	}
+
+	Instance.keyPath = filepath.Join(Instance.appHomeDir, ".key")
+	Instance.secretsPath = filepath.Join(Instance.appHomeDir, Instance.secretExt)
+	Instance.configPath = filepath.Join(Instance.appHomeDir, "config.json")
+	Instance.historyPath = filepath.Join(Instance.appHomeDir, ".history")
+	Instance.snapshotsPath = filepath.Join(Instance.appHomeDir, ".snapshots")
+	Instance.trashPath = filepath.Join(Instance.appHomeDir, ".trash")
 }
@@ -70,6 +70,30 @@ func TestEnvFields(t *testing.T) {
 	if Instance.ExeCmd() == "" {
 		t.Errorf("ExeCmd field should not be empty")
 	}
+
+	// Test ConfigPath field
+	expectedConfigPath := filepath.Join(Instance.AppHomeDir(), "config.json")
+	if Instance.ConfigPath() != expectedConfigPath {
+		t.Errorf("ConfigPath should be %s, got: %s", expectedConfigPath, Instance.ConfigPath())
+	}
+
+	// Test HistoryPath field
+	expectedHistoryPath := filepath.Join(Instance.AppHomeDir(), ".history")
+	if Instance.HistoryPath() != expectedHistoryPath {
+		t.Errorf("HistoryPath should be %s, got: %s", expectedHistoryPath, Instance.HistoryPath())
+	}
+
+	// Test SnapshotsPath field
+	expectedSnapshotsPath := filepath.Join(Instance.AppHomeDir(), ".snapshots")
+	if Instance.SnapshotsPath() != expectedSnapshotsPath {
+		t.Errorf("SnapshotsPath should be %s, got: %s", expectedSnapshotsPath, Instance.SnapshotsPath())
+	}
+
+	// Test TrashPath field
+	expectedTrashPath := filepath.Join(Instance.AppHomeDir(), ".trash")
+	if Instance.TrashPath() != expectedTrashPath {
+		t.Errorf("TrashPath should be %s, got: %s", expectedTrashPath, Instance.TrashPath())
+	}
 }
 
 func TestEnvSingleton(t *testing.T) {
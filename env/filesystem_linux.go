@@ -0,0 +1,25 @@
+//go:build linux
+
+package env
+
+import "syscall"
+
+// Magic numbers of the filesystems mellon treats specially, as reported by statfs(2).
+var filesystemNames = map[uint32]string{
+	0x01021994: "tmpfs",
+	0x858458f6: "ramfs",
+	0x9123683e: "btrfs",
+	0x2fc12fc1: "zfs",
+	0xca451a4e: "bcachefs",
+}
+
+// Filesystem returns the name of the filesystem dir is on if it is tmpfs, ramfs or a
+// copy-on-write filesystem, or an empty string otherwise.
+func Filesystem(dir string) string {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return ""
+	}
+
+	return filesystemNames[uint32(st.Type)]
+}
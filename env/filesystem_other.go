@@ -0,0 +1,10 @@
+//go:build !linux
+
+package env
+
+// Filesystem returns the name of the filesystem dir is on if it is tmpfs, ramfs or a
+// copy-on-write filesystem, or an empty string otherwise. The filesystem is only
+// detected on Linux.
+func Filesystem(dir string) string {
+	return ""
+}
@@ -1,6 +1,7 @@
 package env
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
@@ -30,3 +31,30 @@ func ExpandTilde(path string) (string, error) {
 
 	return path, nil
 }
+
+// IsMemoryBacked reports whether dir is on tmpfs or ramfs, which keep files in memory.
+func IsMemoryBacked(dir string) bool {
+	fs := Filesystem(dir)
+	return fs == "tmpfs" || fs == "ramfs"
+}
+
+// ErrNoMemTempDir is returned by MemTempDir when no memory backed directory is available.
+var ErrNoMemTempDir = errors.New("no memory backed directory found, set XDG_RUNTIME_DIR to a directory on tmpfs")
+
+// MemTempDir returns a directory for short-lived private files that is backed by
+// memory, so their contents never reach a physical disk. It prefers $XDG_RUNTIME_DIR,
+// then /dev/shm, accepting either only if it is on tmpfs or ramfs, and returns
+// ErrNoMemTempDir rather than falling back to a directory on disk.
+func MemTempDir() (string, error) {
+	for _, dir := range []string{os.Getenv("XDG_RUNTIME_DIR"), "/dev/shm"} {
+		if dir == "" {
+			continue
+		}
+
+		if info, err := os.Stat(dir); err == nil && info.IsDir() && IsMemoryBacked(dir) {
+			return dir, nil
+		}
+	}
+
+	return "", ErrNoMemTempDir
+}
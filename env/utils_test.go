@@ -1,6 +1,7 @@
 package env
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
@@ -148,3 +149,40 @@ func TestExpandTildePathSeparators(t *testing.T) {
 		})
 	}
 }
+
+func TestMemTempDir(t *testing.T) {
+	runtimeDir, err := os.MkdirTemp("/dev/shm", "mellon-test-")
+	if err != nil || !IsMemoryBacked(runtimeDir) {
+		t.Skip("/dev/shm is not available on tmpfs")
+	}
+	defer os.RemoveAll(runtimeDir)
+
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+
+	if dir, err := MemTempDir(); err != nil || dir != runtimeDir {
+		t.Errorf("MemTempDir() = %q, %v, expected %q", dir, err, runtimeDir)
+	}
+
+	// Directories on disk are never used, even when set explicitly
+	for _, runtimeDir := range []string{filepath.Join(runtimeDir, "missing"), t.TempDir()} {
+		if IsMemoryBacked(runtimeDir) {
+			continue
+		}
+
+		t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+
+		if dir, err := MemTempDir(); err != nil || dir != "/dev/shm" {
+			t.Errorf("MemTempDir() = %q, %v, expected /dev/shm", dir, err)
+		}
+	}
+
+	// Only /dev/shm is left, the system temp directory may be on disk
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	dir, err := MemTempDir()
+	if err == nil && dir != "/dev/shm" {
+		t.Errorf("MemTempDir() should return /dev/shm, got: %q", dir)
+	}
+	if err != nil && !errors.Is(err, ErrNoMemTempDir) {
+		t.Errorf("MemTempDir() should return ErrNoMemTempDir, got: %v", err)
+	}
+}
@@ -0,0 +1,163 @@
+package exporter
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Formats are the formats secrets can be exported to.
+var Formats = []string{"dotenv", "json", "shell", "k8s-secret"}
+
+// KeyCases are the ways names of secrets can be turned into keys.
+var KeyCases = []string{"upper", "lower", "preserve"}
+
+var (
+	reEnvKey       = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)         // Keys usable as environment variables
+	reK8sKey       = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)                // Keys allowed in the data of Kubernetes Secrets
+	reInvalidChars = regexp.MustCompile(`[^A-Za-z0-9_]+`)                   // Characters replaced when turning names into keys
+	reK8sName      = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`) // Names of Kubernetes objects
+)
+
+// Entry is a value to export under a key.
+type Entry struct {
+	Key   string // Key of the value, e.g. DB_PASSWORD
+	Value []byte // Value to export
+}
+
+// Options configures the exported document.
+type Options struct {
+	Name      string // Name of the Kubernetes Secret
+	Namespace string // Kubernetes namespace of the Secret, left out if empty
+}
+
+// Key turns the name of a secret, relative to the exported namespace, into a key. The
+// parts of the name are joined with underscores, e.g. db/password becomes DB_PASSWORD
+// in upper case.
+func Key(name string, keyCase string) string {
+	key := strings.Trim(reInvalidChars.ReplaceAllString(name, "_"), "_")
+
+	switch keyCase {
+	case "upper":
+		return strings.ToUpper(key)
+	case "lower":
+		return strings.ToLower(key)
+	}
+
+	return key
+}
+
+// ValidateKey checks that a key can be used in the given format.
+func ValidateKey(format string, key string) error {
+	switch format {
+	case "json":
+		if key == "" {
+			return errors.New("invalid key: keys cannot be empty")
+		}
+	case "k8s-secret":
+		if !reK8sKey.MatchString(key) {
+			return fmt.Errorf("invalid key '%s': Kubernetes Secret keys can only contain alphanumeric, hyphens, underscores and dots", key)
+		}
+	default:
+		if !reEnvKey.MatchString(key) {
+			return fmt.Errorf("invalid key '%s': keys can only contain alphanumeric and underscores, and cannot start with a digit", key)
+		}
+	}
+
+	return nil
+}
+
+// Format renders the entries in the given format, sorted by key.
+func Format(format string, entries []Entry, opts Options) ([]byte, error) {
+	entries = slices.Clone(entries)
+	slices.SortFunc(entries, func(a, b Entry) int { return strings.Compare(a.Key, b.Key) })
+
+	switch format {
+	case "dotenv":
+		return formatLines(entries, func(e Entry) string { return e.Key + "=" + dotenvQuote(e.Value) }), nil
+	case "shell":
+		return formatLines(entries, func(e Entry) string { return "export " + e.Key + "=" + shellQuote(e.Value) }), nil
+	case "json":
+		return formatJSON(entries)
+	case "k8s-secret":
+		return formatK8sSecret(entries, opts)
+	}
+
+	return nil, fmt.Errorf("invalid format '%s'. Must be one of: %s", format, strings.Join(Formats, ", "))
+}
+
+// formatLines renders every entry on its own line.
+func formatLines(entries []Entry, line func(Entry) string) []byte {
+	var sb strings.Builder
+	for _, e := range entries {
+		sb.WriteString(line(e))
+		sb.WriteByte('\n')
+	}
+
+	return []byte(sb.String())
+}
+
+// dotenvQuote double quotes a value for a dotenv file, escaping backslashes, quotes,
+// line breaks and dollar signs, as loaders such as docker compose and godotenv expand
+// $VAR and ${VAR} within double quotes.
+func dotenvQuote(value []byte) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + r.Replace(string(value)) + `"`
+}
+
+// shellQuote single quotes a value for POSIX shells.
+func shellQuote(value []byte) string {
+	return "'" + strings.ReplaceAll(string(value), "'", `'\''`) + "'"
+}
+
+// formatJSON renders the entries as a JSON object.
+func formatJSON(entries []Entry) ([]byte, error) {
+	obj := make(map[string]string, len(entries))
+	for _, e := range entries {
+		obj[e.Key] = string(e.Value)
+	}
+
+	data, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(data, '\n'), nil
+}
+
+// formatK8sSecret renders the entries as a v1 Secret manifest with base64 encoded data.
+func formatK8sSecret(entries []Entry, opts Options) ([]byte, error) {
+	if !reK8sName.MatchString(opts.Name) {
+		return nil, fmt.Errorf("invalid Kubernetes Secret name '%s': names can only contain lowercase alphanumeric, hyphens and dots, and must start and end with an alphanumeric", opts.Name)
+	}
+
+	if opts.Namespace != "" && !reK8sName.MatchString(opts.Namespace) {
+		return nil, fmt.Errorf("invalid Kubernetes namespace '%s'", opts.Namespace)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("apiVersion: v1\n")
+	sb.WriteString("kind: Secret\n")
+	sb.WriteString("metadata:\n")
+	fmt.Fprintf(&sb, "  name: %s\n", opts.Name)
+	if opts.Namespace != "" {
+		fmt.Fprintf(&sb, "  namespace: %s\n", opts.Namespace)
+	}
+	sb.WriteString("type: Opaque\n")
+
+	if len(entries) == 0 {
+		sb.WriteString("data: {}\n")
+		return []byte(sb.String()), nil
+	}
+
+	sb.WriteString("data:\n")
+	for _, e := range entries {
+		fmt.Fprintf(&sb, "  %s: %s\n", e.Key, base64.StdEncoding.EncodeToString(e.Value))
+	}
+
+	return []byte(sb.String()), nil
+}
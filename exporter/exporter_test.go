@@ -0,0 +1,70 @@
+package exporter
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testEntries = []Entry{
+	{Key: "DB_PASSWORD", Value: []byte("p@ss \"it's\" $HOME\nline2")},
+	{Key: "API_KEY", Value: []byte("abc")},
+}
+
+func TestKey(t *testing.T) {
+	assert.Equal(t, "DB_PASSWORD", Key("db/password", "upper"))
+	assert.Equal(t, "db_password", Key("DB/Password", "lower"))
+	assert.Equal(t, "Db_api_key", Key("Db/api-key", "preserve"))
+	assert.Equal(t, "PASSWORD", Key("/password", "upper"))
+}
+
+func TestValidateKey(t *testing.T) {
+	assert.NoError(t, ValidateKey("dotenv", "DB_PASSWORD"))
+	assert.Error(t, ValidateKey("dotenv", "1PASSWORD"))
+	assert.Error(t, ValidateKey("shell", "db-password"))
+	assert.NoError(t, ValidateKey("k8s-secret", "db-password.txt"))
+	assert.Error(t, ValidateKey("k8s-secret", "db/password"))
+	assert.NoError(t, ValidateKey("json", "db/password"))
+	assert.Error(t, ValidateKey("json", ""))
+}
+
+func TestFormat(t *testing.T) {
+	data, err := Format("dotenv", testEntries, Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, "API_KEY=\"abc\"\nDB_PASSWORD=\"p@ss \\\"it's\\\" \\$HOME\\nline2\"\n", string(data))
+
+	// Dollar signs are escaped so loaders do not expand them
+	data, err = Format("dotenv", []Entry{{Key: "TOKEN", Value: []byte("a$b${C}$$")}}, Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, "TOKEN=\"a\\$b\\${C}\\$\\$\"\n", string(data))
+
+	data, err = Format("shell", testEntries, Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, "export API_KEY='abc'\nexport DB_PASSWORD='p@ss \"it'\\''s\" $HOME\nline2'\n", string(data))
+
+	data, err = Format("json", testEntries, Options{})
+	assert.NoError(t, err)
+	var obj map[string]string
+	assert.NoError(t, json.Unmarshal(data, &obj))
+	assert.Equal(t, map[string]string{"API_KEY": "abc", "DB_PASSWORD": "p@ss \"it's\" $HOME\nline2"}, obj)
+
+	data, err = Format("k8s-secret", testEntries, Options{Name: "prod-app", Namespace: "apps"})
+	assert.NoError(t, err)
+	assert.Equal(t, `apiVersion: v1
+kind: Secret
+metadata:
+  name: prod-app
+  namespace: apps
+type: Opaque
+data:
+  API_KEY: YWJj
+  DB_PASSWORD: cEBzcyAiaXQncyIgJEhPTUUKbGluZTI=
+`, string(data))
+
+	_, err = Format("k8s-secret", testEntries, Options{Name: "Prod_App"})
+	assert.ErrorContains(t, err, "invalid Kubernetes Secret name")
+
+	_, err = Format("xml", testEntries, Options{})
+	assert.ErrorContains(t, err, "invalid format 'xml'")
+}
@@ -0,0 +1,351 @@
+package generator
+
+import (
+	"crypto/rand"
+	_ "embed"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Types are the kinds of values that can be generated.
+var Types = []string{"password", "pronounceable", "passphrase", "hex", "base64", "urlsafe"}
+
+// Classes are the character classes passwords can be made of.
+var Classes = []string{"lower", "upper", "digits", "symbols"}
+
+const (
+	DefaultLength      = 24 // Characters of generated passwords
+	DefaultTokenLength = 32 // Random bytes of generated tokens
+	DefaultWords       = 6  // Words of generated passphrases
+)
+
+const (
+	lowerChars     = "abcdefghijklmnopqrstuvwxyz"
+	upperChars     = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	digitChars     = "0123456789"
+	symbolChars    = "!#$%&()*+,-./:;<=>?@[]^_{|}~"
+	ambiguousChars = "0Oo1Il|"
+	vowelChars     = "aeiou"
+)
+
+// wordlistData is the EFF large wordlist for diceware passphrases, one word per line
+// after its dice roll. https://www.eff.org/dice, licensed under CC BY 3.0.
+//
+//go:embed eff_large_wordlist.txt
+var wordlistData string
+
+// wordlist returns the words of the embedded wordlist.
+var wordlist = sync.OnceValue(func() []string {
+	lines := strings.Split(strings.TrimSpace(wordlistData), "\n")
+
+	words := make([]string, 0, len(lines))
+	for _, line := range lines {
+		_, word, _ := strings.Cut(strings.TrimSpace(line), "\t")
+		words = append(words, word)
+	}
+
+	return words
+})
+
+// Options configures the generated value.
+type Options struct {
+	Type        string   // Kind of value to generate, one of Types
+	Length      int      // Characters of passwords, or random bytes of tokens. 0 uses the default
+	Words       int      // Words of passphrases. 0 uses the default
+	Separator   string   // Separator between the words of passphrases
+	Classes     []string // Character classes of passwords, each used at least once. Empty uses them all
+	Exclude     string   // Characters never used in passwords
+	NoAmbiguous bool     // Whether to leave characters that are easily confused, e.g. 0 and O, out of passwords
+}
+
+// Generate generates a random value as configured by opts. All randomness comes from
+// crypto/rand.
+func Generate(opts Options) ([]byte, error) {
+	if opts.Length < 0 {
+		return nil, errors.New("length cannot be negative")
+	}
+
+	classes := opts.Classes
+	if len(classes) == 0 {
+		classes = Classes
+	}
+
+	for _, c := range classes {
+		if !slices.Contains(Classes, c) {
+			return nil, fmt.Errorf("invalid character class '%s'. Must be one of: %s", c, strings.Join(Classes, ", "))
+		}
+	}
+
+	exclude := opts.Exclude
+	if opts.NoAmbiguous {
+		exclude += ambiguousChars
+	}
+
+	switch opts.Type {
+	case "", "password":
+		return password(lengthOr(opts.Length, DefaultLength), classes, exclude)
+	case "pronounceable":
+		return pronounceable(lengthOr(opts.Length, DefaultLength), classes, exclude)
+	case "passphrase":
+		return passphrase(lengthOr(opts.Words, DefaultWords), opts.Separator)
+	case "hex", "base64", "urlsafe":
+		return token(opts.Type, lengthOr(opts.Length, DefaultTokenLength))
+	}
+
+	return nil, fmt.Errorf("invalid type '%s'. Must be one of: %s", opts.Type, strings.Join(Types, ", "))
+}
+
+// lengthOr returns n, or def if n is 0.
+func lengthOr(n int, def int) int {
+	if n == 0 {
+		return def
+	}
+	return n
+}
+
+// password generates a password of the given length holding at least one character of
+// each class.
+func password(length int, classes []string, exclude string) ([]byte, error) {
+	if length < len(classes) {
+		return nil, fmt.Errorf("length must be at least %d to use every character class", len(classes))
+	}
+
+	var sets []string
+	for _, c := range classes {
+		set, err := classChars(c, exclude)
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, set)
+	}
+
+	all := strings.Join(sets, "")
+	value := make([]byte, 0, length)
+
+	for i := 0; i < length; i++ {
+		set := all
+		if i < len(sets) {
+			set = sets[i]
+		}
+
+		c, err := pick(set)
+		if err != nil {
+			return nil, err
+		}
+		value = append(value, c)
+	}
+
+	// The characters guaranteed for each class would otherwise always come first
+	if err := shuffle(value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// pronounceable generates a password of alternating consonants and vowels. With both
+// the lower and upper classes one letter is capitalised, and a digit and a symbol are
+// appended for the digits and symbols classes.
+func pronounceable(length int, classes []string, exclude string) ([]byte, error) {
+	lower := slices.Contains(classes, "lower")
+	upper := slices.Contains(classes, "upper")
+	if !lower && !upper {
+		return nil, errors.New("pronounceable passwords need the lower or upper character class")
+	}
+
+	var extras []string
+	for _, c := range []string{"digits", "symbols"} {
+		if slices.Contains(classes, c) {
+			set, err := classChars(c, exclude)
+			if err != nil {
+				return nil, err
+			}
+			extras = append(extras, set)
+		}
+	}
+
+	letters := length - len(extras)
+	if letters < 2 {
+		return nil, fmt.Errorf("length must be at least %d to use every character class", len(extras)+2)
+	}
+
+	toCase := strings.ToLower
+	if !lower {
+		toCase = strings.ToUpper
+	}
+
+	vowels := removeChars(toCase(vowelChars), exclude)
+	consonants := removeChars(removeChars(toCase(lowerChars), toCase(vowelChars)), exclude)
+	if vowels == "" || consonants == "" {
+		return nil, errors.New("no consonants or vowels are left after exclusions")
+	}
+
+	value := make([]byte, 0, length)
+	for i := 0; i < letters; i++ {
+		set := consonants
+		if i%2 == 1 {
+			set = vowels
+		}
+
+		c, err := pick(set)
+		if err != nil {
+			return nil, err
+		}
+		value = append(value, c)
+	}
+
+	if lower && upper {
+		if err := capitalise(value, exclude); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, set := range extras {
+		c, err := pick(set)
+		if err != nil {
+			return nil, err
+		}
+		value = append(value, c)
+	}
+
+	return value, nil
+}
+
+// capitalise capitalises a random letter of value whose capital is not excluded.
+func capitalise(value []byte, exclude string) error {
+	var candidates []int
+	for i, c := range value {
+		if !strings.ContainsRune(exclude, rune(c-'a'+'A')) {
+			candidates = append(candidates, i)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return errors.New("no capital letters are left after exclusions")
+	}
+
+	i, err := randomInt(len(candidates))
+	if err != nil {
+		return err
+	}
+
+	value[candidates[i]] -= 'a' - 'A'
+
+	return nil
+}
+
+// passphrase generates a diceware passphrase of words from the embedded wordlist.
+func passphrase(words int, separator string) ([]byte, error) {
+	if words < 1 {
+		return nil, errors.New("passphrases need at least 1 word")
+	}
+
+	list := wordlist()
+	value := make([]byte, 0, words*8)
+
+	for i := 0; i < words; i++ {
+		n, err := randomInt(len(list))
+		if err != nil {
+			return nil, err
+		}
+
+		if i > 0 {
+			value = append(value, separator...)
+		}
+		value = append(value, list[n]...)
+	}
+
+	return value, nil
+}
+
+// token generates length random bytes in the given encoding.
+func token(encoding string, length int) ([]byte, error) {
+	raw := make([]byte, length)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("could not generate random bytes: %w", err)
+	}
+	defer clear(raw)
+
+	var value []byte
+	switch encoding {
+	case "hex":
+		value = make([]byte, hex.EncodedLen(length))
+		hex.Encode(value, raw)
+	case "base64":
+		value = make([]byte, base64.StdEncoding.EncodedLen(length))
+		base64.StdEncoding.Encode(value, raw)
+	case "urlsafe":
+		value = make([]byte, base64.RawURLEncoding.EncodedLen(length))
+		base64.RawURLEncoding.Encode(value, raw)
+	}
+
+	return value, nil
+}
+
+// classChars returns the characters of a class, without the excluded ones.
+func classChars(class string, exclude string) (string, error) {
+	var set string
+	switch class {
+	case "lower":
+		set = lowerChars
+	case "upper":
+		set = upperChars
+	case "digits":
+		set = digitChars
+	case "symbols":
+		set = symbolChars
+	}
+
+	set = removeChars(set, exclude)
+	if set == "" {
+		return "", fmt.Errorf("no characters of class '%s' are left after exclusions", class)
+	}
+
+	return set, nil
+}
+
+// removeChars returns s without the characters in chars.
+func removeChars(s string, chars string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(chars, r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// pick returns a random character of set.
+func pick(set string) (byte, error) {
+	i, err := randomInt(len(set))
+	if err != nil {
+		return 0, err
+	}
+	return set[i], nil
+}
+
+// shuffle shuffles b in place.
+func shuffle(b []byte) error {
+	for i := len(b) - 1; i > 0; i-- {
+		j, err := randomInt(i + 1)
+		if err != nil {
+			return err
+		}
+		b[i], b[j] = b[j], b[i]
+	}
+	return nil
+}
+
+// randomInt returns a uniformly random integer in [0, n).
+func randomInt(n int) (int, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, fmt.Errorf("could not generate random number: %w", err)
+	}
+	return int(i.Int64()), nil
+}
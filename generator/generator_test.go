@@ -0,0 +1,112 @@
+package generator
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPassword(t *testing.T) {
+	value, err := Generate(Options{})
+	assert.NoError(t, err)
+	assert.Len(t, value, DefaultLength)
+
+	// Every class is used at least once, even in the shortest password
+	for range 20 {
+		value, err = Generate(Options{Length: 4})
+		assert.NoError(t, err)
+		assert.True(t, strings.ContainsAny(string(value), lowerChars))
+		assert.True(t, strings.ContainsAny(string(value), upperChars))
+		assert.True(t, strings.ContainsAny(string(value), digitChars))
+		assert.True(t, strings.ContainsAny(string(value), symbolChars))
+	}
+
+	value, err = Generate(Options{Length: 64, Classes: []string{"digits"}, Exclude: "0123"})
+	assert.NoError(t, err)
+	assert.Len(t, value, 64)
+	assert.Empty(t, strings.Trim(string(value), "456789"))
+
+	value, err = Generate(Options{Length: 200, NoAmbiguous: true})
+	assert.NoError(t, err)
+	assert.False(t, strings.ContainsAny(string(value), ambiguousChars))
+
+	_, err = Generate(Options{Length: 3})
+	assert.ErrorContains(t, err, "at least 4")
+
+	_, err = Generate(Options{Classes: []string{"digits"}, Exclude: digitChars})
+	assert.ErrorContains(t, err, "no characters of class 'digits'")
+
+	_, err = Generate(Options{Classes: []string{"emoji"}})
+	assert.ErrorContains(t, err, "invalid character class")
+
+	_, err = Generate(Options{Length: -1})
+	assert.Error(t, err)
+
+	_, err = Generate(Options{Type: "pin"})
+	assert.ErrorContains(t, err, "invalid type")
+}
+
+func TestPronounceable(t *testing.T) {
+	value, err := Generate(Options{Type: "pronounceable", Length: 12, Classes: []string{"lower"}})
+	assert.NoError(t, err)
+	assert.Len(t, value, 12)
+
+	for i, c := range string(value) {
+		assert.Equal(t, i%2 == 1, strings.ContainsRune(vowelChars, c), "consonants and vowels should alternate in %s", value)
+	}
+
+	value, err = Generate(Options{Type: "pronounceable", Length: 12})
+	assert.NoError(t, err)
+	assert.Len(t, value, 12)
+	assert.True(t, strings.ContainsAny(string(value[:10]), upperChars))
+	assert.True(t, strings.ContainsAny(string(value[:10]), lowerChars))
+	assert.True(t, strings.ContainsAny(string(value[10:]), digitChars))
+	assert.True(t, strings.ContainsAny(string(value[10:]), symbolChars))
+
+	_, err = Generate(Options{Type: "pronounceable", Classes: []string{"digits"}})
+	assert.ErrorContains(t, err, "lower or upper")
+}
+
+func TestPassphrase(t *testing.T) {
+	assert.Len(t, wordlist(), 7776)
+
+	value, err := Generate(Options{Type: "passphrase", Separator: " "})
+	assert.NoError(t, err)
+	assert.Len(t, strings.Split(string(value), " "), DefaultWords)
+
+	value, err = Generate(Options{Type: "passphrase", Words: 4, Separator: "."})
+	assert.NoError(t, err)
+
+	words := strings.Split(string(value), ".")
+	assert.Len(t, words, 4)
+	for _, w := range words {
+		assert.Contains(t, wordlist(), w)
+	}
+
+	_, err = Generate(Options{Type: "passphrase", Words: -1})
+	assert.Error(t, err)
+}
+
+func TestToken(t *testing.T) {
+	value, err := Generate(Options{Type: "hex"})
+	assert.NoError(t, err)
+	raw, err := hex.DecodeString(string(value))
+	assert.NoError(t, err)
+	assert.Len(t, raw, DefaultTokenLength)
+
+	value, err = Generate(Options{Type: "base64", Length: 16})
+	assert.NoError(t, err)
+	raw, err = base64.StdEncoding.DecodeString(string(value))
+	assert.NoError(t, err)
+	assert.Len(t, raw, 16)
+
+	value, err = Generate(Options{Type: "urlsafe", Length: 16})
+	assert.NoError(t, err)
+	assert.False(t, strings.ContainsAny(string(value), "+/="))
+	raw, err = base64.RawURLEncoding.DecodeString(string(value))
+	assert.NoError(t, err)
+	assert.Len(t, raw, 16)
+}
@@ -0,0 +1,111 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+)
+
+// bitwardenExport is an unencrypted Bitwarden JSON export.
+type bitwardenExport struct {
+	Encrypted bool `json:"encrypted"`
+	Folders   []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"folders"`
+	Collections []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"collections"`
+	Items []bitwardenItem `json:"items"`
+}
+
+// bitwardenItem is a login, secure note, card or identity.
+type bitwardenItem struct {
+	FolderID      string   `json:"folderId"`
+	CollectionIDs []string `json:"collectionIds"`
+	Name          string   `json:"name"`
+	Notes         string   `json:"notes"`
+	Login         *struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		TOTP     string `json:"totp"`
+		URIs     []struct {
+			URI string `json:"uri"`
+		} `json:"uris"`
+	} `json:"login"`
+	Card     map[string]any `json:"card"`
+	Identity map[string]any `json:"identity"`
+	Fields   []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"fields"`
+}
+
+// parseBitwarden reads an unencrypted Bitwarden JSON export. Folders, or collections
+// of organization exports, become namespaces.
+func parseBitwarden(data []byte) ([]Entry, error) {
+	var export bitwardenExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("could not parse Bitwarden JSON: %w", err)
+	}
+
+	if export.Encrypted {
+		return nil, errors.New("encrypted Bitwarden exports are not supported. Export the vault as unencrypted JSON")
+	}
+
+	folders := map[string]string{}
+	for _, f := range export.Folders {
+		folders[f.ID] = f.Name
+	}
+	for _, c := range export.Collections {
+		folders[c.ID] = c.Name
+	}
+
+	var entries []Entry
+
+	for _, item := range export.Items {
+		folder := folders[item.FolderID]
+		if folder == "" && len(item.CollectionIDs) > 0 {
+			folder = folders[item.CollectionIDs[0]]
+		}
+
+		fields := map[string]string{FieldNotes: item.Notes}
+
+		if item.Login != nil {
+			fields[FieldUsername] = item.Login.Username
+			fields[FieldPassword] = item.Login.Password
+			fields[FieldTOTP] = item.Login.TOTP
+
+			for i, uri := range item.Login.URIs {
+				if i == 0 {
+					fields[FieldURL] = uri.URI
+				} else {
+					addField(fields, FieldURL+"-"+strconv.Itoa(i+1), uri.URI)
+				}
+			}
+		}
+
+		// Cards and identities are kept with the names Bitwarden gives their values
+		for _, details := range []map[string]any{item.Card, item.Identity} {
+			for _, key := range slices.Sorted(maps.Keys(details)) {
+				if value, ok := details[key].(string); ok {
+					addField(fields, key, value)
+				}
+			}
+		}
+
+		for _, f := range item.Fields {
+			addField(fields, f.Name, f.Value)
+		}
+
+		if entry, ok := login(folder, item.Name, fields); ok {
+			entries = append(entries, entry)
+		}
+	}
+
+	return uniqueNames(entries), nil
+}
@@ -0,0 +1,51 @@
+package importer
+
+import (
+	"errors"
+	"net/url"
+	"slices"
+)
+
+// parseBrowser reads a password CSV export of Chrome, Firefox or other browsers
+// derived from them. Logins are named after their site, since browsers have no folders.
+func parseBrowser(data []byte) ([]Entry, error) {
+	header, records, err := csvRecords(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(header) > 0 && (!slices.Contains(header, "url") || !slices.Contains(header, "password")) {
+		return nil, errors.New("could not parse browser CSV: expected url and password columns")
+	}
+
+	var entries []Entry
+
+	for _, record := range records {
+		title := record["name"]
+		if title == "" {
+			title = siteName(record["url"])
+		}
+
+		fields := map[string]string{
+			FieldURL:      record["url"],
+			FieldUsername: record["username"],
+			FieldPassword: record["password"],
+			FieldNotes:    firstOf(record, "note", "notes"),
+		}
+
+		if entry, ok := login("", title, fields); ok {
+			entries = append(entries, entry)
+		}
+	}
+
+	return uniqueNames(entries), nil
+}
+
+// siteName returns the host of a URL, or the URL itself if it has no host.
+func siteName(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil && u.Hostname() != "" {
+		return u.Hostname()
+	}
+
+	return rawURL
+}
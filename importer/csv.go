@@ -0,0 +1,31 @@
+package importer
+
+import (
+	"errors"
+	"slices"
+	"strings"
+)
+
+// parseCSV reads rows with a header naming a name and a value column. Other columns
+// are ignored.
+func parseCSV(data []byte) ([]Entry, error) {
+	header, records, err := csvRecords(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(header) == 0 {
+		return nil, nil
+	}
+
+	if !slices.Contains(header, "name") || !slices.Contains(header, "value") {
+		return nil, errors.New("could not parse CSV: the first row must be a header with name and value columns")
+	}
+
+	entries := make([]Entry, 0, len(records))
+	for _, record := range records {
+		entries = append(entries, Entry{Name: strings.TrimSpace(record["name"]), Value: []byte(record["value"])})
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,98 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// parseDotenv reads KEY=value lines. Lines may start with export, values may be
+// quoted, and double quoted values may span several lines and use \n, \r, \t, \", \$
+// and \\ escapes. Blank lines and comments starting with # are ignored.
+func parseDotenv(data []byte) ([]Entry, error) {
+	var entries []Entry
+	var errs []error
+
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+
+	for i := 0; i < len(lines); i++ {
+		lineNum := i + 1
+
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			errs = append(errs, fmt.Errorf("line %d: expected KEY=value", lineNum))
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+
+		if value != "" && (value[0] == '"' || value[0] == '\'') {
+			quoted, consumed, err := readQuoted(value, lines[i+1:])
+			if err != nil {
+				errs = append(errs, fmt.Errorf("line %d: %w", lineNum, err))
+				break
+			}
+			i += consumed
+			value = quoted
+		} else if idx := strings.Index(value, " #"); idx >= 0 {
+			value = strings.TrimSpace(value[:idx])
+		}
+
+		entries = append(entries, Entry{Name: key, Value: []byte(value)})
+	}
+
+	return entries, errors.Join(errs...)
+}
+
+// readQuoted reads a quoted value starting at value, continuing onto the following
+// lines until the closing quote. It returns the value and the number of following
+// lines consumed.
+func readQuoted(value string, following []string) (string, int, error) {
+	quote := value[0]
+	text := value[1:]
+
+	var sb strings.Builder
+
+	for consumed := 0; ; consumed++ {
+		for j := 0; j < len(text); j++ {
+			c := text[j]
+
+			switch {
+			case c == quote:
+				return sb.String(), consumed, nil
+			case c == '\\' && quote == '"' && j+1 < len(text):
+				j++
+				switch text[j] {
+				case 'n':
+					sb.WriteByte('\n')
+				case 'r':
+					sb.WriteByte('\r')
+				case 't':
+					sb.WriteByte('\t')
+				case '"', '$', '\\':
+					sb.WriteByte(text[j])
+				default:
+					sb.WriteByte('\\')
+					sb.WriteByte(text[j])
+				}
+			default:
+				sb.WriteByte(c)
+			}
+		}
+
+		if consumed == len(following) {
+			return "", 0, errors.New("unterminated quoted value")
+		}
+
+		sb.WriteByte('\n')
+		text = following[consumed]
+	}
+}
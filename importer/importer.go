@@ -0,0 +1,66 @@
+package importer
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Formats are the formats secrets can be imported from. The pass format reads a
+// pass(1) password store directory instead of a file.
+var Formats = []string{"dotenv", "json", "yaml", "csv", "keepass", "bitwarden", "1password", "browser", "pass"}
+
+// Entry is a secret read from an import file.
+type Entry struct {
+	Name        string            // Name of the secret
+	Value       []byte            // Value of a single value secret
+	Fields      map[string]string // Fields of a structured secret, nil for a single value secret
+	Description string            // Description of the secret, e.g. the title of a login in a password manager
+}
+
+// Parse reads the entries of an import file in the given format.
+func Parse(format string, data []byte) ([]Entry, error) {
+	switch format {
+	case "dotenv":
+		return parseDotenv(data)
+	case "json":
+		return parseJSON(data)
+	case "yaml":
+		return parseYAML(data)
+	case "csv":
+		return parseCSV(data)
+	case "keepass":
+		return parseKeePass(data)
+	case "bitwarden":
+		return parseBitwarden(data)
+	case "1password":
+		return parseOnePassword(data)
+	case "browser":
+		return parseBrowser(data)
+	case "pass":
+		return nil, fmt.Errorf("format '%s' reads a password store directory, use ParsePassStore", format)
+	}
+
+	return nil, fmt.Errorf("invalid format '%s'. Must be one of: %s", format, strings.Join(Formats, ", "))
+}
+
+// DetectFormat returns the format of an import file from its extension. Exports of
+// password managers other than KeePass cannot be told apart from plain JSON and CSV
+// files, so their format must be given explicitly.
+func DetectFormat(path string) (string, error) {
+	base := strings.ToLower(filepath.Base(path))
+
+	switch ext := filepath.Ext(base); {
+	case base == ".env" || strings.HasPrefix(base, ".env.") || ext == ".env":
+		return "dotenv", nil
+	case ext == ".yml":
+		return "yaml", nil
+	case ext == ".xml":
+		return "keepass", nil
+	case slices.Contains(Formats, strings.TrimPrefix(ext, ".")):
+		return strings.TrimPrefix(ext, "."), nil
+	}
+
+	return "", fmt.Errorf("could not detect the format of '%s'. Use --format with one of: %s", path, strings.Join(Formats, ", "))
+}
@@ -0,0 +1,300 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// values returns the entries as a map of names to values.
+func values(entries []Entry) map[string]string {
+	m := map[string]string{}
+	for _, e := range entries {
+		m[e.Name] = string(e.Value)
+	}
+	return m
+}
+
+func TestParseDotenv(t *testing.T) {
+	data := []byte(`# Database
+export DB_HOST=db.example.com
+DB_PORT = 5432 # inline comment
+DB_PASS="p@ss \"word\"\n"
+DOLLAR="a\$b\${C}"
+SINGLE='raw \n # kept'
+EMPTY=
+KEY="-----BEGIN KEY-----
+abc
+-----END KEY-----"
+`)
+
+	entries, err := Parse("dotenv", data)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"DB_HOST": "db.example.com",
+		"DB_PORT": "5432",
+		"DB_PASS": "p@ss \"word\"\n",
+		"DOLLAR":  "a$b${C}",
+		"SINGLE":  `raw \n # kept`,
+		"EMPTY":   "",
+		"KEY":     "-----BEGIN KEY-----\nabc\n-----END KEY-----",
+	}, values(entries))
+	assert.Equal(t, "DB_HOST", entries[0].Name)
+
+	_, err = Parse("dotenv", []byte("VALID=1\nnot a pair\n=value\n"))
+	assert.ErrorContains(t, err, "line 2")
+	assert.ErrorContains(t, err, "line 3")
+
+	_, err = Parse("dotenv", []byte("KEY=\"unterminated\nvalue\n"))
+	assert.ErrorContains(t, err, "unterminated")
+}
+
+func TestParseJSON(t *testing.T) {
+	entries, err := Parse("json", []byte(`{"api_key": "abc", "prod": {"db": {"password": "p@ss", "port": 5432}}, "debug": true}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []Entry{
+		{Name: "api_key", Value: []byte("abc")},
+		{Name: "debug", Value: []byte("true")},
+		{Name: "prod/db/password", Value: []byte("p@ss")},
+		{Name: "prod/db/port", Value: []byte("5432")},
+	}, entries)
+
+	_, err = Parse("json", []byte(`{"list": [1, 2], "empty": null}`))
+	assert.ErrorContains(t, err, "list: value must be")
+	assert.ErrorContains(t, err, "empty: value cannot be empty")
+
+	_, err = Parse("json", []byte(`["a"]`))
+	assert.ErrorContains(t, err, "could not parse JSON")
+}
+
+func TestParseYAML(t *testing.T) {
+	entries, err := Parse("yaml", []byte("api_key: abc\nprod:\n  db:\n    password: \"p@ss\"\n    port: 5432\n  cert: |\n    line1\n    line2\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"api_key":          "abc",
+		"prod/cert":        "line1\nline2\n",
+		"prod/db/password": "p@ss",
+		"prod/db/port":     "5432",
+	}, values(entries))
+}
+
+func TestParseCSV(t *testing.T) {
+	entries, err := Parse("csv", []byte("Value,Name,Comment\n\"a,b\",first,x\nsecond-value,second,y\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []Entry{
+		{Name: "first", Value: []byte("a,b")},
+		{Name: "second", Value: []byte("second-value")},
+	}, entries)
+
+	_, err = Parse("csv", []byte("key,secret\na,b\n"))
+	assert.ErrorContains(t, err, "header with name and value columns")
+}
+
+func TestDetectFormat(t *testing.T) {
+	for path, format := range map[string]string{
+		".env":            "dotenv",
+		"dir/.env.local":  "dotenv",
+		"prod.env":        "dotenv",
+		"secrets.JSON":    "json",
+		"secrets.yml":     "yaml",
+		"secrets.yaml":    "yaml",
+		"export/list.csv": "csv",
+	} {
+		detected, err := DetectFormat(path)
+		assert.NoError(t, err, path)
+		assert.Equal(t, format, detected, path)
+	}
+
+	_, err := DetectFormat("secrets.txt")
+	assert.Error(t, err)
+
+	_, err = Parse("xml", nil)
+	assert.ErrorContains(t, err, "invalid format 'xml'")
+}
+
+func TestParseKeePass(t *testing.T) {
+	data := []byte(`<?xml version="1.0" encoding="utf-8" standalone="yes"?>
+<KeePassFile>
+	<Meta><RecycleBinUUID>bin</RecycleBinUUID></Meta>
+	<Root>
+		<Group>
+			<UUID>root</UUID>
+			<Name>Database</Name>
+			<Entry>
+				<String><Key>Title</Key><Value>GitHub</Value></String>
+				<String><Key>UserName</Key><Value>octocat</Value></String>
+				<String><Key>Password</Key><Value Protected="True">p@ss</Value></String>
+				<String><Key>URL</Key><Value>https://github.com</Value></String>
+				<String><Key>Notes</Key><Value>line1
+line2</Value></String>
+				<String><Key>Recovery Code</Key><Value>1234</Value></String>
+				<History><Entry><String><Key>Title</Key><Value>Old</Value></String></Entry></History>
+			</Entry>
+			<Group>
+				<UUID>work</UUID>
+				<Name>Work Stuff</Name>
+				<Entry>
+					<String><Key>Title</Key><Value>VPN: Office</Value></String>
+					<String><Key>Password</Key><Value>vpn</Value></String>
+					<String><Key>UserName</Key><Value></Value></String>
+				</Entry>
+			</Group>
+			<Group>
+				<UUID>bin</UUID>
+				<Name>Recycle Bin</Name>
+				<Entry><String><Key>Title</Key><Value>Deleted</Value></String><String><Key>Password</Key><Value>x</Value></String></Entry>
+			</Group>
+		</Group>
+	</Root>
+</KeePassFile>`)
+
+	entries, err := Parse("keepass", data)
+	assert.NoError(t, err)
+	assert.Equal(t, []Entry{
+		{
+			Name:        "GitHub",
+			Description: "GitHub",
+			Fields: map[string]string{
+				"username":      "octocat",
+				"password":      "p@ss",
+				"url":           "https://github.com",
+				"notes":         "line1\nline2",
+				"Recovery-Code": "1234",
+			},
+		},
+		{Name: "Work-Stuff/VPN-Office", Description: "VPN: Office", Fields: map[string]string{"password": "vpn"}},
+	}, entries)
+}
+
+func TestParseBitwarden(t *testing.T) {
+	data := []byte(`{
+		"encrypted": false,
+		"folders": [{"id": "f1", "name": "Work/Infra"}],
+		"items": [
+			{"type": 1, "folderId": "f1", "name": "AWS", "notes": "root account",
+			 "login": {"username": "admin", "password": "aws-pass", "totp": "JBSWY3DPEHPK3PXP", "uris": [{"uri": "https://aws.amazon.com"}, {"uri": "https://console.aws.amazon.com"}]},
+			 "fields": [{"name": "Account ID", "value": "123", "type": 0}]},
+			{"type": 2, "folderId": null, "name": "Wifi", "notes": "wifi-pass"},
+			{"type": 3, "folderId": null, "name": "Wifi", "card": {"cardholderName": "Jane", "number": "4111", "code": "123", "expMonth": null}},
+			{"type": 2, "folderId": null, "name": "Empty"}
+		]
+	}`)
+
+	entries, err := Parse("bitwarden", data)
+	assert.NoError(t, err)
+	assert.Equal(t, []Entry{
+		{
+			Name:        "Work/Infra/AWS",
+			Description: "AWS",
+			Fields: map[string]string{
+				"username":   "admin",
+				"password":   "aws-pass",
+				"totp":       "JBSWY3DPEHPK3PXP",
+				"url":        "https://aws.amazon.com",
+				"url-2":      "https://console.aws.amazon.com",
+				"notes":      "root account",
+				"Account-ID": "123",
+			},
+		},
+		{Name: "Wifi", Description: "Wifi", Fields: map[string]string{"notes": "wifi-pass"}},
+		{Name: "Wifi-2", Description: "Wifi", Fields: map[string]string{"cardholderName": "Jane", "number": "4111", "code": "123"}},
+	}, entries)
+
+	_, err = Parse("bitwarden", []byte(`{"encrypted": true, "items": []}`))
+	assert.ErrorContains(t, err, "unencrypted")
+}
+
+func TestParseOnePassword(t *testing.T) {
+	data := []byte("\xef\xbb\xbf\"Title\",\"Url\",\"Username\",\"Password\",\"OTPAuth\",\"Favorite\",\"Archived\",\"Tags\",\"Notes\",\"PIN\"\n" +
+		"\"Mail\",\"https://mail.example.com\",\"jane\",\"mail-pass\",\"\",\"false\",\"false\",\"work\",\"note\",\"0000\"\n")
+
+	entries, err := Parse("1password", data)
+	assert.NoError(t, err)
+	assert.Equal(t, []Entry{
+		{
+			Name:        "Mail",
+			Description: "Mail",
+			Fields: map[string]string{
+				"url":      "https://mail.example.com",
+				"username": "jane",
+				"password": "mail-pass",
+				"notes":    "note",
+				"pin":      "0000",
+			},
+		},
+	}, entries)
+
+	_, err = Parse("1password", []byte("name,value\na,b\n"))
+	assert.ErrorContains(t, err, "title column")
+}
+
+func TestParseBrowser(t *testing.T) {
+	chrome := []byte("name,url,username,password,note\n" +
+		"example.com,https://example.com/login,jane,p1,\n" +
+		"example.com,https://example.com/login,john,p2,shared\n")
+
+	entries, err := Parse("browser", chrome)
+	assert.NoError(t, err)
+	assert.Equal(t, []Entry{
+		{Name: "example-com", Description: "example.com", Fields: map[string]string{"url": "https://example.com/login", "username": "jane", "password": "p1"}},
+		{Name: "example-com-2", Description: "example.com", Fields: map[string]string{"url": "https://example.com/login", "username": "john", "password": "p2", "notes": "shared"}},
+	}, entries)
+
+	firefox := []byte(`"url","username","password","httpRealm","formActionOrigin","guid","timeCreated","timeLastUsed","timePasswordChanged"` + "\n" +
+		`"https://accounts.example.org","jane","p3",,"https://accounts.example.org","{guid}","1","2","3"` + "\n")
+
+	entries, err = Parse("browser", firefox)
+	assert.NoError(t, err)
+	assert.Equal(t, []Entry{
+		{Name: "accounts-example-org", Description: "accounts.example.org", Fields: map[string]string{"url": "https://accounts.example.org", "username": "jane", "password": "p3"}},
+	}, entries)
+}
+
+func TestParsePassStore(t *testing.T) {
+	dir := t.TempDir()
+
+	// A stand-in for gpg that prints the files as they are
+	fakeGPG := filepath.Join(t.TempDir(), "gpg")
+	assert.NoError(t, os.WriteFile(fakeGPG, []byte("#!/bin/sh\nfor last; do :; done\nexec cat \"$last\"\n"), 0700))
+
+	previous := gpgCommand
+	gpgCommand = fakeGPG
+	defer func() { gpgCommand = previous }()
+
+	files := map[string]string{
+		".gpg-id":                 "key",
+		".git/objects/x.gpg":      "ignored",
+		"email/work.gpg":          "mail-pass\nlogin: jane@example.com\nurl: https://mail.example.com\nRemember to rotate\notpauth://totp/Work?secret=JBSWY3DPEHPK3PXP\n",
+		"servers/db/postgres.gpg": "pg-pass\n",
+		"README.txt":              "not an entry",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	}
+
+	entries, err := ParsePassStore(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []Entry{
+		{
+			Name:        "email/work",
+			Description: "work",
+			Fields: map[string]string{
+				"password": "mail-pass",
+				"username": "jane@example.com",
+				"url":      "https://mail.example.com",
+				"notes":    "Remember to rotate",
+				"totp":     "otpauth://totp/Work?secret=JBSWY3DPEHPK3PXP",
+			},
+		},
+		{Name: "servers/db/postgres", Description: "postgres", Fields: map[string]string{"password": "pg-pass"}},
+	}, entries)
+
+	gpgCommand = "false"
+	_, err = ParsePassStore(dir)
+	assert.ErrorContains(t, err, "email/work")
+	assert.ErrorContains(t, err, "servers/db/postgres")
+}
@@ -0,0 +1,93 @@
+package importer
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// keepassFile is a KeePass 2 XML export.
+type keepassFile struct {
+	Meta struct {
+		RecycleBinUUID string `xml:"RecycleBinUUID"`
+	} `xml:"Meta"`
+	Root struct {
+		Groups []keepassGroup `xml:"Group"`
+	} `xml:"Root"`
+}
+
+// keepassGroup is a group of entries, which may hold nested groups.
+type keepassGroup struct {
+	UUID    string         `xml:"UUID"`
+	Name    string         `xml:"Name"`
+	Entries []keepassEntry `xml:"Entry"`
+	Groups  []keepassGroup `xml:"Group"`
+}
+
+// keepassEntry is an entry of a group. Its previous versions are not imported.
+type keepassEntry struct {
+	Strings []struct {
+		Key   string `xml:"Key"`
+		Value string `xml:"Value"`
+	} `xml:"String"`
+}
+
+// parseKeePass reads a KeePass 2 XML export. Groups become namespaces, leaving out the
+// root group named after the database, and the recycle bin is skipped.
+func parseKeePass(data []byte) ([]Entry, error) {
+	var file keepassFile
+	if err := xml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("could not parse KeePass XML: %w", err)
+	}
+
+	var entries []Entry
+
+	var walk func(folder string, group keepassGroup)
+	walk = func(folder string, group keepassGroup) {
+		if group.UUID != "" && group.UUID == file.Meta.RecycleBinUUID {
+			return
+		}
+
+		for _, e := range group.Entries {
+			var title string
+			fields := map[string]string{}
+			var custom [][2]string
+
+			for _, s := range e.Strings {
+				switch s.Key {
+				case "Title":
+					title = s.Value
+				case "UserName":
+					fields[FieldUsername] = s.Value
+				case "Password":
+					fields[FieldPassword] = s.Value
+				case "URL":
+					fields[FieldURL] = s.Value
+				case "Notes":
+					fields[FieldNotes] = s.Value
+				case "otp":
+					fields[FieldTOTP] = s.Value
+				default:
+					custom = append(custom, [2]string{s.Key, s.Value})
+				}
+			}
+
+			for _, c := range custom {
+				addField(fields, c[0], c[1])
+			}
+
+			if entry, ok := login(folder, title, fields); ok {
+				entries = append(entries, entry)
+			}
+		}
+
+		for _, child := range group.Groups {
+			walk(folder+"/"+child.Name, child)
+		}
+	}
+
+	for _, root := range file.Root.Groups {
+		walk("", root)
+	}
+
+	return uniqueNames(entries), nil
+}
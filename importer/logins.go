@@ -0,0 +1,153 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Fields of the structured secrets created for logins of password managers.
+const (
+	FieldUsername = "username"
+	FieldPassword = "password"
+	FieldURL      = "url"
+	FieldNotes    = "notes"
+	FieldTOTP     = "totp"
+)
+
+// reInvalidNameChars matches the characters that cannot be used in names of secrets
+// and fields.
+var reInvalidNameChars = regexp.MustCompile(`[^\w\-]+`)
+
+// sanitizeName turns a title or folder name from a password manager into a valid
+// name by replacing unsupported characters with hyphens.
+func sanitizeName(s string) string {
+	return strings.Trim(reInvalidNameChars.ReplaceAllString(strings.TrimSpace(s), "-"), "-")
+}
+
+// loginName returns the name of the secret for a login in the given folder. Folders
+// may be nested with slashes and become namespaces.
+func loginName(folder string, title string) string {
+	var parts []string
+	for _, part := range strings.Split(folder, "/") {
+		if part = sanitizeName(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	name := sanitizeName(title)
+	if name == "" {
+		name = "unnamed"
+	}
+
+	return strings.Join(append(parts, name), "/")
+}
+
+// login builds the entry for a login of a password manager. Empty fields are left
+// out, and the title is kept as the description of the secret. It reports false if
+// the login has no fields at all.
+func login(folder string, title string, fields map[string]string) (Entry, bool) {
+	kept := map[string]string{}
+	for name, value := range fields {
+		if strings.TrimSpace(value) != "" {
+			kept[name] = value
+		}
+	}
+
+	if len(kept) == 0 {
+		return Entry{}, false
+	}
+
+	return Entry{Name: loginName(folder, title), Fields: kept, Description: strings.TrimSpace(title)}, true
+}
+
+// addField adds a custom field to the fields of a login. Custom fields never replace
+// a field already set, such as the password.
+func addField(fields map[string]string, name string, value string) {
+	name = sanitizeName(name)
+	if name == "" || strings.TrimSpace(value) == "" {
+		return
+	}
+
+	if _, ok := fields[name]; ok {
+		return
+	}
+
+	fields[name] = value
+}
+
+// uniqueNames makes the names of the entries unique by adding a number to every
+// repeated name, e.g. github-com-2.
+func uniqueNames(entries []Entry) []Entry {
+	used := map[string]bool{}
+	for _, e := range entries {
+		used[e.Name] = true
+	}
+
+	seen := map[string]bool{}
+	for i, e := range entries {
+		if !seen[e.Name] {
+			seen[e.Name] = true
+			continue
+		}
+
+		for n := 2; ; n++ {
+			name := e.Name + "-" + strconv.Itoa(n)
+			if !used[name] {
+				used[name] = true
+				entries[i].Name = name
+				break
+			}
+		}
+	}
+
+	return entries
+}
+
+// csvRecords reads CSV rows as maps keyed by the lowercased column names of the
+// header row, which is returned as well.
+func csvRecords(data []byte) ([]string, []map[string]string, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not parse CSV: %w", err)
+	}
+
+	if len(rows) == 0 {
+		return nil, nil, nil
+	}
+
+	header := make([]string, len(rows[0]))
+	for i, col := range rows[0] {
+		header[i] = strings.ToLower(strings.TrimSpace(col))
+	}
+
+	records := make([]map[string]string, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		record := map[string]string{}
+		for i, value := range row {
+			if i < len(header) {
+				record[header[i]] = value
+			}
+		}
+		records = append(records, record)
+	}
+
+	return header, records, nil
+}
+
+// firstOf returns the first non-empty value of the given columns of a record.
+func firstOf(record map[string]string, columns ...string) string {
+	for _, col := range columns {
+		if value := record[col]; value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
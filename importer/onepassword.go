@@ -0,0 +1,59 @@
+package importer
+
+import (
+	"errors"
+	"slices"
+)
+
+// onePasswordColumns are the columns of 1Password CSV exports holding the values of a
+// login, by field. Older versions of 1Password use different column names.
+var onePasswordColumns = map[string][]string{
+	FieldUsername: {"username"},
+	FieldPassword: {"password"},
+	FieldURL:      {"url", "website", "urls"},
+	FieldNotes:    {"notes", "notesplain"},
+	FieldTOTP:     {"otpauth", "one-time password"},
+}
+
+// onePasswordIgnored are columns of 1Password CSV exports describing the item rather
+// than holding values.
+var onePasswordIgnored = []string{"title", "vault", "folder", "favorite", "archived", "tags", "type", "uuid", "created", "modified", "createdat", "updatedat"}
+
+// parseOnePassword reads a 1Password CSV export. Vaults or folders, when exported,
+// become namespaces and columns other than the standard ones are kept as fields.
+func parseOnePassword(data []byte) ([]Entry, error) {
+	header, records, err := csvRecords(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(header) > 0 && !slices.Contains(header, "title") {
+		return nil, errors.New("could not parse 1Password CSV: expected a title column")
+	}
+
+	known := slices.Clone(onePasswordIgnored)
+	for _, columns := range onePasswordColumns {
+		known = append(known, columns...)
+	}
+
+	var entries []Entry
+
+	for _, record := range records {
+		fields := map[string]string{}
+		for field, columns := range onePasswordColumns {
+			fields[field] = firstOf(record, columns...)
+		}
+
+		for _, col := range header {
+			if !slices.Contains(known, col) {
+				addField(fields, col, record[col])
+			}
+		}
+
+		if entry, ok := login(firstOf(record, "folder", "vault"), record["title"], fields); ok {
+			entries = append(entries, entry)
+		}
+	}
+
+	return uniqueNames(entries), nil
+}
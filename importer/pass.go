@@ -0,0 +1,129 @@
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// gpgCommand is the command used to decrypt the files of a password store.
+var gpgCommand = "gpg"
+
+// passFieldNames maps the keys used in the extra lines of pass(1) entries to fields.
+var passFieldNames = map[string]string{
+	"login":    FieldUsername,
+	"user":     FieldUsername,
+	"username": FieldUsername,
+	"email":    FieldUsername,
+	"url":      FieldURL,
+	"website":  FieldURL,
+}
+
+// ParsePassStore reads a pass(1) password store, decrypting every entry with the local
+// gpg. Directories become namespaces. The first line of an entry is its password,
+// "key: value" lines become fields and any other lines are kept as notes.
+func ParsePassStore(dir string) ([]Entry, error) {
+	var entries []Entry
+	var errs []error
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if path != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if filepath.Ext(path) != ".gpg" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, strings.TrimSuffix(path, ".gpg"))
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		data, err := decryptPassFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", rel, err))
+			return nil
+		}
+
+		folder, title := "", rel
+		if i := strings.LastIndex(rel, "/"); i >= 0 {
+			folder, title = rel[:i], rel[i+1:]
+		}
+
+		if entry, ok := login(folder, title, parsePassEntry(data)); ok {
+			entries = append(entries, entry)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not read password store '%s': %w", dir, err)
+	}
+
+	return uniqueNames(entries), errors.Join(errs...)
+}
+
+// decryptPassFile decrypts a file of a password store with gpg.
+func decryptPassFile(path string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command(gpgCommand, "--quiet", "--batch", "--decrypt", path)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("could not decrypt with %s: %s", gpgCommand, msg)
+		}
+		return nil, fmt.Errorf("could not decrypt with %s: %w", gpgCommand, err)
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// parsePassEntry splits the decrypted contents of a pass(1) entry into fields.
+func parsePassEntry(data []byte) map[string]string {
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+
+	fields := map[string]string{FieldPassword: strings.TrimRight(lines[0], "\r")}
+	var notes []string
+
+	for _, line := range lines[1:] {
+		line = strings.TrimRight(line, "\r")
+
+		if strings.HasPrefix(line, "otpauth://") {
+			fields[FieldTOTP] = line
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || strings.Contains(key, " ") || sanitizeName(key) == "" || strings.HasPrefix(value, "//") {
+			notes = append(notes, line)
+			continue
+		}
+
+		key = strings.ToLower(strings.TrimSpace(key))
+		if field, ok := passFieldNames[key]; ok {
+			key = field
+		}
+
+		addField(fields, key, strings.TrimSpace(value))
+	}
+
+	addField(fields, FieldNotes, strings.TrimSpace(strings.Join(notes, "\n")))
+
+	return fields
+}
@@ -0,0 +1,66 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// parseJSON reads a JSON object. Nested objects become namespaces.
+func parseJSON(data []byte) ([]Entry, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var tree map[string]any
+	if err := decoder.Decode(&tree); err != nil {
+		return nil, fmt.Errorf("could not parse JSON: %w", err)
+	}
+
+	return flattenTree(tree)
+}
+
+// parseYAML reads a YAML mapping. Nested mappings become namespaces.
+func parseYAML(data []byte) ([]Entry, error) {
+	var tree map[string]any
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("could not parse YAML: %w", err)
+	}
+
+	return flattenTree(tree)
+}
+
+// flattenTree turns a tree of values into entries, joining the keys of nested
+// objects with slashes. Entries are sorted by name.
+func flattenTree(tree map[string]any) ([]Entry, error) {
+	var entries []Entry
+	var errs []error
+
+	var walk func(prefix string, node map[string]any)
+	walk = func(prefix string, node map[string]any) {
+		for _, key := range slices.Sorted(maps.Keys(node)) {
+			name := prefix + key
+
+			switch value := node[key].(type) {
+			case map[string]any:
+				walk(name+"/", value)
+			case string:
+				entries = append(entries, Entry{Name: name, Value: []byte(value)})
+			case json.Number, bool, int, int64, uint64, float64:
+				entries = append(entries, Entry{Name: name, Value: fmt.Append(nil, value)})
+			case nil:
+				errs = append(errs, fmt.Errorf("%s: value cannot be empty", name))
+			default:
+				errs = append(errs, fmt.Errorf("%s: value must be a string, number, boolean or object", name))
+			}
+		}
+	}
+
+	walk("", tree)
+
+	return entries, errors.Join(errs...)
+}
@@ -0,0 +1,266 @@
+package otp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	TypeTOTP = "totp" // Time-based one-time passwords, RFC 6238
+	TypeHOTP = "hotp" // Counter-based one-time passwords, RFC 4226
+
+	DefaultAlgorithm = "SHA1" // Algorithm used when none is given
+	DefaultDigits    = 6      // Digits of the codes when none are given
+	DefaultPeriod    = 30     // Seconds each TOTP code is valid for when none are given
+)
+
+// Types are the kinds of one-time passwords.
+var Types = []string{TypeTOTP, TypeHOTP}
+
+// Algorithms are the HMAC algorithms codes can be computed with.
+var Algorithms = []string{"SHA1", "SHA256", "SHA512"}
+
+// Key holds the seed of a one-time password and the parameters its codes are computed with.
+type Key struct {
+	Type      string // Kind of one-time password, TypeTOTP or TypeHOTP
+	Issuer    string // Service the key belongs to, e.g. GitHub
+	Account   string // Account the key belongs to, e.g. ops@example.com
+	Secret    string // Seed encoded as base32, without padding
+	Algorithm string // HMAC algorithm, one of Algorithms
+	Digits    int    // Number of digits of the codes
+	Period    int    // Seconds each code is valid for, only used with TOTP
+	Counter   uint64 // Counter of the next code, only used with HOTP
+}
+
+// NewKey returns a TOTP key with the default parameters for a base32 encoded seed.
+func NewKey(secret string) (*Key, error) {
+	k := &Key{
+		Type:      TypeTOTP,
+		Secret:    normaliseSecret(secret),
+		Algorithm: DefaultAlgorithm,
+		Digits:    DefaultDigits,
+		Period:    DefaultPeriod,
+	}
+
+	if err := k.Validate(); err != nil {
+		return nil, err
+	}
+
+	return k, nil
+}
+
+// Parse parses an otpauth:// URI, as shown in QR codes when setting up two-factor
+// authentication, or a base32 encoded seed using the default parameters.
+func Parse(s string) (*Key, error) {
+	s = strings.TrimSpace(s)
+
+	if !strings.HasPrefix(strings.ToLower(s), "otpauth://") {
+		return NewKey(s)
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, errors.New("invalid otpauth URI")
+	}
+
+	k := &Key{
+		Type:      strings.ToLower(u.Host),
+		Algorithm: DefaultAlgorithm,
+		Digits:    DefaultDigits,
+		Period:    DefaultPeriod,
+	}
+
+	label := strings.TrimPrefix(u.Path, "/")
+	if issuer, account, ok := strings.Cut(label, ":"); ok {
+		k.Issuer, k.Account = strings.TrimSpace(issuer), strings.TrimSpace(account)
+	} else {
+		k.Account = label
+	}
+
+	q := u.Query()
+	k.Secret = normaliseSecret(q.Get("secret"))
+
+	// The issuer parameter is preferred over the prefix of the label
+	if issuer := q.Get("issuer"); issuer != "" {
+		k.Issuer = issuer
+	}
+
+	if algorithm := q.Get("algorithm"); algorithm != "" {
+		k.Algorithm = strings.ToUpper(algorithm)
+	}
+
+	if digits := q.Get("digits"); digits != "" {
+		if k.Digits, err = strconv.Atoi(digits); err != nil {
+			return nil, fmt.Errorf("invalid digits '%s' in otpauth URI", digits)
+		}
+	}
+
+	if period := q.Get("period"); period != "" {
+		if k.Period, err = strconv.Atoi(period); err != nil {
+			return nil, fmt.Errorf("invalid period '%s' in otpauth URI", period)
+		}
+	}
+
+	if counter := q.Get("counter"); counter != "" {
+		if k.Counter, err = strconv.ParseUint(counter, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid counter '%s' in otpauth URI", counter)
+		}
+	}
+
+	if err := k.Validate(); err != nil {
+		return nil, err
+	}
+
+	return k, nil
+}
+
+// normaliseSecret removes the spaces, dashes and padding seeds are often shown with,
+// and upper cases them.
+func normaliseSecret(secret string) string {
+	secret = strings.ToUpper(strings.NewReplacer(" ", "", "-", "", "=", "").Replace(secret))
+	return strings.TrimSpace(secret)
+}
+
+// Validate checks that codes can be computed with the key.
+func (k *Key) Validate() error {
+	if !slices.Contains(Types, k.Type) {
+		return fmt.Errorf("invalid one-time password type '%s'. Must be one of: %s", k.Type, strings.Join(Types, ", "))
+	}
+
+	if k.Secret == "" {
+		return errors.New("one-time password seed cannot be empty")
+	}
+
+	if _, err := k.seed(); err != nil {
+		return errors.New("invalid one-time password seed: seeds must be base32 encoded")
+	}
+
+	if !slices.Contains(Algorithms, k.Algorithm) {
+		return fmt.Errorf("invalid algorithm '%s'. Must be one of: %s", k.Algorithm, strings.Join(Algorithms, ", "))
+	}
+
+	if k.Digits < 6 || k.Digits > 10 {
+		return fmt.Errorf("invalid digits %d. Codes must have between 6 and 10 digits", k.Digits)
+	}
+
+	if k.Type == TypeTOTP && k.Period <= 0 {
+		return fmt.Errorf("invalid period %d. Codes must be valid for at least 1 second", k.Period)
+	}
+
+	return nil
+}
+
+// URI returns the key as an otpauth:// URI.
+func (k *Key) URI() string {
+	q := url.Values{}
+	q.Set("secret", k.Secret)
+	if k.Issuer != "" {
+		q.Set("issuer", k.Issuer)
+	}
+	q.Set("algorithm", k.Algorithm)
+	q.Set("digits", strconv.Itoa(k.Digits))
+
+	if k.Type == TypeHOTP {
+		q.Set("counter", strconv.FormatUint(k.Counter, 10))
+	} else {
+		q.Set("period", strconv.Itoa(k.Period))
+	}
+
+	label := k.Account
+	if k.Issuer != "" {
+		label = k.Issuer + ":" + k.Account
+	}
+
+	u := url.URL{Scheme: "otpauth", Host: k.Type, Path: "/" + label, RawQuery: q.Encode()}
+
+	return u.String()
+}
+
+// TOTP returns the code valid at t and how long it remains valid.
+func (k *Key) TOTP(t time.Time) (string, time.Duration, error) {
+	if k.Type != TypeTOTP {
+		return "", 0, errors.New("key is not a time-based one-time password")
+	}
+
+	period := int64(k.Period)
+	counter := t.Unix() / period
+
+	code, err := k.code(uint64(counter))
+	if err != nil {
+		return "", 0, err
+	}
+
+	next := time.Unix((counter+1)*period, 0)
+
+	return code, next.Sub(t), nil
+}
+
+// HOTP returns the code for the current counter and advances the counter. The key has
+// to be stored again for the counter to be kept.
+func (k *Key) HOTP() (string, error) {
+	if k.Type != TypeHOTP {
+		return "", errors.New("key is not a counter-based one-time password")
+	}
+
+	code, err := k.code(k.Counter)
+	if err != nil {
+		return "", err
+	}
+
+	k.Counter++
+
+	return code, nil
+}
+
+// code computes the code for counter as described in RFC 4226.
+func (k *Key) code(counter uint64) (string, error) {
+	seed, err := k.seed()
+	if err != nil {
+		return "", err
+	}
+	defer clear(seed)
+
+	var newHash func() hash.Hash
+	switch k.Algorithm {
+	case "SHA256":
+		newHash = sha256.New
+	case "SHA512":
+		newHash = sha512.New
+	default:
+		newHash = sha1.New
+	}
+
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, counter)
+
+	mac := hmac.New(newHash, seed)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := uint64(binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff)
+
+	mod := uint64(1)
+	for range k.Digits {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", k.Digits, value%mod), nil
+}
+
+// seed decodes the base32 encoded seed.
+func (k *Key) seed() ([]byte, error) {
+	return base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(k.Secret)
+}
@@ -0,0 +1,134 @@
+package otp
+
+import (
+	"encoding/base32"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+	"github.com/stretchr/testify/assert"
+)
+
+// encodeSeed encodes an ASCII seed from the RFC test vectors as base32.
+func encodeSeed(seed string) string {
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte(seed))
+}
+
+func TestTOTP(t *testing.T) {
+	// Test vectors from RFC 6238
+	tests := []struct {
+		algorithm string
+		seed      string
+		unix      int64
+		code      string
+	}{
+		{"SHA1", "12345678901234567890", 59, "94287082"},
+		{"SHA256", "12345678901234567890123456789012", 59, "46119246"},
+		{"SHA512", "1234567890123456789012345678901234567890123456789012345678901234", 59, "90693936"},
+		{"SHA1", "12345678901234567890", 1111111109, "07081804"},
+		{"SHA1", "12345678901234567890", 20000000000, "65353130"},
+	}
+
+	for _, tt := range tests {
+		k := &Key{Type: TypeTOTP, Secret: encodeSeed(tt.seed), Algorithm: tt.algorithm, Digits: 8, Period: 30}
+		assert.NoError(t, k.Validate())
+
+		code, remaining, err := k.TOTP(time.Unix(tt.unix, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, tt.code, code, tt.algorithm)
+		assert.Equal(t, time.Duration(30-tt.unix%30)*time.Second, remaining)
+	}
+
+	k, err := NewKey("JBSWY3DPEHPK3PXP")
+	assert.NoError(t, err)
+	_, err = k.HOTP()
+	assert.Error(t, err)
+}
+
+func TestHOTP(t *testing.T) {
+	k, err := Parse("otpauth://hotp/Example:ops@example.com?secret=" + encodeSeed("12345678901234567890"))
+	assert.NoError(t, err)
+
+	// Test vectors from RFC 4226
+	for i, expected := range []string{"755224", "287082", "359152", "969429"} {
+		code, err := k.HOTP()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, code)
+		assert.Equal(t, uint64(i+1), k.Counter)
+	}
+
+	_, _, err = k.TOTP(time.Now())
+	assert.Error(t, err)
+}
+
+func TestParse(t *testing.T) {
+	k, err := Parse("otpauth://totp/ACME%20Co:john@example.com?secret=JBSWY3DPEHPK3PXP&issuer=ACME%20Co&algorithm=sha256&digits=8&period=60")
+	assert.NoError(t, err)
+	assert.Equal(t, &Key{
+		Type:      TypeTOTP,
+		Issuer:    "ACME Co",
+		Account:   "john@example.com",
+		Secret:    "JBSWY3DPEHPK3PXP",
+		Algorithm: "SHA256",
+		Digits:    8,
+		Period:    60,
+	}, k)
+
+	// The URI of a key parses back to the same key
+	parsed, err := Parse(k.URI())
+	assert.NoError(t, err)
+	assert.Equal(t, k, parsed)
+
+	// Seeds are accepted on their own, as they are often shown in groups
+	k, err = Parse("jbsw y3dp ehpk 3pxp")
+	assert.NoError(t, err)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", k.Secret)
+	assert.Equal(t, TypeTOTP, k.Type)
+	assert.Equal(t, DefaultDigits, k.Digits)
+	assert.Equal(t, DefaultPeriod, k.Period)
+
+	for _, invalid := range []string{
+		"",
+		"not base32!",
+		"otpauth://totp/label",
+		"otpauth://sms/label?secret=JBSWY3DPEHPK3PXP",
+		"otpauth://totp/label?secret=JBSWY3DPEHPK3PXP&algorithm=MD5",
+		"otpauth://totp/label?secret=JBSWY3DPEHPK3PXP&digits=4",
+		"otpauth://totp/label?secret=JBSWY3DPEHPK3PXP&period=0",
+		"otpauth://hotp/label?secret=JBSWY3DPEHPK3PXP&counter=-1",
+	} {
+		_, err := Parse(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestDecodeQR(t *testing.T) {
+	uri := "otpauth://totp/Example:ops@example.com?secret=JBSWY3DPEHPK3PXP&issuer=Example"
+
+	matrix, err := qrcode.NewQRCodeWriter().Encode(uri, gozxing.BarcodeFormat_QR_CODE, 300, 300, nil)
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "qr.png")
+	f, err := os.Create(path)
+	assert.NoError(t, err)
+	assert.NoError(t, png.Encode(f, matrix))
+	assert.NoError(t, f.Close())
+
+	text, err := DecodeQR(path)
+	assert.NoError(t, err)
+	assert.Equal(t, uri, text)
+
+	notImage := filepath.Join(t.TempDir(), "qr.txt")
+	assert.NoError(t, os.WriteFile(notImage, []byte(uri), 0600))
+
+	_, err = DecodeQR(notImage)
+	assert.Error(t, err)
+
+	_, err = DecodeQR(filepath.Join(t.TempDir(), "missing.png"))
+	assert.True(t, strings.Contains(err.Error(), "could not open image"))
+}
@@ -0,0 +1,670 @@
+package secrets
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/engmtcdrm/go-entomb"
+	"github.com/engmtcdrm/mellon/secrets/passphrase"
+)
+
+const (
+	backupFormatVersion = 1               // Version of the backup format written by CreateBackup
+	backupManifestPath  = "manifest.json" // Path of the manifest within a backup
+	backupKeyPath       = "key"           // Path of the encryption key within a backup
+	backupSecretsDir    = "secrets"       // Directory of the secrets within a backup
+	backupHistoryDir    = "history"       // Directory of the previous versions of secrets within a backup
+	backupStagingExt    = ".restoring"    // Extension for the files staged while restoring a backup
+)
+
+// Actions taken for a secret when restoring a backup.
+const (
+	RestoreCreate    = "create"    // The secret does not exist and is restored
+	RestoreOverwrite = "overwrite" // The secret exists and is replaced by the one in the backup
+	RestoreSkip      = "skip"      // The secret exists and is kept as it is
+)
+
+// ErrRestoreCleanup is returned by Restore when the secrets of the backup are in
+// place, but the files they replaced could not all be removed.
+var ErrRestoreCleanup = errors.New("backup restored, but the replaced files could not all be removed")
+
+// BackupManifest describes the contents of a backup.
+type BackupManifest struct {
+	Version   int          `json:"version"`    // Version of the backup format
+	CreatedAt time.Time    `json:"created_at"` // When the backup was created
+	Secrets   []string     `json:"secrets"`    // Names of the secrets in the backup
+	Files     []BackupFile `json:"files"`      // Every file in the backup besides the manifest
+}
+
+// BackupFile is a file stored in a backup.
+type BackupFile struct {
+	Path   string `json:"path"`   // Path of the file within the backup
+	Size   int64  `json:"size"`   // Size of the file in bytes
+	SHA256 string `json:"sha256"` // Hex encoded SHA-256 checksum of the file
+}
+
+// Backup is a backup that has been opened and verified.
+type Backup struct {
+	Manifest BackupManifest
+	files    map[string][]byte // Contents of the files by path within the backup
+}
+
+// RestoreAction is what restoring a backup does with one of its secrets.
+type RestoreAction struct {
+	Name   string // Name of the secret
+	Action string // One of RestoreCreate, RestoreOverwrite or RestoreSkip
+}
+
+// Versions returns the number of previous versions of the secrets in the backup.
+func (b *Backup) Versions() int {
+	count := 0
+	for _, f := range b.Manifest.Files {
+		if strings.HasPrefix(f.Path, backupHistoryDir+"/") && path.Ext(f.Path) != metaExt {
+			count++
+		}
+	}
+
+	return count
+}
+
+// CreateBackup writes the encryption key and every secret in secretFiles, along with
+// their metadata and previous versions, into a single archive sealed with the
+// passphrase. The secrets stay encrypted with the encryption key inside the archive,
+// and the archive as a whole can only be opened with the passphrase.
+func CreateBackup(keyPath string, secretFiles []Secret, pass []byte) ([]byte, BackupManifest, error) {
+	manifest := BackupManifest{Version: backupFormatVersion, CreatedAt: time.Now().UTC()}
+	files := map[string][]byte{}
+	defer func() {
+		for _, data := range files {
+			ClearSecret(&data)
+		}
+	}()
+
+	add := func(archivePath string, src string, optional bool) error {
+		data, err := os.ReadFile(src)
+		if err != nil {
+			if optional && os.IsNotExist(err) {
+				return nil
+			}
+			return fmt.Errorf("could not read '%s': %w", src, err)
+		}
+
+		sum := sha256.Sum256(data)
+		files[archivePath] = data
+		manifest.Files = append(manifest.Files, BackupFile{Path: archivePath, Size: int64(len(data)), SHA256: hex.EncodeToString(sum[:])})
+
+		return nil
+	}
+
+	if err := add(backupKeyPath, keyPath, false); err != nil {
+		return nil, BackupManifest{}, err
+	}
+
+	for _, secret := range secretFiles {
+		name := filepath.ToSlash(secret.name)
+		ext := filepath.Ext(secret.path)
+
+		if err := add(path.Join(backupSecretsDir, name+ext), secret.path, false); err != nil {
+			return nil, BackupManifest{}, err
+		}
+
+		if err := add(path.Join(backupSecretsDir, name+metaExt), secret.MetaPath(), true); err != nil {
+			return nil, BackupManifest{}, err
+		}
+
+		versions, err := secret.previousVersions()
+		if err != nil {
+			return nil, BackupManifest{}, err
+		}
+
+		for _, v := range versions {
+			dir := path.Join(backupHistoryDir, name)
+
+			if err := add(path.Join(dir, filepath.Base(v.path)), v.path, false); err != nil {
+				return nil, BackupManifest{}, err
+			}
+
+			if err := add(path.Join(dir, filepath.Base(v.metaPath())), v.metaPath(), true); err != nil {
+				return nil, BackupManifest{}, err
+			}
+		}
+
+		manifest.Secrets = append(manifest.Secrets, name)
+	}
+
+	slices.Sort(manifest.Secrets)
+	slices.SortFunc(manifest.Files, func(a, b BackupFile) int { return strings.Compare(a.Path, b.Path) })
+
+	archive, err := writeArchive(manifest, files)
+	if err != nil {
+		return nil, BackupManifest{}, err
+	}
+	defer ClearSecret(&archive)
+
+	sealed, err := passphrase.Seal(archive, pass)
+	if err != nil {
+		return nil, BackupManifest{}, fmt.Errorf("could not seal backup: %w", err)
+	}
+
+	return sealed, manifest, nil
+}
+
+// writeArchive writes the manifest followed by the files into a gzip compressed tar archive.
+func writeArchive(manifest BackupManifest, files map[string][]byte) ([]byte, error) {
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	write := func(name string, data []byte) error {
+		hdr := &tar.Header{
+			Name:    name,
+			Mode:    int64(secretMode),
+			Size:    int64(len(data)),
+			ModTime: manifest.CreatedAt,
+			Format:  tar.FormatPAX,
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		_, err := tw.Write(data)
+		return err
+	}
+
+	if err := write(backupManifestPath, manifestData); err != nil {
+		return nil, fmt.Errorf("could not write backup: %w", err)
+	}
+
+	for _, f := range manifest.Files {
+		if err := write(f.Path, files[f.Path]); err != nil {
+			return nil, fmt.Errorf("could not write backup: %w", err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("could not write backup: %w", err)
+	}
+
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("could not write backup: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// OpenBackup opens a backup sealed with the passphrase and verifies that every file
+// listed in its manifest is present and intact, without restoring anything.
+func OpenBackup(sealed []byte, pass []byte) (*Backup, error) {
+	archive, err := passphrase.Open(sealed, pass)
+	if err != nil {
+		if errors.Is(err, passphrase.ErrNotSealed) {
+			return nil, errors.New("file is not a backup")
+		}
+		return nil, fmt.Errorf("could not open backup: %w", err)
+	}
+	defer ClearSecret(&archive)
+
+	files, err := readArchive(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	manifestData, ok := files[backupManifestPath]
+	if !ok {
+		return nil, errors.New("invalid backup: manifest is missing")
+	}
+	delete(files, backupManifestPath)
+
+	var manifest BackupManifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid backup: could not read manifest: %w", err)
+	}
+
+	if manifest.Version < 1 || manifest.Version > backupFormatVersion {
+		return nil, fmt.Errorf("unsupported backup version %d", manifest.Version)
+	}
+
+	b := &Backup{Manifest: manifest, files: files}
+	if err := b.verify(); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// readArchive reads every file in the gzip compressed tar archive.
+func readArchive(archive []byte) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup: %w", err)
+	}
+	defer gz.Close()
+
+	files := map[string][]byte{}
+	tr := tar.NewReader(gz)
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid backup: %w", err)
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			return nil, fmt.Errorf("invalid backup: '%s' is not a regular file", hdr.Name)
+		}
+
+		if _, ok := files[hdr.Name]; ok {
+			return nil, fmt.Errorf("invalid backup: '%s' is stored more than once", hdr.Name)
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid backup: could not read '%s': %w", hdr.Name, err)
+		}
+
+		files[hdr.Name] = data
+	}
+
+	return files, nil
+}
+
+// verify checks the files of the backup against its manifest. Every problem found is
+// reported at once.
+func (b *Backup) verify() error {
+	var errs []error
+
+	listed := map[string]bool{}
+	for _, f := range b.Manifest.Files {
+		listed[f.Path] = true
+
+		if err := validateBackupPath(f.Path); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		data, ok := b.files[f.Path]
+		if !ok {
+			errs = append(errs, fmt.Errorf("'%s' is missing", f.Path))
+			continue
+		}
+
+		sum := sha256.Sum256(data)
+		if int64(len(data)) != f.Size || hex.EncodeToString(sum[:]) != f.SHA256 {
+			errs = append(errs, fmt.Errorf("'%s' does not match its checksum", f.Path))
+		}
+	}
+
+	for p := range b.files {
+		if !listed[p] {
+			errs = append(errs, fmt.Errorf("'%s' is not listed in the manifest", p))
+		}
+	}
+
+	if !listed[backupKeyPath] {
+		errs = append(errs, errors.New("encryption key is missing"))
+	}
+
+	for _, name := range b.Manifest.Secrets {
+		if err := ValidateName(name); err != nil {
+			errs = append(errs, fmt.Errorf("'%s': %w", name, err))
+			continue
+		}
+
+		if b.secretFile(name) == "" {
+			errs = append(errs, fmt.Errorf("secret '%s' is missing", name))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("backup is corrupted:\n%w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// validateBackupPath checks that a path within a backup cannot be restored outside
+// of the directories it belongs to.
+func validateBackupPath(p string) error {
+	if p == backupKeyPath {
+		return nil
+	}
+
+	if path.Clean(p) != p || path.IsAbs(p) || strings.Contains(p, "..") || strings.Contains(p, `\`) {
+		return fmt.Errorf("'%s' is not a valid path", p)
+	}
+
+	dir, _, _ := strings.Cut(p, "/")
+	if (dir != backupSecretsDir && dir != backupHistoryDir) || dir == p {
+		return fmt.Errorf("'%s' is not a valid path", p)
+	}
+
+	return nil
+}
+
+// secretFile returns the path of the encrypted secret within the backup, or an empty
+// string if the secret is not in the backup.
+func (b *Backup) secretFile(name string) string {
+	for _, f := range b.Manifest.Files {
+		dir, file := path.Split(f.Path)
+		ext := path.Ext(file)
+
+		if ext != metaExt && dir+strings.TrimSuffix(file, ext) == path.Join(backupSecretsDir, name) {
+			return f.Path
+		}
+	}
+
+	return ""
+}
+
+// secretFiles returns the paths of every file belonging to the secret within the backup.
+func (b *Backup) secretFiles(name string) []string {
+	secretPath := b.secretFile(name)
+	paths := []string{secretPath}
+
+	metaPath := strings.TrimSuffix(secretPath, path.Ext(secretPath)) + metaExt
+	if _, ok := b.files[metaPath]; ok {
+		paths = append(paths, metaPath)
+	}
+
+	historyDir := path.Join(backupHistoryDir, name) + "/"
+	for _, f := range b.Manifest.Files {
+		if rest, ok := strings.CutPrefix(f.Path, historyDir); ok && !strings.Contains(rest, "/") {
+			paths = append(paths, f.Path)
+		}
+	}
+
+	return paths
+}
+
+// Plan returns what restoring the backup would do with each of its secrets. Secrets
+// that already exist are replaced with overwrite, otherwise they are kept.
+func (b *Backup) Plan(secretFiles []Secret, overwrite bool) []RestoreAction {
+	actions := make([]RestoreAction, 0, len(b.Manifest.Secrets))
+
+	for _, name := range b.Manifest.Secrets {
+		action := RestoreCreate
+		if FindSecretByName(filepath.FromSlash(name), secretFiles) != nil {
+			action = RestoreSkip
+			if overwrite {
+				action = RestoreOverwrite
+			}
+		}
+
+		actions = append(actions, RestoreAction{Name: name, Action: action})
+	}
+
+	return actions
+}
+
+// Restore restores the secrets of the backup into secretsPath, along with their
+// metadata and previous versions, as planned by Plan.
+//
+// When there is no encryption key at keyPath, the key in the backup is restored.
+// When the backup was made with a different key, its secrets are re-encrypted with
+// the current key.
+//
+// Every file is staged next to the file it replaces before anything is replaced, and
+// every replacement is undone if one of them fails, so a failure leaves the secrets as
+// they were. The files of an overwritten secret that the backup has no replacement
+// for, such as versions missing from the backup, are shredded only once everything is
+// in place.
+func (b *Backup) Restore(keyPath string, secretsPath string, secretFiles []Secret, overwrite bool) ([]RestoreAction, error) {
+	actions := b.Plan(secretFiles, overwrite)
+
+	backupTomb, err := b.restoreKey(keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	type restoreFile struct {
+		path string
+		data []byte
+	}
+
+	var writes []restoreFile
+	defer func() {
+		for _, w := range writes {
+			ClearSecret(&w.data)
+		}
+	}()
+
+	for _, a := range actions {
+		if a.Action == RestoreSkip {
+			continue
+		}
+
+		for _, p := range b.secretFiles(a.Name) {
+			dst := b.restorePath(secretsPath, p)
+			if dst == "" {
+				continue
+			}
+
+			data := b.files[p]
+			if backupTomb != nil && path.Ext(p) != metaExt {
+				if data, err = reencryptBackup(backupTomb, keyPath, a.Name, data); err != nil {
+					return nil, err
+				}
+			} else {
+				data = slices.Clone(data)
+			}
+
+			writes = append(writes, restoreFile{path: dst, data: data})
+		}
+	}
+
+	// Files of the overwritten secrets that are not replaced by a file of the backup
+	replaced := make(map[string]bool, len(writes))
+	for _, w := range writes {
+		replaced[w.path] = true
+	}
+
+	var obsolete []string
+	for _, a := range actions {
+		if a.Action != RestoreOverwrite {
+			continue
+		}
+
+		if existing := FindSecretByName(filepath.FromSlash(a.Name), secretFiles); existing != nil {
+			files, err := existing.files()
+			if err != nil {
+				return nil, err
+			}
+
+			for _, f := range files {
+				if !replaced[f] {
+					obsolete = append(obsolete, f)
+				}
+			}
+		}
+	}
+
+	committed := false
+
+	defer func() {
+		if !committed {
+			for _, w := range writes {
+				os.Remove(w.path + backupStagingExt)
+			}
+		}
+	}()
+
+	for _, w := range writes {
+		if err := os.MkdirAll(filepath.Dir(w.path), dirMode); err != nil {
+			return nil, fmt.Errorf("could not create directory '%s': %w", filepath.Dir(w.path), err)
+		}
+
+		if err := writeSyncedFile(w.path+backupStagingExt, w.data); err != nil {
+			return nil, fmt.Errorf("could not restore '%s': %w", w.path, err)
+		}
+	}
+
+	// Each file replaced is kept until every file is in place
+	var undo []func()
+	defer func() {
+		if !committed {
+			for i := len(undo) - 1; i >= 0; i-- {
+				undo[i]()
+			}
+		}
+	}()
+
+	for _, w := range writes {
+		target, oldPath := w.path, w.path+rekeyOldExt
+
+		kept := false
+		if err := os.Rename(target, oldPath); err == nil {
+			kept = true
+		} else if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("could not restore '%s': %w", target, err)
+		}
+
+		if err := os.Rename(target+backupStagingExt, target); err != nil {
+			if kept {
+				os.Rename(oldPath, target)
+			}
+			return nil, fmt.Errorf("could not restore '%s': %w", target, err)
+		}
+
+		undo = append(undo, func() {
+			os.Remove(target)
+			if kept {
+				os.Rename(oldPath, target)
+			}
+		})
+
+		if kept {
+			obsolete = append(obsolete, oldPath)
+		}
+	}
+
+	committed = true
+
+	var errs []error
+	for _, f := range obsolete {
+		if err := ShredFile(f); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+			continue
+		}
+
+		for _, root := range []string{secretsPath, historyPath} {
+			if root == "" {
+				continue
+			}
+			if err := removeEmptyDirs(root, filepath.Dir(f)); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return actions, fmt.Errorf("%w: %w", ErrRestoreCleanup, errors.Join(errs...))
+	}
+
+	return actions, nil
+}
+
+// restorePath returns where a file of the backup is restored to, or an empty string
+// if it is not restored, e.g. previous versions when no history is kept.
+func (b *Backup) restorePath(secretsPath string, p string) string {
+	dir, rest, _ := strings.Cut(p, "/")
+
+	switch dir {
+	case backupSecretsDir:
+		return filepath.Join(secretsPath, filepath.FromSlash(rest))
+	case backupHistoryDir:
+		if historyPath == "" {
+			return ""
+		}
+		return filepath.Join(historyPath, filepath.FromSlash(rest))
+	}
+
+	return ""
+}
+
+// restoreKey restores the encryption key of the backup if there is no key at keyPath
+// yet. If the current key differs from the one in the backup, the tomb of the key in
+// the backup is returned so its secrets can be re-encrypted with the current key.
+func (b *Backup) restoreKey(keyPath string) (*entomb.Tomb, error) {
+	key := b.files[backupKeyPath]
+
+	current, err := os.ReadFile(keyPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("could not read key file: %w", err)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(keyPath), dirMode); err != nil {
+			return nil, fmt.Errorf("could not create directory for key file: %w", err)
+		}
+
+		return nil, writeKeyFile(keyPath, key)
+	}
+	defer ClearSecret(&current)
+
+	if bytes.Equal(current, key) {
+		return nil, nil
+	}
+
+	if !passphrase.IsSealed(key) {
+		return tombFromKey(key)
+	}
+
+	// A key protected by a passphrase is expected to use the current passphrase
+	pass, err := getPassphrase()
+	if err != nil {
+		return nil, err
+	}
+
+	opened, err := passphrase.Open(key, pass)
+	if err != nil {
+		return nil, fmt.Errorf("could not unlock the encryption key of the backup: %w", err)
+	}
+	defer ClearSecret(&opened)
+
+	return tombFromKey(opened)
+}
+
+// reencryptBackup decrypts a version of a secret from the backup with the key of the
+// backup and encrypts it with the key at keyPath.
+func reencryptBackup(backupTomb *entomb.Tomb, keyPath string, name string, data []byte) ([]byte, error) {
+	tomb, err := openTomb(keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := backupTomb.Decrypt(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secret '%s' from backup. The backup may have been made on another machine or by another user", name)
+	}
+	defer ClearSecret(&secret)
+
+	encSecret, err := tomb.Encrypt(secret)
+	if err != nil {
+		return nil, fmt.Errorf("could not encrypt secret '%s' with current key: %w", name, err)
+	}
+
+	return encSecret, nil
+}
@@ -0,0 +1,201 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/engmtcdrm/mellon/secrets/passphrase"
+)
+
+func TestBackupRestore(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, ".key")
+	secretsPath := filepath.Join(dir, "secrets")
+
+	SetHistory(filepath.Join(dir, ".history"), 5)
+	defer SetHistory("", 0)
+
+	secret, err := NewSecret(keyPath, "prod/db/password", filepath.Join(secretsPath, "prod", "db", "password.thurin"))
+	assert.NoError(t, err)
+	assert.NoError(t, secret.Encrypt([]byte("one"), false))
+	assert.NoError(t, secret.Encrypt([]byte("two"), false))
+
+	other, err := NewSecret(keyPath, "api", filepath.Join(secretsPath, "api.thurin"))
+	assert.NoError(t, err)
+	assert.NoError(t, other.Encrypt([]byte("api-key"), false))
+
+	pass := []byte("backup pass")
+	sealed, manifest, err := CreateBackup(keyPath, []Secret{*secret, *other}, pass)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"api", "prod/db/password"}, manifest.Secrets)
+
+	_, err = OpenBackup(sealed, []byte("wrong"))
+	assert.ErrorIs(t, err, passphrase.ErrIncorrectPassphrase)
+
+	_, err = OpenBackup([]byte("not a backup"), pass)
+	assert.ErrorContains(t, err, "not a backup")
+
+	backup, err := OpenBackup(sealed, pass)
+	assert.NoError(t, err)
+	assert.Equal(t, manifest.Secrets, backup.Manifest.Secrets)
+	assert.Equal(t, 1, backup.Versions())
+
+	// Without a key, the key of the backup is restored along with the secrets
+	restoreDir := t.TempDir()
+	restoreKeyPath := filepath.Join(restoreDir, ".key")
+	restoreSecretsPath := filepath.Join(restoreDir, "secrets")
+	SetHistory(filepath.Join(restoreDir, ".history"), 5)
+
+	actions, err := backup.Restore(restoreKeyPath, restoreSecretsPath, nil, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []RestoreAction{{Name: "api", Action: RestoreCreate}, {Name: "prod/db/password", Action: RestoreCreate}}, actions)
+
+	restored, err := GetSecretFiles(restoreKeyPath, restoreSecretsPath, ".thurin")
+	assert.NoError(t, err)
+	assert.Len(t, restored, 2)
+
+	db := FindSecretByName("prod/db/password", restored)
+	value, err := db.Decrypt()
+	assert.NoError(t, err)
+	assert.Equal(t, "two", string(value))
+
+	value, err = db.DecryptVersion(1)
+	assert.NoError(t, err)
+	assert.Equal(t, "one", string(value))
+
+	// Existing secrets are kept unless overwritten
+	api := FindSecretByName("api", restored)
+	assert.NoError(t, api.Encrypt([]byte("changed"), false))
+
+	assert.Equal(t, RestoreSkip, backup.Plan(restored, false)[0].Action)
+	_, err = backup.Restore(restoreKeyPath, restoreSecretsPath, restored, false)
+	assert.NoError(t, err)
+	value, err = api.Decrypt()
+	assert.NoError(t, err)
+	assert.Equal(t, "changed", string(value))
+
+	_, err = backup.Restore(restoreKeyPath, restoreSecretsPath, restored, true)
+	assert.NoError(t, err)
+	value, err = api.Decrypt()
+	assert.NoError(t, err)
+	assert.Equal(t, "api-key", string(value))
+
+	// With a different key, the secrets are re-encrypted with it
+	otherDir := t.TempDir()
+	otherKeyPath := filepath.Join(otherDir, ".key")
+	otherSecretsPath := filepath.Join(otherDir, "secrets")
+	SetHistory("", 0)
+
+	existing, err := NewSecret(otherKeyPath, "existing", filepath.Join(otherSecretsPath, "existing.thurin"))
+	assert.NoError(t, err)
+	assert.NoError(t, existing.Encrypt([]byte("kept"), false))
+
+	_, err = backup.Restore(otherKeyPath, otherSecretsPath, []Secret{*existing}, false)
+	assert.NoError(t, err)
+
+	restored, err = GetSecretFiles(otherKeyPath, otherSecretsPath, ".thurin")
+	assert.NoError(t, err)
+	assert.Len(t, restored, 3)
+
+	for name, expected := range map[string]string{"existing": "kept", "api": "api-key", "prod/db/password": "two"} {
+		value, err := FindSecretByName(name, restored).Decrypt()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(value))
+	}
+}
+
+func TestOpenBackupCorrupted(t *testing.T) {
+	pass := []byte("backup pass")
+
+	manifest := BackupManifest{
+		Version: backupFormatVersion,
+		Secrets: []string{"api", "missing"},
+		Files: []BackupFile{
+			{Path: backupKeyPath, Size: 3, SHA256: "0000"},
+			{Path: "secrets/api.thurin", Size: 1, SHA256: "0000"},
+			{Path: "../escape.thurin", Size: 1, SHA256: "0000"},
+		},
+	}
+	files := map[string][]byte{
+		backupKeyPath:        []byte("key"),
+		"secrets/api.thurin": []byte("x"),
+	}
+
+	archive, err := writeArchive(manifest, files)
+	assert.NoError(t, err)
+
+	sealed, err := passphrase.Seal(archive, pass)
+	assert.NoError(t, err)
+
+	_, err = OpenBackup(sealed, pass)
+	assert.ErrorContains(t, err, "'key' does not match its checksum")
+	assert.ErrorContains(t, err, "'secrets/api.thurin' does not match its checksum")
+	assert.ErrorContains(t, err, "'../escape.thurin' is not a valid path")
+	assert.ErrorContains(t, err, "secret 'missing' is missing")
+}
+
+func TestBackupRestore_Failure(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, ".key")
+	secretsPath := filepath.Join(dir, "secrets")
+	historyDir := filepath.Join(dir, ".history")
+
+	SetHistory(historyDir, 5)
+	defer SetHistory("", 0)
+
+	secret, err := NewSecret(keyPath, "db", filepath.Join(secretsPath, "db.thurin"))
+	assert.NoError(t, err)
+	assert.NoError(t, secret.Encrypt([]byte("one"), false))
+	assert.NoError(t, secret.Encrypt([]byte("two"), false))
+
+	pass := []byte("backup pass")
+	sealed, _, err := CreateBackup(keyPath, []Secret{*secret}, pass)
+	assert.NoError(t, err)
+	backup, err := OpenBackup(sealed, pass)
+	assert.NoError(t, err)
+
+	assert.NoError(t, secret.Encrypt([]byte("three"), false))
+
+	// A previous version that cannot be replaced undoes the secret already replaced
+	blocked := filepath.Join(historyDir, "db", "1.thurin"+rekeyOldExt)
+	assert.NoError(t, os.MkdirAll(filepath.Join(blocked, "blocked"), dirMode))
+
+	_, err = backup.Restore(keyPath, secretsPath, []Secret{*secret}, true)
+	assert.Error(t, err)
+
+	value, err := secret.Decrypt()
+	assert.NoError(t, err)
+	assert.Equal(t, "three", string(value))
+
+	value, err = secret.DecryptVersion(2)
+	assert.NoError(t, err)
+	assert.Equal(t, "two", string(value))
+
+	staged, _ := filepath.Glob(filepath.Join(secretsPath, "*"+backupStagingExt))
+	assert.Empty(t, staged)
+
+	// Once restored, versions missing from the backup are removed
+	assert.NoError(t, os.RemoveAll(blocked))
+
+	_, err = backup.Restore(keyPath, secretsPath, []Secret{*secret}, true)
+	assert.NoError(t, err)
+
+	value, err = secret.Decrypt()
+	assert.NoError(t, err)
+	assert.Equal(t, "two", string(value))
+
+	versions, err := secret.Versions()
+	assert.NoError(t, err)
+	assert.Len(t, versions, 2)
+
+	for _, dir := range []string{secretsPath, filepath.Join(historyDir, "db")} {
+		entries, err := os.ReadDir(dir)
+		assert.NoError(t, err)
+		for _, entry := range entries {
+			assert.NotContains(t, []string{rekeyOldExt, backupStagingExt}, filepath.Ext(entry.Name()))
+		}
+	}
+}
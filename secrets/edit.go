@@ -0,0 +1,55 @@
+package secrets
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/engmtcdrm/mellon/env"
+)
+
+// WriteEditFile writes the value of a secret to a file only the current user can read,
+// in a private directory backed by memory, so it can be edited without its contents
+// reaching a physical disk. The file is named after the secret so editors can
+// recognise its type. It must be removed with RemoveEditFile once edited.
+//
+// If no memory backed directory is available, an error wrapping env.ErrNoMemTempDir
+// is returned, unless allowDisk is set and the file is written to the system temp
+// directory instead.
+func WriteEditFile(name string, value []byte, allowDisk bool) (string, error) {
+	baseDir, err := env.MemTempDir()
+	if err != nil {
+		if !allowDisk {
+			return "", fmt.Errorf("could not edit secret without writing it to disk: %w", err)
+		}
+		baseDir = os.TempDir()
+	}
+
+	dir, err := os.MkdirTemp(baseDir, ".edit-*")
+	if err != nil {
+		return "", fmt.Errorf("could not create temporary directory: %w", err)
+	}
+
+	path := filepath.Join(dir, filepath.Base(name))
+
+	if err := os.WriteFile(path, value, secretMode); err != nil {
+		shredTree(dir, shredFile)
+		return "", fmt.Errorf("could not write temporary file: %w", err)
+	}
+
+	return path, nil
+}
+
+// RemoveEditFile shreds a file written with WriteEditFile along with anything else
+// left in its directory, such as the swap and backup files of editors, then removes
+// the directory. Files written to disk are shredded with ShredFile, which warns when
+// overwriting cannot destroy their contents. In memory, that warning does not apply.
+func RemoveEditFile(path string) error {
+	shred := ShredFile
+	if memDir, err := env.MemTempDir(); err == nil && strings.HasPrefix(path, memDir+string(filepath.Separator)) {
+		shred = shredFile
+	}
+
+	return shredTree(filepath.Dir(path), shred)
+}
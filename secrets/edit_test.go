@@ -0,0 +1,41 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/engmtcdrm/mellon/env"
+)
+
+func TestEditFile(t *testing.T) {
+	runtimeDir, err := os.MkdirTemp("/dev/shm", "mellon-test-")
+	if err != nil || !env.IsMemoryBacked(runtimeDir) {
+		t.Skip("/dev/shm is not available on tmpfs")
+	}
+	defer os.RemoveAll(runtimeDir)
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+
+	path, err := WriteEditFile("prod/db/config.json", []byte(`{"user":"app"}`), false)
+	assert.NoError(t, err)
+	assert.Equal(t, "config.json", filepath.Base(path))
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(secretMode), info.Mode().Perm())
+
+	dirInfo, err := os.Stat(filepath.Dir(path))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o700), dirInfo.Mode().Perm())
+
+	// Files left next to it by the editor are removed as well
+	assert.NoError(t, os.WriteFile(filepath.Join(filepath.Dir(path), ".config.json.swp"), []byte("swap"), secretMode))
+
+	assert.NoError(t, RemoveEditFile(path))
+
+	entries, err := os.ReadDir(runtimeDir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
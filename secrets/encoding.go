@@ -0,0 +1,47 @@
+package secrets
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Encodings lists the encodings a secret can be output in.
+var Encodings = []string{"base64", "hex", "url"}
+
+// Encode encodes a decrypted secret for embedding it in other tools. Supported
+// encodings are base64 (standard, padded), hex (lowercase) and url, which
+// percent-encodes every byte other than the unreserved characters of RFC 3986.
+func Encode(data []byte, encoding string) (string, error) {
+	switch encoding {
+	case "base64":
+		return base64.StdEncoding.EncodeToString(data), nil
+	case "hex":
+		return hex.EncodeToString(data), nil
+	case "url":
+		return percentEncode(data), nil
+	}
+
+	return "", fmt.Errorf("invalid encoding '%s'. Valid encodings are: %s", encoding, strings.Join(Encodings, ", "))
+}
+
+// percentEncode percent-encodes every byte that is not an unreserved character, so
+// the result can be embedded in any part of a URL.
+func percentEncode(data []byte) string {
+	const upperHex = "0123456789ABCDEF"
+
+	var sb strings.Builder
+	for _, b := range data {
+		switch {
+		case 'a' <= b && b <= 'z', 'A' <= b && b <= 'Z', '0' <= b && b <= '9', b == '-', b == '.', b == '_', b == '~':
+			sb.WriteByte(b)
+		default:
+			sb.WriteByte('%')
+			sb.WriteByte(upperHex[b>>4])
+			sb.WriteByte(upperHex[b&0x0f])
+		}
+	}
+
+	return sb.String()
+}
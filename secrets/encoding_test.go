@@ -0,0 +1,30 @@
+package secrets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncode(t *testing.T) {
+	data := []byte("p@ss word/\n\x00")
+
+	tests := map[string]string{
+		"base64": "cEBzcyB3b3JkLwoA",
+		"hex":    "7040737320776f72642f0a00",
+		"url":    "p%40ss%20word%2F%0A%00",
+	}
+
+	for encoding, expected := range tests {
+		value, err := Encode(data, encoding)
+		assert.NoError(t, err, encoding)
+		assert.Equal(t, expected, value, encoding)
+	}
+
+	value, err := Encode([]byte("Az09-._~"), "url")
+	assert.NoError(t, err)
+	assert.Equal(t, "Az09-._~", value)
+
+	_, err = Encode(data, "rot13")
+	assert.Error(t, err)
+}
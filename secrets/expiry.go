@@ -0,0 +1,182 @@
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const reDuration = `(\d+)([smhdwy])`
+
+var (
+	// ErrExpired is returned when decrypting a secret that has expired.
+	ErrExpired = errors.New("secret has expired")
+
+	allowExpired bool // Whether expired secrets may still be decrypted
+
+	durationUnits = map[string]time.Duration{
+		"s": time.Second,
+		"m": time.Minute,
+		"h": time.Hour,
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+		"y": 365 * 24 * time.Hour,
+	}
+)
+
+// SetAllowExpired sets whether expired secrets may still be decrypted.
+func SetAllowExpired(allow bool) {
+	allowExpired = allow
+}
+
+// expirySealPrefix starts the header sealing the expiry of a secret in front of its
+// value before it is encrypted, so the expiry cannot be lifted by editing the metadata.
+var expirySealPrefix = []byte("\x00mellon-expires:")
+
+// IsExpired reports whether the metadata has an expiry that lies before t.
+func (m Metadata) IsExpired(t time.Time) bool {
+	return isExpired(m.ExpiresAt, t)
+}
+
+// isExpired reports whether expiresAt is set and lies before t.
+func isExpired(expiresAt time.Time, t time.Time) bool {
+	return !expiresAt.IsZero() && !expiresAt.After(t)
+}
+
+// sealExpiry returns value preceded by a header recording when it expires, the zero
+// time meaning it never does.
+func sealExpiry(value []byte, expiresAt time.Time) []byte {
+	header := append([]byte{}, expirySealPrefix...)
+	if !expiresAt.IsZero() {
+		header = expiresAt.UTC().AppendFormat(header, time.RFC3339Nano)
+	}
+	header = append(header, '\n')
+
+	return append(header, value...)
+}
+
+// openExpiry splits a decrypted value into the value itself and the expiry sealed in
+// front of it. Values encrypted before expiries were sealed have no header, in which
+// case sealed is false and data is returned whole.
+func openExpiry(data []byte) (value []byte, expiresAt time.Time, sealed bool) {
+	if !bytes.HasPrefix(data, expirySealPrefix) {
+		return data, time.Time{}, false
+	}
+
+	rest := data[len(expirySealPrefix):]
+	end := bytes.IndexByte(rest, '\n')
+	if end < 0 {
+		return data, time.Time{}, false
+	}
+
+	if end > 0 {
+		t, err := time.Parse(time.RFC3339Nano, string(rest[:end]))
+		if err != nil {
+			return data, time.Time{}, false
+		}
+		expiresAt = t
+	}
+
+	return rest[end+1:], expiresAt, true
+}
+
+// SetExpiry makes the secret expire after ttl, renewing the expiry every time the
+// secret is rotated. A ttl of zero removes the expiry.
+func (m *Metadata) SetExpiry(ttl time.Duration) {
+	if ttl <= 0 {
+		m.ExpiresAt = time.Time{}
+		m.TTL = ""
+		return
+	}
+
+	m.TTL = FormatDuration(ttl)
+	m.ExpiresAt = time.Now().Add(ttl)
+}
+
+// SetExpiresAt makes the secret expire at a fixed point in time.
+func (m *Metadata) SetExpiresAt(t time.Time) {
+	m.ExpiresAt = t
+	m.TTL = ""
+}
+
+// renewExpiry moves the expiry forward by the TTL of the secret, if it has one.
+func (m *Metadata) renewExpiry() {
+	if m.TTL == "" {
+		return
+	}
+
+	if ttl, err := ParseDuration(m.TTL); err == nil {
+		m.ExpiresAt = time.Now().Add(ttl)
+	}
+}
+
+// ParseDuration parses a duration such as 90d, 2w or 1d12h. Besides the units
+// understood by time.ParseDuration, d (days), w (weeks) and y (years) are supported.
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, nil
+	}
+
+	re := regexp.MustCompile(`^(` + reDuration + `)+$`)
+	if !re.MatchString(s) {
+		return 0, fmt.Errorf("invalid duration '%s'. Use a number followed by a unit (s, m, h, d, w or y), e.g. 90d", s)
+	}
+
+	var total time.Duration
+	for _, match := range regexp.MustCompile(reDuration).FindAllStringSubmatch(s, -1) {
+		n, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration '%s': %w", s, err)
+		}
+
+		total += time.Duration(n) * durationUnits[match[2]]
+	}
+
+	return total, nil
+}
+
+// FormatDuration formats a duration using the largest whole units, e.g. 90d or 1d12h.
+// Weeks are not used so durations given in days are kept as days.
+func FormatDuration(d time.Duration) string {
+	if d <= 0 {
+		return "0s"
+	}
+
+	var sb strings.Builder
+	for _, unit := range []string{"y", "d", "h", "m", "s"} {
+		if n := d / durationUnits[unit]; n > 0 {
+			sb.WriteString(strconv.FormatInt(int64(n), 10) + unit)
+			d -= n * durationUnits[unit]
+		}
+	}
+
+	if sb.Len() == 0 {
+		return "0s"
+	}
+
+	return sb.String()
+}
+
+// ParseTime parses a date (2006-01-02), a date and time (2006-01-02 15:04) or an
+// RFC 3339 timestamp in the local timezone.
+func ParseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time '%s'. Use a date such as 2006-01-02, 2006-01-02 15:04 or an RFC 3339 timestamp", s)
+}
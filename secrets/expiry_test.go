@@ -0,0 +1,183 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDuration(t *testing.T) {
+	day := 24 * time.Hour
+
+	cases := map[string]time.Duration{
+		"90d":    90 * day,
+		"2w":     14 * day,
+		"1y":     365 * day,
+		"1d12h":  day + 12*time.Hour,
+		"12h":    12 * time.Hour,
+		"1h30m":  90 * time.Minute,
+		" 30s ":  30 * time.Second,
+		"0s":     0,
+		"1w2d3h": 9*day + 3*time.Hour,
+	}
+
+	for input, expected := range cases {
+		d, err := ParseDuration(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, expected, d, input)
+	}
+
+	for _, input := range []string{"", "d", "90", "90x", "-1d", "1.5d"} {
+		_, err := ParseDuration(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	day := 24 * time.Hour
+
+	assert.Equal(t, "90d", FormatDuration(90*day))
+	assert.Equal(t, "14d", FormatDuration(14*day))
+	assert.Equal(t, "1d12h", FormatDuration(day+12*time.Hour))
+	assert.Equal(t, "0s", FormatDuration(0))
+
+	// Formatted durations can be parsed again
+	for _, d := range []time.Duration{90 * day, 400 * day, 36 * time.Hour, 90 * time.Second} {
+		parsed, err := ParseDuration(FormatDuration(d))
+		assert.NoError(t, err)
+		assert.Equal(t, d, parsed)
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	parsed, err := ParseTime("2030-01-31")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2030, 1, 31, 0, 0, 0, 0, time.Local), parsed)
+
+	parsed, err = ParseTime("2030-01-31 17:30")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2030, 1, 31, 17, 30, 0, 0, time.Local), parsed)
+
+	parsed, err = ParseTime("2030-01-31T17:30:00Z")
+	assert.NoError(t, err)
+	assert.True(t, parsed.Equal(time.Date(2030, 1, 31, 17, 30, 0, 0, time.UTC)))
+
+	_, err = ParseTime("next tuesday")
+	assert.Error(t, err)
+}
+
+func TestMetadataExpiry(t *testing.T) {
+	var meta Metadata
+	now := time.Now()
+
+	assert.False(t, meta.IsExpired(now))
+
+	meta.SetExpiry(90 * 24 * time.Hour)
+	assert.Equal(t, "90d", meta.TTL)
+	assert.False(t, meta.IsExpired(now))
+	assert.True(t, meta.IsExpired(now.Add(91*24*time.Hour)))
+
+	meta.SetExpiresAt(now.Add(-time.Minute))
+	assert.Empty(t, meta.TTL)
+	assert.True(t, meta.IsExpired(now))
+
+	meta.SetExpiry(0)
+	assert.True(t, meta.ExpiresAt.IsZero())
+	assert.False(t, meta.IsExpired(now))
+}
+
+func TestSealedExpiry(t *testing.T) {
+	dir := t.TempDir()
+	value := []byte("sealedvalue")
+
+	secret, err := NewSecret(filepath.Join(dir, ".key"), "sealed", filepath.Join(dir, "sealed.thurin"))
+	assert.NoError(t, err)
+
+	assert.NoError(t, secret.Encrypt(bytes.Clone(value), false))
+
+	meta, err := secret.Metadata()
+	assert.NoError(t, err)
+	meta.SetExpiresAt(time.Now().Add(-time.Minute))
+	assert.NoError(t, secret.SaveMetadata(meta))
+
+	_, err = secret.Decrypt()
+	assert.ErrorIs(t, err, ErrExpired)
+
+	// Removing the expiry from the metadata by hand does not lift it
+	meta.ExpiresAt = time.Time{}
+	assert.NoError(t, os.WriteFile(secret.MetaPath(), mustMarshal(t, meta), 0600))
+
+	_, err = secret.Decrypt()
+	assert.ErrorIs(t, err, ErrExpired)
+
+	// Removing it through SaveMetadata does
+	meta.SetExpiresAt(time.Now().Add(-time.Minute))
+	assert.NoError(t, os.WriteFile(secret.MetaPath(), mustMarshal(t, meta), 0600))
+	meta.SetExpiry(0)
+	assert.NoError(t, secret.SaveMetadata(meta))
+
+	decrypted, err := secret.Decrypt()
+	assert.NoError(t, err)
+	assert.Equal(t, value, decrypted)
+
+	// Values encrypted before expiries were sealed fall back to the metadata
+	tomb, err := openTomb(secret.keyPath)
+	assert.NoError(t, err)
+	encSecret, err := tomb.Encrypt(bytes.Clone(value))
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(secret.path, encSecret, 0600))
+
+	meta.SetExpiresAt(time.Now().Add(-time.Minute))
+	assert.NoError(t, os.WriteFile(secret.MetaPath(), mustMarshal(t, meta), 0600))
+
+	_, err = secret.Decrypt()
+	assert.ErrorIs(t, err, ErrExpired)
+
+	SetAllowExpired(true)
+	defer SetAllowExpired(false)
+
+	decrypted, err = secret.Decrypt()
+	assert.NoError(t, err)
+	assert.Equal(t, value, decrypted)
+}
+
+func mustMarshal(t *testing.T, v any) []byte {
+	data, err := json.Marshal(v)
+	assert.NoError(t, err)
+	return data
+}
+
+func TestSealedExpiry_Versions(t *testing.T) {
+	dir := t.TempDir()
+
+	SetHistory(filepath.Join(dir, ".history"), 5)
+	defer SetHistory("", 0)
+
+	secret, err := NewSecret(filepath.Join(dir, ".key"), "versioned", filepath.Join(dir, "versioned.thurin"))
+	assert.NoError(t, err)
+
+	for _, value := range []string{"one", "two"} {
+		assert.NoError(t, secret.Encrypt([]byte(value), false))
+	}
+
+	meta, err := secret.Metadata()
+	assert.NoError(t, err)
+	meta.SetExpiresAt(time.Now().Add(-time.Minute))
+	assert.NoError(t, secret.SaveMetadata(meta))
+
+	// Previous versions of an expired secret are refused as well
+	_, err = secret.DecryptVersion(1)
+	assert.ErrorIs(t, err, ErrExpired)
+
+	SetAllowExpired(true)
+	defer SetAllowExpired(false)
+
+	value, err := secret.DecryptVersion(1)
+	assert.NoError(t, err)
+	assert.Equal(t, "one", string(value))
+}
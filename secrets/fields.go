@@ -0,0 +1,103 @@
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	KindFields = "fields" // Kind of secrets holding named fields instead of a single value
+
+	reValidField = `^[\w\-]+$`
+)
+
+// IsStructured reports whether the secret holds named fields instead of a single value.
+func (m Metadata) IsStructured() bool {
+	return m.Kind == KindFields
+}
+
+// EncryptFields encrypts the named fields as a structured secret and writes it to
+// the secret's path. Field values are stored exactly as given.
+func (s *Secret) EncryptFields(fields map[string]string) error {
+	if len(fields) == 0 {
+		return fmt.Errorf("secret '%s' must have at least one field", s.name)
+	}
+
+	for name := range fields {
+		if err := ValidateFieldName(name); err != nil {
+			return fmt.Errorf("%w. The field provided was '%s'", err, name)
+		}
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+
+	defer ClearSecret(&data)
+
+	return s.writeSecret(data, KindFields, false)
+}
+
+// DecryptFields decrypts a structured secret and returns its fields.
+func (s *Secret) DecryptFields() (map[string]string, error) {
+	meta, err := s.Metadata()
+	if err != nil {
+		return nil, err
+	}
+
+	if !meta.IsStructured() {
+		return nil, fmt.Errorf("secret '%s' holds a single value and has no fields", s.name)
+	}
+
+	data, err := s.Decrypt()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]string{}
+	err = json.Unmarshal(data, &fields)
+	ClearSecret(&data)
+	if err != nil {
+		return nil, fmt.Errorf("could not read fields of secret '%s': %w", s.name, err)
+	}
+
+	return fields, nil
+}
+
+// ValidateFieldName checks if a string is a valid field name.
+func ValidateFieldName(s string) error {
+	var re = regexp.MustCompile(reValidField)
+
+	if re.MatchString(s) {
+		return nil
+	}
+
+	return errors.New("invalid field name: Field names can only contain alphanumeric, hyphens and underscores")
+}
+
+// Field returns the value of a top-level field in a decrypted secret. This works for
+// structured secrets as well as single value secrets holding a JSON object.
+func Field(data []byte, name string) (string, error) {
+	doc, err := parseJSON(data)
+	if err != nil {
+		return "", errors.New("secret has no fields and does not hold a JSON object")
+	}
+
+	obj, ok := doc.(map[string]any)
+	if !ok {
+		return "", errors.New("secret has no fields and does not hold a JSON object")
+	}
+
+	value, ok := obj[name]
+	if !ok {
+		return "", fmt.Errorf("field '%s' %w. Available fields are: %s", name, ErrNotFound, strings.Join(slices.Sorted(maps.Keys(obj)), ", "))
+	}
+
+	return formatJSONValue(value)
+}
@@ -0,0 +1,39 @@
+package secrets
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptFields(t *testing.T) {
+	dir := t.TempDir()
+
+	secret, err := NewSecret(filepath.Join(dir, ".key"), "db", filepath.Join(dir, "db.thurin"))
+	assert.NoError(t, err)
+
+	assert.Error(t, secret.EncryptFields(map[string]string{}))
+	assert.Error(t, secret.EncryptFields(map[string]string{"bad name": "x"}))
+
+	fields := map[string]string{"host": "db.example.com", "password": " keep spaces "}
+	assert.NoError(t, secret.EncryptFields(fields))
+
+	meta, err := secret.Metadata()
+	assert.NoError(t, err)
+	assert.True(t, meta.IsStructured())
+
+	decrypted, err := secret.DecryptFields()
+	assert.NoError(t, err)
+	assert.Equal(t, fields, decrypted)
+
+	// Replacing the fields with a single value changes the kind of the secret
+	assert.NoError(t, secret.Encrypt([]byte("single"), false))
+
+	meta, err = secret.Metadata()
+	assert.NoError(t, err)
+	assert.False(t, meta.IsStructured())
+
+	_, err = secret.DecryptFields()
+	assert.Error(t, err)
+}
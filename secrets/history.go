@@ -0,0 +1,293 @@
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	historyPath      string // Where previous versions of secrets are stored
+	historyRetention int    // Number of previous versions kept for each secret
+)
+
+// Version is a version of a secret, either a previous one kept in the history or
+// the current one.
+type Version struct {
+	Number    int       `json:"version"`    // Version number, starting at 1 and increasing with every change of value
+	RotatedAt time.Time `json:"rotated_at"` // When the value of this version was set
+	Current   bool      `json:"current"`    // Whether this is the current version of the secret
+	path      string    // Path of the encrypted version
+	meta      Metadata  // Metadata of the version
+}
+
+// SetHistory sets the directory where previous versions of secrets are stored and
+// how many previous versions are kept for each secret. A retention of 0 disables
+// keeping previous versions.
+func SetHistory(path string, retention int) {
+	historyPath = path
+	historyRetention = retention
+}
+
+// Versions returns all versions of the secret, oldest first, ending with the current version.
+func (s *Secret) Versions() ([]Version, error) {
+	versions, err := s.previousVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	meta, err := s.Metadata()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(versions, Version{
+		Number:    meta.currentVersion(),
+		RotatedAt: meta.RotatedAt,
+		Current:   true,
+		path:      s.path,
+		meta:      meta,
+	}), nil
+}
+
+// DecryptVersion decrypts the given version of the secret. Previous versions of an
+// expired secret are refused like its current value is by Decrypt.
+func (s *Secret) DecryptVersion(number int) ([]byte, error) {
+	v, err := s.findVersion(number)
+	if err != nil {
+		return nil, err
+	}
+
+	if v.Current {
+		return s.Decrypt()
+	}
+
+	current, expiresAt, err := s.decryptCurrent()
+	ClearSecret(&current)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkExpiry(expiresAt); err != nil {
+		return nil, err
+	}
+
+	return s.decryptValue(v.path)
+}
+
+// Rollback makes a previous version the current version of the secret. The version
+// being replaced is kept in the history like any other change.
+func (s *Secret) Rollback(number int) error {
+	v, err := s.findVersion(number)
+	if err != nil {
+		return err
+	}
+
+	if v.Current {
+		return fmt.Errorf("version %d is already the current version of secret '%s'", number, s.name)
+	}
+
+	// Decrypted so the current expiry is sealed in it, rather than that of the version
+	value, err := s.decryptValue(v.path)
+	if err != nil {
+		return err
+	}
+	defer ClearSecret(&value)
+
+	return s.writeSecret(value, v.meta.Kind, v.meta.Raw)
+}
+
+// VersionMetadata returns the metadata of the given version of the secret.
+func (s *Secret) VersionMetadata(number int) (Metadata, error) {
+	v, err := s.findVersion(number)
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	return v.meta, nil
+}
+
+// findVersion returns the given version of the secret.
+func (s *Secret) findVersion(number int) (Version, error) {
+	versions, err := s.Versions()
+	if err != nil {
+		return Version{}, err
+	}
+
+	for _, v := range versions {
+		if v.Number == number {
+			return v, nil
+		}
+	}
+
+	return Version{}, fmt.Errorf("version %d of secret '%s' %w", number, s.name, ErrNotFound)
+}
+
+// historyDir returns the directory holding the previous versions of the secret.
+func (s *Secret) historyDir() string {
+	return filepath.Join(historyPath, s.name)
+}
+
+// previousVersions returns the versions of the secret kept in the history, oldest first.
+func (s *Secret) previousVersions() ([]Version, error) {
+	if historyPath == "" {
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(s.historyDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("could not read history of secret '%s': %w", s.name, err)
+	}
+
+	ext := filepath.Ext(s.path)
+
+	var versions []Version
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ext {
+			continue
+		}
+
+		number, err := strconv.Atoi(strings.TrimSuffix(entry.Name(), ext))
+		if err != nil {
+			continue
+		}
+
+		v := Version{Number: number, path: filepath.Join(s.historyDir(), entry.Name())}
+
+		// The metadata of a version records when its value was set
+		var meta Metadata
+		if data, err := os.ReadFile(v.metaPath()); err == nil && json.Unmarshal(data, &meta) == nil {
+			v.RotatedAt = meta.RotatedAt
+			v.meta = meta
+		}
+
+		versions = append(versions, v)
+	}
+
+	slices.SortFunc(versions, func(a, b Version) int { return a.Number - b.Number })
+
+	return versions, nil
+}
+
+// archive copies the current version of the secret into the history and prunes
+// versions beyond the retention. It returns the number of the next version.
+func (s *Secret) archive(meta Metadata) (int, error) {
+	current := meta.currentVersion()
+
+	if historyPath == "" || historyRetention <= 0 {
+		return current + 1, nil
+	}
+
+	encSecret, err := os.ReadFile(s.path)
+	if err != nil {
+		return 0, fmt.Errorf("could not read secret '%s' to keep it in history: %w", s.name, err)
+	}
+
+	if err := os.MkdirAll(s.historyDir(), dirMode); err != nil {
+		return 0, fmt.Errorf("could not create history directory for secret '%s': %w", s.name, err)
+	}
+
+	v := Version{Number: current, path: filepath.Join(s.historyDir(), strconv.Itoa(current)+filepath.Ext(s.path))}
+
+	if err := os.WriteFile(v.path, encSecret, secretMode); err != nil {
+		return 0, fmt.Errorf("could not keep secret '%s' in history: %w", s.name, err)
+	}
+
+	metaData, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+
+	if err := os.WriteFile(v.metaPath(), metaData, secretMode); err != nil {
+		return 0, fmt.Errorf("could not keep metadata of secret '%s' in history: %w", s.name, err)
+	}
+
+	versions, err := s.previousVersions()
+	if err != nil {
+		return 0, err
+	}
+
+	for len(versions) > historyRetention {
+		if err := versions[0].remove(); err != nil {
+			return 0, err
+		}
+		versions = versions[1:]
+	}
+
+	return current + 1, nil
+}
+
+// removeHistory removes all previous versions of the secret, along with any
+// directories left empty in the history.
+func (s *Secret) removeHistory() error {
+	versions, err := s.previousVersions()
+	if err != nil {
+		return err
+	}
+
+	for _, v := range versions {
+		if err := v.remove(); err != nil {
+			return err
+		}
+	}
+
+	if len(versions) == 0 {
+		return nil
+	}
+
+	return removeEmptyDirs(historyPath, s.historyDir())
+}
+
+// files returns the paths of every file of the secret that exists, its previous
+// versions and their metadata included.
+func (s *Secret) files() ([]string, error) {
+	paths := []string{s.path, s.MetaPath()}
+
+	versions, err := s.previousVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range versions {
+		paths = append(paths, v.path, v.metaPath())
+	}
+
+	return slices.DeleteFunc(paths, func(p string) bool {
+		_, err := os.Stat(p)
+		return os.IsNotExist(err)
+	}), nil
+}
+
+// metaPath returns the path of the metadata file of the version.
+func (v Version) metaPath() string {
+	return strings.TrimSuffix(v.path, filepath.Ext(v.path)) + metaExt
+}
+
+// remove shreds the version and its metadata from the history.
+func (v Version) remove() error {
+	if err := ShredFile(v.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("could not remove version %d from history: %w", v.Number, err)
+	}
+
+	if err := ShredFile(v.metaPath()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("could not remove metadata of version %d from history: %w", v.Number, err)
+	}
+
+	return nil
+}
+
+// currentVersion returns the number of the current version. Secrets created before
+// versions were recorded are at version 1.
+func (m Metadata) currentVersion() int {
+	return max(m.Version, 1)
+}
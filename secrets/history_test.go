@@ -0,0 +1,60 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory(t *testing.T) {
+	dir := t.TempDir()
+	historyDir := filepath.Join(dir, ".history")
+
+	SetHistory(historyDir, 2)
+	defer SetHistory("", 0)
+
+	secret, err := NewSecret(filepath.Join(dir, ".key"), "team/secret", filepath.Join(dir, "team", "secret.thurin"))
+	assert.NoError(t, err)
+
+	for _, value := range []string{"one", "two", "three", "four"} {
+		assert.NoError(t, secret.Encrypt([]byte(value), false))
+	}
+
+	// Only the current version and the last two previous versions are kept
+	versions, err := secret.Versions()
+	assert.NoError(t, err)
+	assert.Len(t, versions, 3)
+	assert.Equal(t, []int{2, 3, 4}, []int{versions[0].Number, versions[1].Number, versions[2].Number})
+	assert.True(t, versions[2].Current)
+	assert.False(t, versions[2].RotatedAt.IsZero())
+
+	value, err := secret.DecryptVersion(3)
+	assert.NoError(t, err)
+	assert.Equal(t, "three", string(value))
+
+	_, err = secret.DecryptVersion(1)
+	assert.Error(t, err)
+
+	// Rolling back keeps the replaced version in the history
+	assert.Error(t, secret.Rollback(4))
+	assert.NoError(t, secret.Rollback(2))
+
+	value, err = secret.Decrypt()
+	assert.NoError(t, err)
+	assert.Equal(t, "two", string(value))
+
+	value, err = secret.DecryptVersion(4)
+	assert.NoError(t, err)
+	assert.Equal(t, "four", string(value))
+
+	meta, err := secret.Metadata()
+	assert.NoError(t, err)
+	assert.Equal(t, 5, meta.Version)
+
+	// Removing the secret prunes its history
+	assert.NoError(t, RemoveSecret(dir, *secret))
+	_, err = os.Stat(filepath.Join(historyDir, "team"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,228 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/engmtcdrm/go-entomb"
+	"github.com/engmtcdrm/mellon/secrets/passphrase"
+)
+
+var (
+	passphraseFunc     func() ([]byte, error)      // Called to obtain the passphrase for a protected key
+	verifiedPassphrase []byte                      // Passphrase that unlocked the key during this run
+	tombs              = map[string]*entomb.Tomb{} // Tombs already opened, keyed by key path
+	tombsMu            sync.Mutex                  // Guards tombs
+)
+
+// ErrKeyLocked is returned when a passphrase protected key is needed but no passphrase is available.
+var ErrKeyLocked = errors.New("encryption key is protected by a passphrase")
+
+// SetPassphraseFunc sets the function called to obtain the passphrase whenever a
+// passphrase protected key has to be unlocked.
+func SetPassphraseFunc(fn func() ([]byte, error)) {
+	passphraseFunc = fn
+}
+
+// IsKeyLocked reports whether the key at keyPath is protected by a passphrase.
+// A key that does not exist yet is not locked.
+func IsKeyLocked(keyPath string) (bool, error) {
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("could not read key file: %w", err)
+	}
+
+	return passphrase.IsSealed(data), nil
+}
+
+// LockKey protects the key at keyPath with a passphrase. If the key does not exist
+// yet, it is created first.
+func LockKey(keyPath string, pass []byte) error {
+	locked, err := IsKeyLocked(keyPath)
+	if err != nil {
+		return err
+	}
+
+	if locked {
+		return errors.New("encryption key is already protected by a passphrase")
+	}
+
+	// The key could not be unlocked again afterwards
+	if errTombFromKey != nil {
+		return fmt.Errorf("cannot protect the encryption key with a passphrase: %w", errTombFromKey)
+	}
+
+	if _, err := os.Stat(keyPath); os.IsNotExist(err) {
+		if _, err := entomb.NewTomb(keyPath, true, true); err != nil {
+			return fmt.Errorf("could not create key: %w", err)
+		}
+	}
+
+	return sealKeyFile(keyPath, pass)
+}
+
+// UnlockKey permanently removes the passphrase protecting the key at keyPath.
+func UnlockKey(keyPath string, pass []byte) error {
+	key, err := openKeyFile(keyPath, pass)
+	if err != nil {
+		return err
+	}
+	defer ClearSecret(&key)
+
+	return writeKeyFile(keyPath, key)
+}
+
+// ChangeKeyPassphrase replaces the passphrase protecting the key at keyPath.
+func ChangeKeyPassphrase(keyPath string, oldPass []byte, newPass []byte) error {
+	key, err := openKeyFile(keyPath, oldPass)
+	if err != nil {
+		return err
+	}
+	defer ClearSecret(&key)
+
+	sealed, err := passphrase.Seal(key, newPass)
+	if err != nil {
+		return fmt.Errorf("could not protect key with passphrase: %w", err)
+	}
+
+	return writeKeyFile(keyPath, sealed)
+}
+
+// openTomb returns the tomb for the key at keyPath, creating the key if it does
+// not exist and unlocking it with the passphrase if it is protected.
+func openTomb(keyPath string) (*entomb.Tomb, error) {
+	tombsMu.Lock()
+	defer tombsMu.Unlock()
+
+	if tomb, ok := tombs[keyPath]; ok {
+		return tomb, nil
+	}
+
+	locked, err := IsKeyLocked(keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var tomb *entomb.Tomb
+	if locked {
+		pass, err := getPassphrase()
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := openKeyFile(keyPath, pass)
+		if err != nil {
+			return nil, err
+		}
+
+		// Only a passphrase that unlocked the key is remembered, a wrong one is asked for again
+		verifiedPassphrase = pass
+
+		tomb, err = tombFromKey(key)
+		ClearSecret(&key)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		tomb, err = entomb.NewTomb(keyPath, true, true)
+		if err != nil {
+			return nil, fmt.Errorf("could not create tomb: %w", err)
+		}
+	}
+
+	tombs[keyPath] = tomb
+
+	return tomb, nil
+}
+
+// forgetTomb removes the cached tomb for keyPath, e.g. after the key has been replaced,
+// along with the passphrase that unlocked it.
+func forgetTomb(keyPath string) {
+	tombsMu.Lock()
+	defer tombsMu.Unlock()
+
+	delete(tombs, keyPath)
+	verifiedPassphrase = nil
+}
+
+// getPassphrase returns the passphrase that already unlocked the key during this run,
+// or obtains it through the function set with SetPassphraseFunc.
+func getPassphrase() ([]byte, error) {
+	if verifiedPassphrase != nil {
+		return verifiedPassphrase, nil
+	}
+
+	if passphraseFunc == nil {
+		return nil, ErrKeyLocked
+	}
+
+	pass, err := passphraseFunc()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pass) == 0 {
+		return nil, errors.New("passphrase cannot be empty")
+	}
+
+	return pass, nil
+}
+
+// openKeyFile reads the passphrase protected key at keyPath and returns the unprotected key.
+func openKeyFile(keyPath string, pass []byte) ([]byte, error) {
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read key file: %w", err)
+	}
+
+	if !passphrase.IsSealed(data) {
+		return nil, errors.New("encryption key is not protected by a passphrase")
+	}
+
+	key, err := passphrase.Open(data, pass)
+	if err != nil {
+		return nil, fmt.Errorf("could not unlock encryption key: %w", err)
+	}
+
+	return key, nil
+}
+
+// sealKeyFile protects the unprotected key at keyPath with a passphrase in place.
+func sealKeyFile(keyPath string, pass []byte) error {
+	key, err := os.ReadFile(keyPath)
+	if err != nil {
+		return fmt.Errorf("could not read key file: %w", err)
+	}
+	defer ClearSecret(&key)
+
+	sealed, err := passphrase.Seal(key, pass)
+	if err != nil {
+		return fmt.Errorf("could not protect key with passphrase: %w", err)
+	}
+
+	return writeKeyFile(keyPath, sealed)
+}
+
+// writeKeyFile atomically replaces the key file so an interrupted write never
+// leaves a truncated key behind.
+func writeKeyFile(keyPath string, data []byte) error {
+	tmpPath := keyPath + ".tmp"
+
+	if err := os.WriteFile(tmpPath, data, secretMode); err != nil {
+		return fmt.Errorf("could not write key file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, keyPath); err != nil {
+		shredFile(tmpPath)
+		return fmt.Errorf("could not replace key file: %w", err)
+	}
+
+	forgetTomb(keyPath)
+
+	return nil
+}
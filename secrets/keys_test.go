@@ -0,0 +1,42 @@
+package secrets
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenTomb_Passphrase(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, ".key")
+
+	secret, err := NewSecret(keyPath, "db", filepath.Join(dir, "db.thurin"))
+	assert.NoError(t, err)
+	assert.NoError(t, secret.Encrypt([]byte("value"), false))
+
+	assert.NoError(t, LockKey(keyPath, []byte("right")))
+
+	var asked []string
+	answers := []string{"wrong", "right"}
+	SetPassphraseFunc(func() ([]byte, error) {
+		asked = append(asked, answers[len(asked)])
+		return []byte(asked[len(asked)-1]), nil
+	})
+	defer SetPassphraseFunc(nil)
+	defer forgetTomb(keyPath)
+
+	// A wrong passphrase is not remembered, so it is asked for again
+	_, err = secret.Decrypt()
+	assert.ErrorContains(t, err, "could not unlock encryption key")
+
+	value, err := secret.Decrypt()
+	assert.NoError(t, err)
+	assert.Equal(t, "value", string(value))
+
+	// The passphrase that unlocked the key is reused without asking
+	pass, err := getPassphrase()
+	assert.NoError(t, err)
+	assert.Equal(t, "right", string(pass))
+	assert.Equal(t, []string{"wrong", "right"}, asked)
+}
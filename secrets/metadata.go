@@ -0,0 +1,142 @@
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const metaExt = ".meta" // The file extension for secret metadata files, stored next to the secret
+
+// Metadata holds information about a secret. It is stored unencrypted next to the
+// secret so it can be listed without decrypting anything.
+type Metadata struct {
+	CreatedAt   time.Time `json:"created_at"`            // When the secret was created
+	UpdatedAt   time.Time `json:"updated_at"`            // When the secret or its metadata was last changed
+	RotatedAt   time.Time `json:"rotated_at"`            // When the value of the secret was last changed
+	Description string    `json:"description,omitempty"` // Free-text description of the secret
+	Owner       string    `json:"owner,omitempty"`       // Owner of the secret
+	Tags        []string  `json:"tags,omitempty"`        // Labels used to group and filter secrets
+	ExpiresAt   time.Time `json:"expires_at,omitzero"`   // When the secret expires
+	TTL         string    `json:"ttl,omitempty"`         // How long the secret is valid after each rotation
+	Version     int       `json:"version,omitempty"`     // Version of the value, increased every time it changes
+	Kind        string    `json:"kind,omitempty"`        // Kind of value held, empty for a single value, KindFields or KindOTP
+	Raw         bool      `json:"raw,omitempty"`         // Whether the value is stored exactly as given, without trimming whitespace
+}
+
+// MetaPath returns the path of the metadata file of the secret.
+func (s *Secret) MetaPath() string {
+	return strings.TrimSuffix(s.path, filepath.Ext(s.path)) + metaExt
+}
+
+// Metadata returns the metadata of the secret. Secrets created before metadata was
+// recorded fall back to the modification time of the secret file.
+func (s *Secret) Metadata() (Metadata, error) {
+	var meta Metadata
+
+	data, err := os.ReadFile(s.MetaPath())
+	if err == nil {
+		if err := json.Unmarshal(data, &meta); err != nil {
+			return Metadata{}, fmt.Errorf("could not read metadata of secret '%s': %w", s.name, err)
+		}
+
+		return meta, nil
+	}
+
+	if !os.IsNotExist(err) {
+		return Metadata{}, fmt.Errorf("could not read metadata of secret '%s': %w", s.name, err)
+	}
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return meta, nil
+		}
+		return Metadata{}, fmt.Errorf("could not read metadata of secret '%s': %w", s.name, err)
+	}
+
+	meta.CreatedAt = info.ModTime()
+	meta.UpdatedAt = info.ModTime()
+	meta.RotatedAt = info.ModTime()
+
+	return meta, nil
+}
+
+// SaveMetadata writes the metadata of the secret, marking it as updated. When the
+// expiry changes, the value is encrypted again with the new expiry sealed in it.
+func (s *Secret) SaveMetadata(meta Metadata) error {
+	if current, err := s.Metadata(); err == nil && !current.ExpiresAt.Equal(meta.ExpiresAt) {
+		if err := s.resealExpiry(meta.ExpiresAt); err != nil {
+			return err
+		}
+	}
+
+	return s.saveMetadata(meta)
+}
+
+// saveMetadata writes the metadata of the secret as SaveMetadata does, leaving the
+// encrypted value untouched.
+func (s *Secret) saveMetadata(meta Metadata) error {
+	meta.UpdatedAt = time.Now()
+	if meta.CreatedAt.IsZero() {
+		meta.CreatedAt = meta.UpdatedAt
+	}
+
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(s.MetaPath(), data, secretMode); err != nil {
+		return fmt.Errorf("could not write metadata of secret '%s': %w", s.name, err)
+	}
+
+	return nil
+}
+
+// writeSecret encrypts value, with the expiry of the secret sealed in it, and writes
+// it to the secret's path. The rotation, the kind of value and whether it is stored
+// raw are recorded in its metadata. The value being replaced is kept in the history.
+func (s *Secret) writeSecret(value []byte, kind string, raw bool) error {
+	meta := Metadata{Version: 1}
+
+	// Metadata left behind without its secret does not belong to a new secret
+	var previous *Metadata
+	if _, err := os.Stat(s.path); err == nil {
+		current, err := s.Metadata()
+		if err != nil {
+			return err
+		}
+		meta, previous = current, &current
+	}
+
+	meta.RotatedAt = time.Now()
+	meta.Kind = kind
+	meta.Raw = raw
+	meta.renewExpiry()
+
+	encSecret, err := s.encryptValue(value, meta.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), dirMode); err != nil {
+		return fmt.Errorf("could not create directory for secret '%s': %w", s.name, err)
+	}
+
+	// Keep the value being replaced in the history
+	if previous != nil {
+		if meta.Version, err = s.archive(*previous); err != nil {
+			return err
+		}
+	}
+
+	if err := os.WriteFile(s.path, encSecret, secretMode); err != nil {
+		return err
+	}
+
+	return s.saveMetadata(meta)
+}
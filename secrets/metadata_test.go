@@ -0,0 +1,51 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetadata(t *testing.T) {
+	dir := t.TempDir()
+	secretPath := filepath.Join(dir, "nested", "secret.thurin")
+
+	secret, err := NewSecret(filepath.Join(dir, ".key"), "nested/secret", secretPath)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "nested", "secret.meta"), secret.MetaPath())
+
+	// A secret that does not exist has no metadata
+	meta, err := secret.Metadata()
+	assert.NoError(t, err)
+	assert.True(t, meta.CreatedAt.IsZero())
+
+	// Secrets without a metadata file fall back to the modification time
+	assert.NoError(t, os.MkdirAll(filepath.Dir(secretPath), 0700))
+	assert.NoError(t, os.WriteFile(secretPath, []byte("data"), 0600))
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	assert.NoError(t, os.Chtimes(secretPath, modTime, modTime))
+
+	meta, err = secret.Metadata()
+	assert.NoError(t, err)
+	assert.True(t, meta.CreatedAt.Equal(modTime))
+	assert.True(t, meta.RotatedAt.Equal(modTime))
+
+	meta.Description = "A test secret"
+	meta.Owner = "tester"
+	assert.NoError(t, secret.SaveMetadata(meta))
+
+	saved, err := secret.Metadata()
+	assert.NoError(t, err)
+	assert.Equal(t, "A test secret", saved.Description)
+	assert.Equal(t, "tester", saved.Owner)
+	assert.True(t, saved.CreatedAt.Equal(modTime))
+	assert.True(t, saved.UpdatedAt.After(modTime))
+
+	// Removing the secret removes its metadata too
+	assert.NoError(t, RemoveSecret(dir, *secret))
+	_, err = os.Stat(secret.MetaPath())
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,46 @@
+package secrets
+
+import (
+	"slices"
+	"strings"
+)
+
+// NormalizeNamespace returns the namespace with a single trailing slash, e.g. prod/db/.
+func NormalizeNamespace(namespace string) string {
+	return strings.TrimRight(namespace, "/") + "/"
+}
+
+// FilterNamespace returns the secrets within the namespace, including those in nested
+// namespaces. The trailing slash of the namespace is optional.
+func FilterNamespace(secretFiles []Secret, namespace string) []Secret {
+	namespace = NormalizeNamespace(namespace)
+
+	var filtered []Secret
+	for _, secret := range secretFiles {
+		if strings.HasPrefix(secret.name, namespace) {
+			filtered = append(filtered, secret)
+		}
+	}
+
+	return filtered
+}
+
+// Namespaces returns every namespace holding secrets, including parent namespaces of
+// nested ones, sorted by name and each with a trailing slash.
+func Namespaces(secretFiles []Secret) []string {
+	var namespaces []string
+
+	for _, secret := range secretFiles {
+		parts := strings.Split(secret.name, "/")
+		for i := 1; i < len(parts); i++ {
+			namespace := strings.Join(parts[:i], "/") + "/"
+			if !slices.Contains(namespaces, namespace) {
+				namespaces = append(namespaces, namespace)
+			}
+		}
+	}
+
+	slices.Sort(namespaces)
+
+	return namespaces
+}
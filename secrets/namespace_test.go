@@ -0,0 +1,49 @@
+package secrets
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamespaces(t *testing.T) {
+	var secretFiles []Secret
+	for _, name := range []string{"root", "prod/api", "prod/db/password", "production/api"} {
+		secret, err := NewSecret(".key", name, filepath.Join("secrets", name+".thurin"))
+		assert.NoError(t, err)
+		secretFiles = append(secretFiles, *secret)
+	}
+
+	names := func(secretFiles []Secret) []string {
+		var names []string
+		for _, s := range secretFiles {
+			names = append(names, s.Name())
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"prod/api", "prod/db/password"}, names(FilterNamespace(secretFiles, "prod/")))
+	assert.Equal(t, []string{"prod/api", "prod/db/password"}, names(FilterNamespace(secretFiles, "prod")))
+	assert.Equal(t, []string{"prod/db/password"}, names(FilterNamespace(secretFiles, "prod/db//")))
+	assert.Empty(t, FilterNamespace(secretFiles, "pro"))
+
+	assert.Equal(t, []string{"prod/", "prod/db/", "production/"}, Namespaces(secretFiles))
+}
+
+func TestRemoveSecretPrunesAncestors(t *testing.T) {
+	dir := t.TempDir()
+
+	secret, err := NewSecret(filepath.Join(dir, ".key"), "prod/db/password", filepath.Join(dir, "prod", "db", "password.thurin"))
+	assert.NoError(t, err)
+	assert.NoError(t, secret.Encrypt([]byte("value"), false))
+
+	sibling, err := NewSecret(filepath.Join(dir, ".key"), "other/db/password", filepath.Join(dir, "other", "db", "password.thurin"))
+	assert.NoError(t, err)
+	assert.NoError(t, sibling.Encrypt([]byte("value"), false))
+
+	assert.NoError(t, RemoveSecret(dir, *secret))
+	assert.NoDirExists(t, filepath.Join(dir, "prod"))
+	assert.DirExists(t, filepath.Join(dir, "other", "db"))
+	assert.DirExists(t, dir)
+}
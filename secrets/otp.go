@@ -0,0 +1,93 @@
+package secrets
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/engmtcdrm/mellon/otp"
+)
+
+const KindOTP = "otp" // Kind of secrets holding the key of a one-time password as an otpauth:// URI
+
+// IsOTP reports whether the secret holds the key of a one-time password.
+func (m Metadata) IsOTP() bool {
+	return m.Kind == KindOTP
+}
+
+// EncryptOTP encrypts the key of a one-time password as an otpauth:// URI and writes
+// it to the secret's path.
+func (s *Secret) EncryptOTP(key *otp.Key) error {
+	if err := key.Validate(); err != nil {
+		return err
+	}
+
+	data := []byte(key.URI())
+	defer ClearSecret(&data)
+
+	return s.writeSecret(data, KindOTP, false)
+}
+
+// DecryptOTP decrypts the key of a one-time password secret.
+func (s *Secret) DecryptOTP() (*otp.Key, error) {
+	key, _, err := s.decryptOTP()
+	return key, err
+}
+
+// decryptOTP decrypts the key of a one-time password secret as DecryptOTP does, and
+// returns it along with the expiry sealed with it.
+func (s *Secret) decryptOTP() (*otp.Key, time.Time, error) {
+	meta, err := s.Metadata()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	if !meta.IsOTP() {
+		return nil, time.Time{}, fmt.Errorf("secret '%s' does not hold a one-time password", s.name)
+	}
+
+	data, expiresAt, err := s.decryptUnexpired()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	key, err := otp.Parse(string(data))
+	ClearSecret(&data)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("could not read one-time password of secret '%s': %w", s.name, err)
+	}
+
+	return key, expiresAt, nil
+}
+
+// OTPCode returns the current code of a one-time password secret and, for TOTP, how
+// long it remains valid. For HOTP, the advanced counter is stored before the code is
+// returned so a code is never handed out twice. Advancing the counter is not a change
+// of value, so it is neither kept in the history nor recorded as a rotation.
+func (s *Secret) OTPCode(now time.Time) (string, time.Duration, error) {
+	key, expiresAt, err := s.decryptOTP()
+	if err != nil {
+		return "", 0, err
+	}
+
+	if key.Type == otp.TypeTOTP {
+		return key.TOTP(now)
+	}
+
+	code, err := key.HOTP()
+	if err != nil {
+		return "", 0, err
+	}
+
+	data := []byte(key.URI())
+	encSecret, err := s.encryptValue(data, expiresAt)
+	ClearSecret(&data)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if err := s.replaceSecretFile(encSecret); err != nil {
+		return "", 0, fmt.Errorf("could not store counter of secret '%s': %w", s.name, err)
+	}
+
+	return code, 0, nil
+}
@@ -0,0 +1,63 @@
+package secrets
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/engmtcdrm/mellon/otp"
+)
+
+func TestOTP(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, ".key")
+
+	SetHistory(filepath.Join(dir, ".history"), 5)
+	defer SetHistory("", 0)
+
+	secret, err := NewSecret(keyPath, "github", filepath.Join(dir, "github.thurin"))
+	assert.NoError(t, err)
+
+	// Seed of the test vectors from RFC 4226
+	key, err := otp.Parse("otpauth://hotp/GitHub:ops?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ")
+	assert.NoError(t, err)
+	assert.NoError(t, secret.EncryptOTP(key))
+
+	meta, err := secret.Metadata()
+	assert.NoError(t, err)
+	assert.True(t, meta.IsOTP())
+
+	// The counter is kept between codes without adding versions to the history
+	for _, expected := range []string{"755224", "287082", "359152"} {
+		code, _, err := secret.OTPCode(time.Now())
+		assert.NoError(t, err)
+		assert.Equal(t, expected, code)
+	}
+
+	stored, err := secret.DecryptOTP()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), stored.Counter)
+	assert.Equal(t, "GitHub", stored.Issuer)
+
+	versions, err := secret.previousVersions()
+	assert.NoError(t, err)
+	assert.Empty(t, versions)
+
+	key, err = otp.NewKey("GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ")
+	assert.NoError(t, err)
+	assert.NoError(t, secret.EncryptOTP(key))
+
+	code, remaining, err := secret.OTPCode(time.Unix(59, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, "287082", code)
+	assert.Equal(t, time.Second, remaining)
+
+	plain, err := NewSecret(keyPath, "plain", filepath.Join(dir, "plain.thurin"))
+	assert.NoError(t, err)
+	assert.NoError(t, plain.Encrypt([]byte("value"), false))
+
+	_, _, err = plain.OTPCode(time.Now())
+	assert.ErrorContains(t, err, "does not hold a one-time password")
+}
@@ -0,0 +1,143 @@
+package passphrase
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const kdfArgon2id = "argon2id" // The only key derivation function currently supported
+
+// Default Argon2id parameters, following the recommendations of RFC 9106
+const (
+	defaultTime    uint32 = 3
+	defaultMemory  uint32 = 64 * 1024
+	defaultThreads uint8  = 4
+	saltSize              = 16
+	keySize               = 32
+)
+
+var (
+	// ErrIncorrectPassphrase is returned when sealed data cannot be opened with the passphrase provided.
+	ErrIncorrectPassphrase = errors.New("incorrect passphrase")
+
+	// ErrNotSealed is returned when the data provided was not sealed with a passphrase.
+	ErrNotSealed = errors.New("data is not sealed with a passphrase")
+)
+
+// envelope is the on-disk representation of data sealed with a passphrase.
+type envelope struct {
+	KDF     string `json:"kdf"`
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// Seal encrypts data with a key derived from the passphrase using Argon2id and
+// AES-256-GCM. The returned bytes contain everything, except the passphrase,
+// needed to open the data again.
+func Seal(data []byte, passphrase []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, errors.New("passphrase cannot be empty")
+	}
+
+	env := envelope{
+		KDF:     kdfArgon2id,
+		Time:    defaultTime,
+		Memory:  defaultMemory,
+		Threads: defaultThreads,
+		Salt:    make([]byte, saltSize),
+	}
+
+	if _, err := rand.Read(env.Salt); err != nil {
+		return nil, err
+	}
+
+	gcm, err := env.cipher(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	env.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(env.Nonce); err != nil {
+		return nil, err
+	}
+
+	env.Data = gcm.Seal(nil, env.Nonce, data, []byte(env.KDF))
+
+	return json.Marshal(env)
+}
+
+// Open decrypts data previously sealed with Seal using the passphrase.
+func Open(sealed []byte, passphrase []byte) ([]byte, error) {
+	env, err := parse(sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := env.cipher(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(env.Nonce) != gcm.NonceSize() {
+		return nil, errors.New("sealed data has an invalid nonce")
+	}
+
+	data, err := gcm.Open(nil, env.Nonce, env.Data, []byte(env.KDF))
+	if err != nil {
+		return nil, ErrIncorrectPassphrase
+	}
+
+	return data, nil
+}
+
+// IsSealed reports whether the data was sealed with a passphrase.
+func IsSealed(data []byte) bool {
+	_, err := parse(data)
+	return err == nil
+}
+
+// parse decodes and validates a sealed envelope.
+func parse(sealed []byte) (*envelope, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(sealed), []byte("{")) {
+		return nil, ErrNotSealed
+	}
+
+	var env envelope
+	if err := json.Unmarshal(sealed, &env); err != nil {
+		return nil, ErrNotSealed
+	}
+
+	if env.KDF != kdfArgon2id {
+		return nil, fmt.Errorf("unsupported key derivation function '%s'", env.KDF)
+	}
+
+	if len(env.Salt) == 0 || len(env.Nonce) == 0 || env.Time == 0 || env.Memory == 0 || env.Threads == 0 {
+		return nil, errors.New("sealed data is missing parameters")
+	}
+
+	return &env, nil
+}
+
+// cipher derives the key from the passphrase and returns the AEAD used to seal and open data.
+func (env *envelope) cipher(passphrase []byte) (cipher.AEAD, error) {
+	key := argon2.IDKey(passphrase, env.Salt, env.Time, env.Memory, env.Threads, keySize)
+	defer clear(key)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
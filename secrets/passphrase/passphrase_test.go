@@ -0,0 +1,37 @@
+package passphrase
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSealOpen(t *testing.T) {
+	data := []byte("the key to the doors of durin")
+
+	sealed, err := Seal(data, []byte("mellon"))
+	assert.NoError(t, err)
+	assert.True(t, IsSealed(sealed))
+	assert.NotContains(t, string(sealed), string(data))
+
+	opened, err := Open(sealed, []byte("mellon"))
+	assert.NoError(t, err)
+	assert.Equal(t, data, opened)
+
+	// Incorrect passphrase
+	_, err = Open(sealed, []byte("friend"))
+	assert.ErrorIs(t, err, ErrIncorrectPassphrase)
+
+	// Empty passphrase
+	_, err = Seal(data, nil)
+	assert.Error(t, err)
+}
+
+func TestIsSealed(t *testing.T) {
+	assert.False(t, IsSealed(nil))
+	assert.False(t, IsSealed([]byte("gAAAAABnot-a-sealed-key")))
+	assert.False(t, IsSealed([]byte(`{"kdf":"none"}`)))
+
+	_, err := Open([]byte("gAAAAABnot-a-sealed-key"), []byte("mellon"))
+	assert.ErrorIs(t, err, ErrNotSealed)
+}
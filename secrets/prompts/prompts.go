@@ -2,6 +2,7 @@ package prompts
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/engmtcdrm/go-pardon"
 	pp "github.com/engmtcdrm/go-prettyprint"
@@ -9,7 +10,8 @@ import (
 )
 
 // GetSecretOptions returns a list of options for selecting a secret from the provided list of secret files.
-func GetSecretOptions(secretFiles []secrets.Secret, action string, exeCmd string) ([]pardon.Option[secrets.Secret], error) {
+// Only secrets matching the tag filter are included, and the tags of each secret are shown next to its name.
+func GetSecretOptions(secretFiles []secrets.Secret, filter secrets.TagFilter, action string, exeCmd string) ([]pardon.Option[secrets.Secret], error) {
 	if len(secretFiles) == 0 {
 		return nil, fmt.Errorf(
 			"no secrets found to %s\n\nPlease run command %s to create a secret",
@@ -21,7 +23,25 @@ func GetSecretOptions(secretFiles []secrets.Secret, action string, exeCmd string
 	options := []pardon.Option[secrets.Secret]{}
 
 	for _, secret := range secretFiles {
-		options = append(options, pardon.NewOption(secret.Name(), secret))
+		meta, err := secret.Metadata()
+		if err != nil {
+			return nil, err
+		}
+
+		if !filter.Match(meta.Tags) {
+			continue
+		}
+
+		key := secret.Name()
+		if len(meta.Tags) > 0 {
+			key += " " + pp.Dimf("[%s]", strings.Join(meta.Tags, ", "))
+		}
+
+		options = append(options, pardon.NewOption(key, secret))
+	}
+
+	if len(options) == 0 {
+		return nil, fmt.Errorf("no secrets %s found to %s", filter, action)
 	}
 
 	return options, nil
@@ -0,0 +1,165 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// queryStep is a single step of a query, either an object key or an array index.
+type queryStep struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// Query returns the value found at a JSONPath-style path in a decrypted secret, such
+// as $.hosts[0].port, .password or ['api-key']. The leading $ is optional. String
+// values nested as JSON inside other strings are parsed as the path goes through them,
+// so fields holding JSON can be queried too. Strings are returned as is and any other
+// value as compact JSON.
+func Query(data []byte, query string) (string, error) {
+	steps, err := parseQuery(query)
+	if err != nil {
+		return "", err
+	}
+
+	current, err := parseJSON(data)
+	if err != nil {
+		return "", errors.New("secret does not hold JSON and cannot be queried")
+	}
+
+	path := "$"
+	for _, step := range steps {
+		// Fields holding JSON are parsed so the query can continue into them
+		if s, ok := current.(string); ok {
+			if nested, err := parseJSON([]byte(s)); err == nil {
+				current = nested
+			}
+		}
+
+		if step.isIndex {
+			arr, ok := current.([]any)
+			if !ok {
+				return "", fmt.Errorf("query '%s' failed: %s is not an array", query, path)
+			}
+
+			if step.index < 0 || step.index >= len(arr) {
+				return "", fmt.Errorf("query '%s' failed: index %d is out of range for %s with %d element(s)", query, step.index, path, len(arr))
+			}
+
+			current = arr[step.index]
+			path += fmt.Sprintf("[%d]", step.index)
+			continue
+		}
+
+		obj, ok := current.(map[string]any)
+		if !ok {
+			return "", fmt.Errorf("query '%s' failed: %s is not an object", query, path)
+		}
+
+		value, ok := obj[step.key]
+		if !ok {
+			return "", fmt.Errorf("query '%s' failed: %s has no key '%s'", query, path, step.key)
+		}
+
+		current = value
+		path += "." + step.key
+	}
+
+	return formatJSONValue(current)
+}
+
+// parseQuery splits a query into its steps.
+func parseQuery(query string) ([]queryStep, error) {
+	invalid := func(reason string) error {
+		return fmt.Errorf("invalid query '%s': %s", query, reason)
+	}
+
+	q := strings.TrimPrefix(strings.TrimSpace(query), "$")
+
+	// A query may start with a key without a leading dot, e.g. password
+	if q != "" && q[0] != '.' && q[0] != '[' {
+		q = "." + q
+	}
+
+	var steps []queryStep
+	for q != "" {
+		switch q[0] {
+		case '.':
+			end := strings.IndexAny(q[1:], ".[")
+			if end < 0 {
+				end = len(q) - 1
+			}
+
+			key := q[1 : end+1]
+			if key == "" {
+				return nil, invalid("empty key")
+			}
+
+			steps = append(steps, queryStep{key: key})
+			q = q[end+1:]
+		case '[':
+			end := strings.IndexByte(q, ']')
+			if end < 0 {
+				return nil, invalid("missing ]")
+			}
+
+			inner := q[1:end]
+			switch {
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				steps = append(steps, queryStep{key: inner[1 : len(inner)-1]})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, invalid(fmt.Sprintf("'%s' is not an array index or quoted key", inner))
+				}
+				steps = append(steps, queryStep{index: index, isIndex: true})
+			}
+
+			q = q[end+1:]
+		default:
+			return nil, invalid(fmt.Sprintf("unexpected '%c'", q[0]))
+		}
+	}
+
+	if len(steps) == 0 {
+		return nil, invalid("the query must select a value")
+	}
+
+	return steps, nil
+}
+
+// parseJSON parses a JSON document, keeping numbers exactly as written.
+func parseJSON(data []byte) (any, error) {
+	var doc any
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	if dec.More() {
+		return nil, errors.New("unexpected data after JSON document")
+	}
+
+	return doc, nil
+}
+
+// formatJSONValue returns strings as is and any other JSON value as compact JSON.
+func formatJSONValue(value any) (string, error) {
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
@@ -0,0 +1,57 @@
+package secrets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuery(t *testing.T) {
+	doc := []byte(`{"user":"app","port":5432,"hosts":[{"name":"a"},{"name":"b"}],"api-key":"k","nested":"{\"token\":\"t\"}"}`)
+
+	tests := map[string]string{
+		"user":             "app",
+		".user":            "app",
+		"$.port":           "5432",
+		"$.hosts[1].name":  "b",
+		"$.hosts[0]":       `{"name":"a"}`,
+		"$['api-key']":     "k",
+		`$["api-key"]`:     "k",
+		"$.nested.token":   "t",
+		"hosts[0]['name']": "a",
+	}
+
+	for query, expected := range tests {
+		value, err := Query(doc, query)
+		assert.NoError(t, err, query)
+		assert.Equal(t, expected, value, query)
+	}
+
+	for _, query := range []string{"", "$", "$.missing", "$.hosts[2]", "$.user[0]", "$.hosts.name", "$.hosts[x]", "$.hosts[0", "$..user"} {
+		_, err := Query(doc, query)
+		assert.Error(t, err, query)
+	}
+
+	_, err := Query([]byte("not json"), "$.user")
+	assert.Error(t, err)
+}
+
+func TestField(t *testing.T) {
+	value, err := Field([]byte(`{"password":"hunter2","port":5432}`), "password")
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", value)
+
+	value, err = Field([]byte(`{"password":"hunter2","port":5432}`), "port")
+	assert.NoError(t, err)
+	assert.Equal(t, "5432", value)
+
+	_, err = Field([]byte(`{"password":"hunter2"}`), "user")
+	assert.ErrorContains(t, err, "Available fields are: password")
+
+	_, err = Field([]byte("plain value"), "password")
+	assert.Error(t, err)
+
+	assert.NoError(t, ValidateFieldName("api-key_2"))
+	assert.Error(t, ValidateFieldName("api.key"))
+	assert.Error(t, ValidateFieldName(""))
+}
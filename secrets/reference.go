@@ -0,0 +1,76 @@
+package secrets
+
+import (
+	"regexp"
+)
+
+// RefScheme is the scheme of references to secrets, such as mellon://prod/db#password.
+const RefScheme = "mellon://"
+
+// reReference matches a reference to a secret, optionally selecting a field of a
+// structured secret. The name and field follow reValidName and reValidField.
+var reReference = regexp.MustCompile(`mellon://([\w/\\\-]+)(?:#([\w\-]+))?`)
+
+// Reference is a reference to a secret, or a field of a structured secret.
+type Reference struct {
+	Name  string // Name of the secret
+	Field string // Field of the structured secret, empty for the whole value
+}
+
+// String returns the reference as a mellon:// URI.
+func (r Reference) String() string {
+	if r.Field == "" {
+		return RefScheme + r.Name
+	}
+
+	return RefScheme + r.Name + "#" + r.Field
+}
+
+// ParseReference parses a value consisting of a single mellon:// reference. It reports
+// false if the value is anything else.
+func ParseReference(s string) (Reference, bool) {
+	m := reReference.FindStringSubmatchIndex(s)
+	if m == nil || m[0] != 0 || m[1] != len(s) {
+		return Reference{}, false
+	}
+
+	return referenceFromMatch(s, m), true
+}
+
+// ReplaceReferences replaces every mellon:// reference in data with the value returned
+// by resolve. References that cannot be resolved are left untouched, and the error
+// of each of them is passed back once, in the order they appear.
+func ReplaceReferences(data []byte, resolve func(Reference) ([]byte, error)) ([]byte, []error) {
+	var errs []error
+	failed := map[Reference]bool{}
+
+	replaced := reReference.ReplaceAllFunc(data, func(match []byte) []byte {
+		m := reReference.FindSubmatchIndex(match)
+		ref := referenceFromMatch(string(match), m)
+
+		if failed[ref] {
+			return match
+		}
+
+		value, err := resolve(ref)
+		if err != nil {
+			failed[ref] = true
+			errs = append(errs, err)
+			return match
+		}
+
+		return value
+	})
+
+	return replaced, errs
+}
+
+// referenceFromMatch builds a reference from the submatch indexes of reReference.
+func referenceFromMatch(s string, m []int) Reference {
+	ref := Reference{Name: s[m[2]:m[3]]}
+	if m[4] >= 0 {
+		ref.Field = s[m[4]:m[5]]
+	}
+
+	return ref
+}
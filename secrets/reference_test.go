@@ -0,0 +1,52 @@
+package secrets
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReference(t *testing.T) {
+	ref, ok := ParseReference("mellon://prod/db#password")
+	assert.True(t, ok)
+	assert.Equal(t, Reference{Name: "prod/db", Field: "password"}, ref)
+	assert.Equal(t, "mellon://prod/db#password", ref.String())
+
+	ref, ok = ParseReference("mellon://api-key")
+	assert.True(t, ok)
+	assert.Equal(t, Reference{Name: "api-key"}, ref)
+	assert.Equal(t, "mellon://api-key", ref.String())
+
+	for _, s := range []string{"", "api-key", "mellon://", "x mellon://api-key", "mellon://api-key suffix", "https://api-key"} {
+		_, ok := ParseReference(s)
+		assert.False(t, ok, s)
+	}
+}
+
+func TestReplaceReferences(t *testing.T) {
+	values := map[string]string{
+		"mellon://api-key":          "abc",
+		"mellon://prod/db#password": "p@ss",
+		"mellon://prod/db#user":     "app",
+	}
+
+	resolve := func(ref Reference) ([]byte, error) {
+		value, ok := values[ref.String()]
+		if !ok {
+			return nil, errors.New(ref.String())
+		}
+		return []byte(value), nil
+	}
+
+	data := []byte("KEY=mellon://api-key\nDSN=postgres://mellon://prod/db#user:mellon://prod/db#password@host\n")
+	replaced, errs := ReplaceReferences(data, resolve)
+	assert.Empty(t, errs)
+	assert.Equal(t, "KEY=abc\nDSN=postgres://app:p@ss@host\n", string(replaced))
+
+	replaced, errs = ReplaceReferences([]byte("a=mellon://missing b=mellon://api-key c=mellon://prod/db#missing d=mellon://missing"), resolve)
+	assert.Equal(t, "a=mellon://missing b=abc c=mellon://prod/db#missing d=mellon://missing", string(replaced))
+	assert.Len(t, errs, 2)
+	assert.EqualError(t, errs[0], "mellon://missing")
+	assert.EqualError(t, errs[1], "mellon://prod/db#missing")
+}
@@ -0,0 +1,490 @@
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/engmtcdrm/go-entomb"
+)
+
+const (
+	rekeyNewExt     = ".new"   // Extension for the staged key and secrets while rekeying
+	rekeyOldExt     = ".old"   // Extension for the previous key and secrets while rekeying
+	rekeyJournalExt = ".rekey" // Extension for the journal of a rekey, next to the key
+)
+
+// ErrRekeyCleanup is returned when a rekey has finished, the new key and secrets being
+// in place, but the files it replaced could not all be removed. The removal is retried
+// by RecoverRekey.
+var ErrRekeyCleanup = errors.New("rekey finished, but the previous key and secrets could not all be removed")
+
+// States of a rekey recorded in its journal.
+const (
+	rekeyStaging   = "staging"   // The new key and secrets are being written next to the originals
+	rekeySwapping  = "swapping"  // The originals are being replaced, every one of them is kept as .old
+	rekeyCommitted = "committed" // Every original has been replaced, the .old files are being removed
+)
+
+// rekeyJournal records the files a rekey replaces, so a rekey that was interrupted
+// can be rolled back or finished by RecoverRekey.
+type rekeyJournal struct {
+	State string   `json:"state"` // One of rekeyStaging, rekeySwapping or rekeyCommitted
+	Paths []string `json:"paths"` // Encrypted files being replaced, besides the key
+}
+
+// Rekey generates a new encryption key at keyPath and re-encrypts every secret in
+// secretFiles, along with their previous versions and the secrets in the trash and in
+// the snapshots, with it.
+//
+// All secrets are first re-encrypted into staging files next to the originals. Only
+// once every secret has been staged, and synced to disk, are the new key and secrets
+// swapped into place. If anything fails along the way, all changes are rolled back so
+// the secrets are never left encrypted with a mix of the old and new keys. A journal
+// kept next to the key lets RecoverRekey do the same for a rekey that was interrupted.
+func Rekey(keyPath string, secretFiles []Secret) (err error) {
+	if keyPath == "" {
+		return errors.New("key path cannot be empty")
+	}
+
+	// A new rekey would lose track of the files left behind by the previous one
+	if err := RecoverRekey(keyPath); errors.Is(err, ErrRekeyCleanup) {
+		return fmt.Errorf("the previous rekey could not be cleaned up: %s", err)
+	} else if err != nil {
+		return err
+	}
+
+	newKeyPath := keyPath + rekeyNewExt
+
+	locked, err := IsKeyLocked(keyPath)
+	if err != nil {
+		return err
+	}
+
+	targets, err := rekeyTargets(keyPath, secretFiles)
+	if err != nil {
+		return err
+	}
+
+	journal := rekeyJournal{State: rekeyStaging}
+	for _, t := range targets {
+		journal.Paths = append(journal.Paths, t.path)
+	}
+
+	if err := writeRekeyJournal(keyPath, journal); err != nil {
+		return err
+	}
+
+	// Nothing is replaced while staging, so a failure only discards the staged files
+	defer func() {
+		if err != nil && journal.State == rekeyStaging {
+			discardStaged(keyPath, journal.Paths)
+		}
+	}()
+
+	newTomb, err := entomb.NewTomb(newKeyPath, true, true)
+	if err != nil {
+		return fmt.Errorf("could not create new key: %w", err)
+	}
+
+	// A passphrase protected key is replaced by a key protected with the same passphrase
+	if locked {
+		// Unlocking the current key first ensures the passphrase is correct
+		if _, err = openTomb(keyPath); err != nil {
+			return err
+		}
+
+		var pass []byte
+		if pass, err = getPassphrase(); err != nil {
+			return err
+		}
+
+		if err = sealKeyFile(newKeyPath, pass); err != nil {
+			return err
+		}
+	}
+
+	if err = syncFile(newKeyPath); err != nil {
+		return err
+	}
+
+	for _, t := range targets {
+		if err = t.secret.reencrypt(newTomb, t.path, t.path+rekeyNewExt); err != nil {
+			return err
+		}
+	}
+
+	if err = syncDirs(append(journal.Paths, keyPath)); err != nil {
+		return err
+	}
+
+	if err = writeRekeyJournal(keyPath, rekeyJournal{State: rekeySwapping, Paths: journal.Paths}); err != nil {
+		return err
+	}
+	journal.State = rekeySwapping
+
+	if err = commitRekey(keyPath, journal.Paths); err != nil {
+		return err
+	}
+
+	// Everything is in place, from here on the rekey is finished rather than rolled back
+	journal.State = rekeyCommitted
+	if err = writeRekeyJournal(keyPath, journal); err != nil {
+		return err
+	}
+
+	forgetTomb(keyPath)
+
+	return finishRekey(keyPath, journal.Paths)
+}
+
+// rekeyTarget is an encrypted file re-encrypted by Rekey, along with the secret it
+// is a version of.
+type rekeyTarget struct {
+	secret *Secret
+	path   string
+}
+
+// rekeyTargets returns every encrypted file Rekey re-encrypts: the secrets, their
+// previous versions kept in the history, the secrets in the trash with their previous
+// versions, and the secrets in the snapshots.
+func rekeyTargets(keyPath string, secretFiles []Secret) ([]rekeyTarget, error) {
+	var targets []rekeyTarget
+
+	for i := range secretFiles {
+		secret := &secretFiles[i]
+
+		versions, err := secret.previousVersions()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, v := range append(versions, Version{path: secret.path}) {
+			targets = append(targets, rekeyTarget{secret, v.path})
+		}
+	}
+
+	trashed, err := TrashedSecrets()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range trashed {
+		files, err := t.encryptedFiles()
+		if err != nil {
+			return nil, err
+		}
+
+		secret := &Secret{name: t.Name, path: t.path, keyPath: keyPath}
+		for _, path := range files {
+			targets = append(targets, rekeyTarget{secret, path})
+		}
+	}
+
+	snapshots, err := Snapshots()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range snapshots {
+		files, err := s.encryptedFiles()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, path := range files {
+			// Named after the file within the snapshots, for error messages
+			name, err := filepath.Rel(snapshotsPath, path)
+			if err != nil {
+				return nil, err
+			}
+
+			secret := &Secret{name: filepath.ToSlash(name), path: path, keyPath: keyPath}
+			targets = append(targets, rekeyTarget{secret, path})
+		}
+	}
+
+	return targets, nil
+}
+
+// encryptedFiles returns every encrypted file in dir, leaving out the metadata, the
+// file named infoFile that describes dir, and the files staged by a rekey.
+func encryptedFiles(dir string, infoFile string) ([]string, error) {
+	var files []string
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir(), path == filepath.Join(dir, infoFile):
+		case slices.Contains([]string{metaExt, rekeyNewExt, rekeyOldExt}, filepath.Ext(path)):
+		default:
+			files = append(files, path)
+		}
+
+		return nil
+	})
+
+	return files, err
+}
+
+// reencrypt decrypts the version of the secret at path with its current key and
+// writes it encrypted with tomb to stagedPath, synced to disk.
+func (s *Secret) reencrypt(tomb *entomb.Tomb, path string, stagedPath string) error {
+	secret, err := s.decryptFile(path)
+	if err != nil {
+		return err
+	}
+
+	encSecret, err := tomb.Encrypt(secret)
+	ClearSecret(&secret)
+	if err != nil {
+		return fmt.Errorf("could not encrypt secret '%s' with new key: %w", s.name, err)
+	}
+
+	if err := writeSyncedFile(stagedPath, encSecret); err != nil {
+		return fmt.Errorf("could not write re-encrypted secret '%s': %w", s.name, err)
+	}
+
+	return nil
+}
+
+// commitRekey swaps the staged key and secrets into place, keeping the previous
+// files until every swap has succeeded. On failure, every swap already made is undone.
+func commitRekey(keyPath string, paths []string) (err error) {
+	defer func() {
+		if err != nil {
+			if rollbackErr := rollbackRekey(keyPath, paths); rollbackErr != nil {
+				err = fmt.Errorf("%w\n\nrolling back also failed, run the command again to finish rolling back: %w", err, rollbackErr)
+			}
+		}
+	}()
+
+	for _, path := range append([]string{keyPath}, paths...) {
+		if err = os.Rename(path, path+rekeyOldExt); err != nil {
+			return fmt.Errorf("could not back up '%s': %w", path, err)
+		}
+
+		if err = os.Rename(path+rekeyNewExt, path); err != nil {
+			return fmt.Errorf("could not replace '%s': %w", path, err)
+		}
+	}
+
+	return syncDirs(append(paths, keyPath))
+}
+
+// RecoverRekey rolls back or finishes a rekey at keyPath that was interrupted, e.g.
+// by a crash or power loss, as recorded in its journal. A rekey interrupted before its
+// new files were all swapped into place is rolled back, otherwise it is finished. The
+// previous key is never removed while a secret may still need it.
+func RecoverRekey(keyPath string) error {
+	data, err := os.ReadFile(keyPath + rekeyJournalExt)
+	if errors.Is(err, fs.ErrNotExist) {
+		return checkRekeyLeftovers(keyPath)
+	}
+	if err != nil {
+		return fmt.Errorf("could not read journal of interrupted rekey: %w", err)
+	}
+
+	var journal rekeyJournal
+	if err := json.Unmarshal(data, &journal); err != nil {
+		return fmt.Errorf("journal of interrupted rekey '%s' is corrupted, restore the key from '%s' if it exists: %w", keyPath+rekeyJournalExt, keyPath+rekeyOldExt, err)
+	}
+
+	switch journal.State {
+	case rekeyStaging:
+		return discardStaged(keyPath, journal.Paths)
+	case rekeySwapping:
+		return rollbackRekey(keyPath, journal.Paths)
+	case rekeyCommitted:
+		return finishRekey(keyPath, journal.Paths)
+	default:
+		return fmt.Errorf("journal of interrupted rekey '%s' has unknown state '%s'", keyPath+rekeyJournalExt, journal.State)
+	}
+}
+
+// checkRekeyLeftovers handles the files of an interrupted rekey that left no journal.
+// A staged key is removed, as nothing was swapped before it was renamed. A previous
+// key is kept, as it may be the only key able to decrypt some secrets.
+func checkRekeyLeftovers(keyPath string) error {
+	if _, err := os.Stat(keyPath + rekeyOldExt); err == nil {
+		return fmt.Errorf(
+			"found '%s' left by an interrupted rekey. It may be the only key able to decrypt some secrets, so it was kept. "+
+				"Check which key decrypts the secrets and move the other one out of the way before running any command",
+			keyPath+rekeyOldExt,
+		)
+	}
+
+	if err := ShredFile(keyPath + rekeyNewExt); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("could not remove key staged by an interrupted rekey: %w", err)
+	}
+
+	return nil
+}
+
+// discardStaged shreds the staged key and secrets of a rekey that was interrupted or
+// failed before anything was replaced, then removes its journal.
+func discardStaged(keyPath string, paths []string) error {
+	var errs []error
+	for _, path := range append([]string{keyPath}, paths...) {
+		if err := ShredFile(path + rekeyNewExt); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("could not remove files staged by rekey: %w", errors.Join(errs...))
+	}
+
+	return removeRekeyJournal(keyPath)
+}
+
+// rollbackRekey restores the previous key and secrets of a rekey that was interrupted
+// or failed while they were being replaced, then removes its journal. The previous
+// files are all kept until the swap is committed, so every file can be restored.
+func rollbackRekey(keyPath string, paths []string) error {
+	var errs []error
+	for _, path := range slices.Backward(append([]string{keyPath}, paths...)) {
+		if _, err := os.Stat(path + rekeyOldExt); err == nil {
+			if err := os.Rename(path+rekeyOldExt, path); err != nil {
+				errs = append(errs, fmt.Errorf("could not restore '%s': %w", path, err))
+				continue
+			}
+		}
+
+		if err := ShredFile(path + rekeyNewExt); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("could not roll back rekey: %w", errors.Join(errs...))
+	}
+
+	forgetTomb(keyPath)
+
+	if err := syncDirs(append(paths, keyPath)); err != nil {
+		return err
+	}
+
+	return removeRekeyJournal(keyPath)
+}
+
+// finishRekey shreds the previous key and secrets of a rekey whose new files are all
+// in place, then removes its journal.
+func finishRekey(keyPath string, paths []string) error {
+	var errs []error
+	for _, path := range append([]string{keyPath}, paths...) {
+		if err := ShredFile(path + rekeyOldExt); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrRekeyCleanup, errors.Join(errs...))
+	}
+
+	if err := removeRekeyJournal(keyPath); err != nil {
+		return fmt.Errorf("%w: %w", ErrRekeyCleanup, err)
+	}
+
+	return nil
+}
+
+// writeRekeyJournal writes the journal of a rekey at keyPath, replacing the previous
+// one only once it is synced to disk.
+func writeRekeyJournal(keyPath string, journal rekeyJournal) error {
+	data, err := json.MarshalIndent(journal, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	journalPath := keyPath + rekeyJournalExt
+	if err := writeSyncedFile(journalPath+rekeyNewExt, data); err != nil {
+		return fmt.Errorf("could not write journal of rekey: %w", err)
+	}
+
+	if err := os.Rename(journalPath+rekeyNewExt, journalPath); err != nil {
+		return fmt.Errorf("could not write journal of rekey: %w", err)
+	}
+
+	return syncDir(filepath.Dir(journalPath))
+}
+
+// removeRekeyJournal removes the journal of a rekey at keyPath once it is no longer needed.
+func removeRekeyJournal(keyPath string) error {
+	if err := os.Remove(keyPath + rekeyJournalExt); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("could not remove journal of rekey: %w", err)
+	}
+
+	return syncDir(filepath.Dir(keyPath))
+}
+
+// writeSyncedFile writes data to the file at path and syncs it to disk.
+func writeSyncedFile(path string, data []byte) (err error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, secretMode)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+
+	return f.Sync()
+}
+
+// syncFile syncs the file at path to disk.
+func syncFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return f.Sync()
+}
+
+// syncDir syncs the directory dir to disk, so the files created, renamed or removed in
+// it survive a crash. Directories cannot be synced on every platform, e.g. Windows, so
+// failing to sync one is not an error.
+func syncDir(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	f.Sync()
+
+	return nil
+}
+
+// syncDirs syncs the directory of every path to disk.
+func syncDirs(paths []string) error {
+	synced := map[string]bool{}
+	for _, path := range paths {
+		dir := filepath.Dir(path)
+		if synced[dir] {
+			continue
+		}
+
+		if err := syncDir(dir); err != nil {
+			return err
+		}
+		synced[dir] = true
+	}
+
+	return nil
+}
@@ -0,0 +1,189 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// interruptedRekey sets up a rekey of the secret at keyPath interrupted in state,
+// after the key and secret were swapped, and returns the new key and secret.
+func interruptedRekey(t *testing.T, keyPath string, secret *Secret, state string) ([]byte, []byte) {
+	for _, path := range []string{keyPath, secret.path} {
+		assert.NoError(t, os.WriteFile(path+rekeyNewExt, []byte("new "+filepath.Base(path)), secretMode))
+		assert.NoError(t, os.Rename(path, path+rekeyOldExt))
+		assert.NoError(t, os.Rename(path+rekeyNewExt, path))
+	}
+	assert.NoError(t, writeRekeyJournal(keyPath, rekeyJournal{State: state, Paths: []string{secret.path}}))
+	forgetTomb(keyPath)
+
+	newKey, _ := os.ReadFile(keyPath)
+	newSecret, _ := os.ReadFile(secret.path)
+
+	return newKey, newSecret
+}
+
+func TestRecoverRekey(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, ".key")
+
+	secret, err := NewSecret(keyPath, "db", filepath.Join(dir, "db.thurin"))
+	assert.NoError(t, err)
+	assert.NoError(t, secret.Encrypt([]byte("value"), false))
+
+	// Without a journal, there is nothing to recover
+	assert.NoError(t, RecoverRekey(keyPath))
+
+	// A rekey interrupted while swapping is rolled back, the previous key still decrypts the secret
+	interruptedRekey(t, keyPath, secret, rekeySwapping)
+	assert.NoError(t, RecoverRekey(keyPath))
+
+	value, err := secret.Decrypt()
+	assert.NoError(t, err)
+	assert.Equal(t, "value", string(value))
+
+	for _, p := range []string{keyPath + rekeyOldExt, secret.path + rekeyOldExt, keyPath + rekeyJournalExt} {
+		_, err := os.Stat(p)
+		assert.True(t, os.IsNotExist(err), p)
+	}
+
+	// A rekey interrupted once committed is finished, keeping the new files
+	newKey, newSecret := interruptedRekey(t, keyPath, secret, rekeyCommitted)
+	assert.NoError(t, RecoverRekey(keyPath))
+
+	key, _ := os.ReadFile(keyPath)
+	assert.Equal(t, newKey, key)
+	data, _ := os.ReadFile(secret.path)
+	assert.Equal(t, newSecret, data)
+
+	for _, p := range []string{keyPath + rekeyOldExt, secret.path + rekeyOldExt, keyPath + rekeyJournalExt} {
+		_, err := os.Stat(p)
+		assert.True(t, os.IsNotExist(err), p)
+	}
+}
+
+func TestRecoverRekey_Leftovers(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, ".key")
+
+	// A staged key without a journal is removed, nothing was swapped yet
+	assert.NoError(t, os.WriteFile(keyPath+rekeyNewExt, []byte("staged"), secretMode))
+	assert.NoError(t, RecoverRekey(keyPath))
+	_, err := os.Stat(keyPath + rekeyNewExt)
+	assert.True(t, os.IsNotExist(err))
+
+	// A previous key without a journal may be the only key for some secrets, so it is kept
+	assert.NoError(t, os.WriteFile(keyPath+rekeyOldExt, []byte("previous"), secretMode))
+	assert.ErrorContains(t, RecoverRekey(keyPath), "interrupted rekey")
+	_, err = os.Stat(keyPath + rekeyOldExt)
+	assert.NoError(t, err)
+}
+
+func TestRecoverRekey_Cleanup(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, ".key")
+
+	secret, err := NewSecret(keyPath, "db", filepath.Join(dir, "db.thurin"))
+	assert.NoError(t, err)
+	assert.NoError(t, secret.Encrypt([]byte("value"), false))
+
+	// A previous secret that cannot be shredded fails the cleanup, not the rekey
+	newKey, _ := interruptedRekey(t, keyPath, secret, rekeyCommitted)
+	oldPath := secret.path + rekeyOldExt
+	assert.NoError(t, os.Remove(oldPath))
+	assert.NoError(t, os.MkdirAll(filepath.Join(oldPath, "blocked"), dirMode))
+
+	assert.ErrorIs(t, RecoverRekey(keyPath), ErrRekeyCleanup)
+
+	key, _ := os.ReadFile(keyPath)
+	assert.Equal(t, newKey, key)
+
+	// The journal is kept so the cleanup is retried, and no new rekey is started meanwhile
+	_, err = os.Stat(keyPath + rekeyJournalExt)
+	assert.NoError(t, err)
+
+	err = Rekey(keyPath, []Secret{*secret})
+	assert.ErrorContains(t, err, "previous rekey")
+	assert.NotErrorIs(t, err, ErrRekeyCleanup)
+
+	assert.NoError(t, os.RemoveAll(oldPath))
+	assert.NoError(t, RecoverRekey(keyPath))
+}
+
+func TestRekey_Trash(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, ".key")
+	secretsPath := filepath.Join(dir, "secrets")
+
+	SetHistory(filepath.Join(dir, ".history"), 5)
+	defer SetHistory("", 0)
+	SetTrash(filepath.Join(dir, ".trash"))
+	defer SetTrash("")
+
+	secret, err := NewSecret(keyPath, "db", filepath.Join(secretsPath, "db.thurin"))
+	assert.NoError(t, err)
+	assert.NoError(t, secret.Encrypt([]byte("one"), false))
+	assert.NoError(t, secret.Encrypt([]byte("two"), false))
+
+	_, err = TrashSecret(secretsPath, *secret)
+	assert.NoError(t, err)
+
+	assert.NoError(t, Rekey(keyPath, nil))
+
+	restored, err := RestoreTrashed(keyPath, secretsPath, "db")
+	assert.NoError(t, err)
+
+	value, err := restored.Decrypt()
+	assert.NoError(t, err)
+	assert.Equal(t, "two", string(value))
+
+	value, err = restored.DecryptVersion(1)
+	assert.NoError(t, err)
+	assert.Equal(t, "one", string(value))
+}
+
+func TestRekey_Snapshots(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, ".key")
+	secretsPath := filepath.Join(dir, "secrets")
+
+	SetHistory(filepath.Join(dir, ".history"), 5)
+	defer SetHistory("", 0)
+	SetSnapshots(filepath.Join(dir, ".snapshots"), 5, 0)
+	defer SetSnapshots("", 0, 0)
+
+	secret, err := NewSecret(keyPath, "db", filepath.Join(secretsPath, "db.thurin"))
+	assert.NoError(t, err)
+	assert.NoError(t, secret.Encrypt([]byte("one"), false))
+	assert.NoError(t, secret.Encrypt([]byte("two"), false))
+
+	snapshot, err := TakeSnapshot(secretsPath, "delete")
+	assert.NoError(t, err)
+	assert.NoError(t, RemoveSecret(secretsPath, *secret))
+
+	before, _ := os.ReadFile(filepath.Join(snapshot.path, snapshotSecretsDir, "db.thurin"))
+
+	assert.NoError(t, Rekey(keyPath, nil))
+
+	// The secrets in the snapshot are re-encrypted, and no key is kept alongside them
+	after, _ := os.ReadFile(filepath.Join(snapshot.path, snapshotSecretsDir, "db.thurin"))
+	assert.NotEqual(t, before, after)
+
+	entries, err := os.ReadDir(snapshot.path)
+	assert.NoError(t, err)
+	for _, entry := range entries {
+		assert.Contains(t, []string{snapshotInfoFile, snapshotSecretsDir, snapshotHistoryDir}, entry.Name())
+	}
+
+	assert.NoError(t, RestoreSnapshot(secretsPath, snapshot.ID))
+
+	value, err := secret.Decrypt()
+	assert.NoError(t, err)
+	assert.Equal(t, "two", string(value))
+
+	value, err = secret.DecryptVersion(1)
+	assert.NoError(t, err)
+	assert.Equal(t, "one", string(value))
+}
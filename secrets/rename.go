@@ -0,0 +1,146 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// RenameSecret moves a secret, its metadata and its history to newName. Directories
+// left empty by the move are removed, the same way RemoveSecret does.
+func RenameSecret(secretsPath string, secret Secret, newName string) (*Secret, error) {
+	renamed, err := secret.relocate(secretsPath, newName, true)
+	if err != nil {
+		return nil, fmt.Errorf("could not rename secret '%s' to '%s': %w", secret.name, newName, err)
+	}
+
+	if err := removeEmptyDirs(secretsPath, filepath.Dir(secret.path)); err != nil {
+		return renamed, err
+	}
+
+	if historyPath != "" {
+		if err := removeEmptyDirs(historyPath, secret.historyDir()); err != nil {
+			return renamed, err
+		}
+	}
+
+	return renamed, nil
+}
+
+// CopySecret copies a secret, its metadata and its history to newName. The copy is
+// recorded as created now, but keeps when its value was last rotated.
+func CopySecret(secretsPath string, secret Secret, newName string) (*Secret, error) {
+	copied, err := secret.relocate(secretsPath, newName, false)
+	if err != nil {
+		return nil, fmt.Errorf("could not copy secret '%s' to '%s': %w", secret.name, newName, err)
+	}
+
+	meta, err := copied.Metadata()
+	if err != nil {
+		return copied, err
+	}
+
+	meta.CreatedAt = time.Time{}
+	if err := copied.SaveMetadata(meta); err != nil {
+		return copied, err
+	}
+
+	return copied, nil
+}
+
+// relocate moves or copies the encrypted files of the secret to newName, without
+// decrypting anything. If any transfer fails, the transfers already made are undone.
+func (s *Secret) relocate(secretsPath string, newName string, move bool) (target *Secret, err error) {
+	if newName == s.name {
+		return nil, errors.New("source and destination are the same")
+	}
+
+	target, err = NewSecret(s.keyPath, newName, filepath.Join(secretsPath, newName+filepath.Ext(s.path)))
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(target.path); err == nil {
+		return nil, fmt.Errorf("secret '%s' %w", newName, ErrExists)
+	}
+
+	versions, err := s.previousVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	transfers := [][2]string{{s.path, target.path}}
+
+	if _, err := os.Stat(s.MetaPath()); err == nil {
+		transfers = append(transfers, [2]string{s.MetaPath(), target.MetaPath()})
+	}
+
+	for _, v := range versions {
+		dst := Version{path: filepath.Join(target.historyDir(), filepath.Base(v.path))}
+		transfers = append(transfers, [2]string{v.path, dst.path})
+
+		if _, err := os.Stat(v.metaPath()); err == nil {
+			transfers = append(transfers, [2]string{v.metaPath(), dst.metaPath()})
+		}
+	}
+
+	transfer := copyFile
+	if move {
+		transfer = moveFile
+	}
+
+	var done [][2]string
+	defer func() {
+		if err != nil {
+			for i := len(done) - 1; i >= 0; i-- {
+				if move {
+					os.Rename(done[i][1], done[i][0])
+				} else {
+					os.Remove(done[i][1])
+				}
+			}
+		}
+	}()
+
+	for _, t := range transfers {
+		if err = transfer(t[0], t[1]); err != nil {
+			return nil, err
+		}
+		done = append(done, t)
+	}
+
+	return target, nil
+}
+
+// moveFile moves the file at src to dst, creating the directories of dst as needed.
+func moveFile(src string, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), dirMode); err != nil {
+		return fmt.Errorf("could not create directory '%s': %w", filepath.Dir(dst), err)
+	}
+
+	if err := os.Rename(src, dst); err != nil {
+		return fmt.Errorf("could not move '%s': %w", src, err)
+	}
+
+	return nil
+}
+
+// copyFile copies the file at src to dst, creating the directories of dst as needed.
+func copyFile(src string, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return fmt.Errorf("could not read '%s': %w", src, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), dirMode); err != nil {
+		return fmt.Errorf("could not create directory '%s': %w", filepath.Dir(dst), err)
+	}
+
+	if err := os.WriteFile(dst, data, secretMode); err != nil {
+		return fmt.Errorf("could not write '%s': %w", dst, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenameCopySecret(t *testing.T) {
+	dir := t.TempDir()
+	secretsPath := filepath.Join(dir, "secrets")
+	historyDir := filepath.Join(dir, ".history")
+
+	SetHistory(historyDir, 5)
+	defer SetHistory("", 0)
+
+	secret, err := NewSecret(filepath.Join(dir, ".key"), "prod/db/password", filepath.Join(secretsPath, "prod", "db", "password.thurin"))
+	assert.NoError(t, err)
+	assert.NoError(t, secret.Encrypt([]byte("one"), false))
+	assert.NoError(t, secret.Encrypt([]byte("two"), false))
+
+	meta, err := secret.Metadata()
+	assert.NoError(t, err)
+	meta.Owner = "ops"
+	assert.NoError(t, secret.SaveMetadata(meta))
+
+	copied, err := CopySecret(secretsPath, *secret, "staging/password")
+	assert.NoError(t, err)
+
+	renamed, err := RenameSecret(secretsPath, *secret, "production/password")
+	assert.NoError(t, err)
+
+	for _, s := range []*Secret{copied, renamed} {
+		value, err := s.Decrypt()
+		assert.NoError(t, err)
+		assert.Equal(t, "two", string(value))
+
+		value, err = s.DecryptVersion(1)
+		assert.NoError(t, err)
+		assert.Equal(t, "one", string(value))
+
+		meta, err := s.Metadata()
+		assert.NoError(t, err)
+		assert.Equal(t, "ops", meta.Owner)
+	}
+
+	// The renamed secret and its history leave no empty directories behind
+	_, err = os.Stat(filepath.Join(secretsPath, "prod"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(historyDir, "prod"))
+	assert.True(t, os.IsNotExist(err))
+
+	// Existing secrets are never overwritten
+	_, err = CopySecret(secretsPath, *copied, "production/password")
+	assert.Error(t, err)
+	_, err = RenameSecret(secretsPath, *copied, "staging/password")
+	assert.Error(t, err)
+	_, err = RenameSecret(secretsPath, *copied, "invalid name")
+	assert.Error(t, err)
+}
@@ -4,18 +4,25 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
+	"time"
 
-	"github.com/engmtcdrm/go-entomb"
 	"github.com/engmtcdrm/mellon/env"
 )
 
+var (
+	// ErrNotFound is returned when a secret, or a version or field of it, does not exist.
+	ErrNotFound = errors.New("does not exist")
+
+	// ErrExists is returned when a secret cannot be written as one already exists.
+	ErrExists = errors.New("already exists")
+)
+
 // Secret represents a secret value stored in the system.
 type Secret struct {
-	name string
-	path string
-	tomb *entomb.Tomb
+	name    string
+	path    string
+	keyPath string
 }
 
 // NewSecret creates a new secret with the given key path, name, and path.
@@ -32,15 +39,10 @@ func NewSecret(keyPath string, name string, path string) (*Secret, error) {
 		return nil, errors.New("path cannot be empty")
 	}
 
-	tomb, err := entomb.NewTomb(keyPath, true, true)
-	if err != nil {
-		return nil, fmt.Errorf("could not create tomb: %w", err)
-	}
-
 	return &Secret{
-		name: name,
-		path: path,
-		tomb: tomb,
+		name:    name,
+		path:    path,
+		keyPath: keyPath,
 	}, nil
 }
 
@@ -55,10 +57,9 @@ func (s *Secret) Path() string {
 }
 
 // EncryptFromFile reads a secret from a file, trims leading and trailing whitespace
-// and encrypts it before writing it to the secret's path.
-func (s *Secret) EncryptFromFile(file string, cleanup bool) error {
-	var encSecret []byte
-
+// and encrypts it before writing it to the secret's path. With raw, the contents of
+// the file are encrypted exactly as read.
+func (s *Secret) EncryptFromFile(file string, cleanup bool, raw bool) error {
 	rawFile, err := env.ExpandTilde(strings.TrimSpace(file))
 	if err != nil {
 		return err
@@ -69,68 +70,186 @@ func (s *Secret) EncryptFromFile(file string, cleanup bool) error {
 		return fmt.Errorf("could not read file '%s': %w", rawFile, err)
 	}
 
-	secretBytes = trimSpaceBytes(&secretBytes)
-	encSecret, err = s.tomb.Encrypt(secretBytes)
+	value := secretBytes
+	if !raw {
+		value = trimSpaceBytes(&secretBytes)
+	}
+
+	err = s.writeSecret(value, "", raw)
 	ClearSecret(&secretBytes)
 	if err != nil {
 		return err
 	}
 
-	err = os.MkdirAll(filepath.Dir(s.path), dirMode)
+	if cleanup {
+		return CleanupFile(rawFile)
+	}
+
+	return nil
+}
+
+// Encrypt encrypts a secret and writes it to the secret's path.
+// The secret is trimmed of leading and trailing whitespace before encryption, unless
+// raw is set in which case it is encrypted exactly as given.
+func (s *Secret) Encrypt(secret []byte, raw bool) error {
+	value := secret
+	if !raw {
+		value = trimSpaceBytes(&secret)
+	}
+
+	err := s.writeSecret(value, "", raw)
+	ClearSecret(&secret)
+
+	return err
+}
+
+// Decrypt reads the encrypted secret from the file and decrypts it. Expired secrets
+// are refused with ErrExpired, unless allowed with SetAllowExpired.
+//
+// The expiry enforced is the one sealed inside the encrypted value, so it cannot be
+// lifted by editing the metadata. Only values encrypted before expiries were sealed
+// fall back to the expiry in the metadata.
+func (s *Secret) Decrypt() ([]byte, error) {
+	value, _, err := s.decryptUnexpired()
+	return value, err
+}
+
+// decryptUnexpired decrypts the current value of the secret as Decrypt does, and
+// returns it along with its expiry.
+func (s *Secret) decryptUnexpired() ([]byte, time.Time, error) {
+	value, expiresAt, err := s.decryptCurrent()
 	if err != nil {
-		return fmt.Errorf("could not create directory for secret '%s': %w", s.name, err)
+		return nil, time.Time{}, err
 	}
 
-	if err = os.WriteFile(s.path, encSecret, secretMode); err != nil {
-		return err
+	if err := s.checkExpiry(expiresAt); err != nil {
+		ClearSecret(&value)
+		return nil, time.Time{}, err
 	}
 
-	if cleanup {
-		if err = os.Remove(rawFile); err != nil {
-			return fmt.Errorf("could not remove file '%s': %w", rawFile, err)
-		}
+	return value, expiresAt, nil
+}
+
+// checkExpiry returns an error wrapping ErrExpired if expiresAt has passed, unless
+// expired secrets are allowed with SetAllowExpired.
+func (s *Secret) checkExpiry(expiresAt time.Time) error {
+	if !allowExpired && isExpired(expiresAt, time.Now()) {
+		return fmt.Errorf("failed to read secret '%s': %w on %s", s.name, ErrExpired, expiresAt.Local().Format("2006-01-02 15:04"))
 	}
 
 	return nil
 }
 
-// Encrypt encrypts a secret and writes it to the secret's path.
-// The secret is trimmed of leading and trailing whitespace before encryption.
-func (s *Secret) Encrypt(secret []byte) error {
-	encSecret, err := s.tomb.Encrypt(trimSpaceBytes(&secret))
-	ClearSecret(&secret)
+// decryptCurrent decrypts the current value of the secret, regardless of whether it
+// has expired, and returns it along with its expiry.
+func (s *Secret) decryptCurrent() ([]byte, time.Time, error) {
+	data, err := s.decryptFile(s.path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	value, expiresAt, sealed := openExpiry(data)
+	if !sealed {
+		meta, err := s.Metadata()
+		if err != nil {
+			ClearSecret(&data)
+			return nil, time.Time{}, err
+		}
+		expiresAt = meta.ExpiresAt
+	}
+
+	return value, expiresAt, nil
+}
+
+// decryptValue decrypts the version of the secret at path, leaving out the expiry
+// sealed with it.
+func (s *Secret) decryptValue(path string) ([]byte, error) {
+	data, err := s.decryptFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	value, _, _ := openExpiry(data)
+
+	return value, nil
+}
+
+// encryptValue encrypts value with expiresAt sealed in front of it.
+func (s *Secret) encryptValue(value []byte, expiresAt time.Time) ([]byte, error) {
+	tomb, err := openTomb(s.keyPath)
 	if err != nil {
+		return nil, err
+	}
+
+	data := sealExpiry(value, expiresAt)
+	defer ClearSecret(&data)
+
+	return tomb.Encrypt(data)
+}
+
+// replaceSecretFile atomically replaces the encrypted value of the secret, so an
+// interrupted write never loses it.
+func (s *Secret) replaceSecretFile(encSecret []byte) error {
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, encSecret, secretMode); err != nil {
 		return err
 	}
 
-	err = os.MkdirAll(filepath.Dir(s.path), dirMode)
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return nil
+}
+
+// resealExpiry encrypts the value of the secret again with expiresAt sealed in it.
+// This is not a change of value, so it is neither kept in the history nor recorded
+// as a rotation. Nothing is done if the secret has no value yet.
+func (s *Secret) resealExpiry(expiresAt time.Time) error {
+	if _, err := os.Stat(s.path); os.IsNotExist(err) {
+		return nil
+	}
+
+	value, err := s.decryptValue(s.path)
 	if err != nil {
-		return fmt.Errorf("could not create directory for secret '%s': %w", s.name, err)
+		return err
 	}
+	defer ClearSecret(&value)
 
-	if err = os.WriteFile(s.path, encSecret, secretMode); err != nil {
+	encSecret, err := s.encryptValue(value, expiresAt)
+	if err != nil {
 		return err
 	}
 
+	if err := s.replaceSecretFile(encSecret); err != nil {
+		return fmt.Errorf("could not store expiry of secret '%s': %w", s.name, err)
+	}
+
 	return nil
 }
 
-// Decrypt reads the encrypted secret from the file and decrypts it.
-func (s *Secret) Decrypt() ([]byte, error) {
-	data, err := os.ReadFile(s.path)
+// decryptFile reads an encrypted version of the secret from path and decrypts it.
+func (s *Secret) decryptFile(path string) ([]byte, error) {
+	tomb, err := openTomb(s.keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsPermission(err) {
 			return nil, fmt.Errorf("failed to read secret '%s': permission denied", s.name)
 		}
 
 		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("failed to read secret '%s': secret does not exist", s.name)
+			return nil, fmt.Errorf("failed to read secret '%s': secret %w", s.name, ErrNotFound)
 		}
 
 		return nil, err
 	}
 
-	secret, err := s.tomb.Decrypt(data)
+	secret, err := tomb.Decrypt(data)
 	ClearSecret(&data)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decrypt secret '%s'. Encrypted secret may be corrupted", s.name)
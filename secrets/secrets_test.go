@@ -1,6 +1,9 @@
 package secrets
 
 import (
+	"bytes"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -19,3 +22,34 @@ func TestValidateName(t *testing.T) {
 	assert.Error(t, ValidateName("invalid name"))
 	assert.Error(t, ValidateName("invalid.name"))
 }
+
+func TestEncryptRaw(t *testing.T) {
+	dir := t.TempDir()
+	value := []byte("-----BEGIN KEY-----\n\x00\xff\n-----END KEY-----\n")
+
+	secret, err := NewSecret(filepath.Join(dir, ".key"), "raw", filepath.Join(dir, "raw.thurin"))
+	assert.NoError(t, err)
+
+	assert.NoError(t, secret.Encrypt(bytes.Clone(value), true))
+
+	decrypted, err := secret.Decrypt()
+	assert.NoError(t, err)
+	assert.Equal(t, value, decrypted)
+
+	meta, err := secret.Metadata()
+	assert.NoError(t, err)
+	assert.True(t, meta.Raw)
+
+	// Without raw, surrounding whitespace is trimmed
+	file := filepath.Join(dir, "value.txt")
+	assert.NoError(t, os.WriteFile(file, value, 0600))
+	assert.NoError(t, secret.EncryptFromFile(file, false, false))
+
+	decrypted, err = secret.Decrypt()
+	assert.NoError(t, err)
+	assert.Equal(t, bytes.TrimSpace(value), decrypted)
+
+	meta, err = secret.Metadata()
+	assert.NoError(t, err)
+	assert.False(t, meta.Raw)
+}
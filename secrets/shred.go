@@ -0,0 +1,176 @@
+package secrets
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/engmtcdrm/mellon/env"
+)
+
+const shredChunkSize = 32 * 1024 // Bytes of random data written at a time when overwriting a file
+
+var (
+	shredPasses   = 3                 // Number of times a file is overwritten before it is removed
+	shredWarnFunc func(reason string) // Called when overwriting files cannot be relied on
+	shredWarned   = map[string]bool{} // Reasons already reported, so each is reported once
+)
+
+// SetShredPasses sets how many times files are overwritten with random data before
+// they are removed. With 0, files are only truncated and renamed before being removed.
+func SetShredPasses(passes int) {
+	shredPasses = passes
+}
+
+// SetShredWarnFunc sets the function called when a file is shredded on a filesystem
+// where overwriting it does not destroy its original contents. Each reason is
+// reported once.
+func SetShredWarnFunc(fn func(reason string)) {
+	shredWarnFunc = fn
+}
+
+// ShredFile overwrites the contents of the file at path with random data, syncing it
+// to disk after each pass, then truncates it, renames it to a random name and removes
+// it, so neither its contents nor its name are left behind. Symbolic links are removed
+// without touching their target.
+//
+// Overwriting cannot destroy the original contents on copy-on-write filesystems such
+// as btrfs or ZFS, nor on tmpfs which may have swapped them to disk. The file is still
+// removed, and the function set with SetShredWarnFunc is called to report it.
+func ShredFile(path string) error {
+	if reason := shredIneffective(path); reason != "" && shredWarnFunc != nil && !shredWarned[reason] {
+		shredWarned[reason] = true
+		shredWarnFunc(reason)
+	}
+
+	return shredFile(path)
+}
+
+// shredIneffective returns why overwriting the file at path cannot be relied on to
+// destroy its contents, or an empty string if it can.
+func shredIneffective(path string) string {
+	dir := filepath.Dir(path)
+
+	switch fs := env.Filesystem(dir); fs {
+	case "":
+		return ""
+	case "tmpfs", "ramfs":
+		return fmt.Sprintf("'%s' is on %s, which keeps files in memory and may have swapped them to disk where overwriting cannot reach them", dir, fs)
+	default:
+		return fmt.Sprintf("'%s' is on %s, a copy-on-write filesystem where overwriting a file writes new blocks and leaves its original contents on disk", dir, fs)
+	}
+}
+
+// shredFile shreds the file at path like ShredFile, without reporting filesystems
+// where overwriting is not effective. It is meant for files that are expected to be
+// in memory, such as temporary copies of the key.
+func shredFile(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return fmt.Errorf("could not shred file '%s': %w", path, err)
+	}
+
+	if info.Mode().IsRegular() {
+		// A file that cannot be overwritten, e.g. because it is read-only, is still
+		// removed rather than left behind in full
+		if err := overwriteFile(path, info.Size()); err != nil {
+			if removeErr := os.Remove(path); removeErr != nil {
+				return fmt.Errorf("could not shred file '%s': %w", path, err)
+			}
+			return fmt.Errorf("file '%s' was removed without being overwritten: %w", path, err)
+		}
+
+		// Hide the original name before removing the file
+		if renamed, err := randomName(filepath.Dir(path)); err == nil && os.Rename(path, renamed) == nil {
+			path = renamed
+		}
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("could not remove file '%s': %w", path, err)
+	}
+
+	return nil
+}
+
+// overwriteFile overwrites the first size bytes of the file at path with random data
+// for each pass, then truncates it. Every change is synced to disk before the next.
+func overwriteFile(path string, size int64) (err error) {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	buf := make([]byte, shredChunkSize)
+	for range shredPasses {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+
+		for remaining := size; remaining > 0; {
+			chunk := buf[:min(remaining, int64(len(buf)))]
+			if _, err := rand.Read(chunk); err != nil {
+				return err
+			}
+			if _, err := f.Write(chunk); err != nil {
+				return err
+			}
+			remaining -= int64(len(chunk))
+		}
+
+		if err := f.Sync(); err != nil {
+			return err
+		}
+	}
+
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+
+	return f.Sync()
+}
+
+// randomName returns a path in dir with a random name that does not exist yet.
+func randomName(dir string) (string, error) {
+	b := make([]byte, 8)
+	for {
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+
+		path := filepath.Join(dir, "."+hex.EncodeToString(b))
+		if _, err := os.Lstat(path); errors.Is(err, fs.ErrNotExist) {
+			return path, nil
+		}
+	}
+}
+
+// shredTree shreds every file in dir with shred, then removes dir and its subdirectories.
+func shredTree(dir string, shred func(path string) error) error {
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		return shred(path)
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return os.RemoveAll(dir)
+}
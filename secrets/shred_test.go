@@ -0,0 +1,79 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShredFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "plain.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("hunter2"), secretMode))
+
+	// A second link to the same file shows what is left of its contents
+	link := filepath.Join(t.TempDir(), "link")
+	if err := os.Link(path, link); err != nil {
+		t.Skipf("hard links are not supported: %v", err)
+	}
+
+	assert.NoError(t, ShredFile(path))
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries, "no file should be left behind, not even under another name")
+
+	data, err := os.ReadFile(link)
+	assert.NoError(t, err)
+	assert.Empty(t, data)
+
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+	assert.Error(t, ShredFile(path))
+
+	// Without passes, files are still truncated and removed
+	SetShredPasses(0)
+	defer SetShredPasses(3)
+
+	assert.NoError(t, os.WriteFile(path, []byte("hunter2"), secretMode))
+	assert.NoError(t, ShredFile(path))
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestShredFileSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.txt")
+	symlink := filepath.Join(dir, "symlink")
+
+	assert.NoError(t, os.WriteFile(target, []byte("keep me"), secretMode))
+	if err := os.Symlink(target, symlink); err != nil {
+		t.Skipf("symbolic links are not supported: %v", err)
+	}
+
+	assert.NoError(t, ShredFile(symlink))
+
+	_, err := os.Lstat(symlink)
+	assert.True(t, os.IsNotExist(err))
+
+	data, err := os.ReadFile(target)
+	assert.NoError(t, err)
+	assert.Equal(t, "keep me", string(data))
+}
+
+func TestShredTree(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "tree")
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "nested"), dirMode))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "a"), []byte("a"), secretMode))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "nested", "b"), []byte("b"), secretMode))
+
+	assert.NoError(t, shredTree(dir, ShredFile))
+
+	_, err := os.Stat(dir)
+	assert.True(t, os.IsNotExist(err))
+
+	// A missing directory has nothing to shred
+	assert.NoError(t, shredTree(dir, ShredFile))
+}
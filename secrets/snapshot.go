@@ -0,0 +1,377 @@
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	snapshotInfoFile   = "snapshot.json" // File describing a snapshot, within its directory
+	snapshotSecretsDir = "secrets"       // Copy of the secrets, within a snapshot
+	snapshotHistoryDir = "history"       // Copy of the history, within a snapshot
+	snapshotStagingExt = ".restoring"    // Extension for the directories staged while restoring a snapshot
+)
+
+var (
+	snapshotsPath     string        // Where automatic snapshots are stored
+	snapshotRetention int           // Number of snapshots kept
+	snapshotMaxAge    time.Duration // How long snapshots are kept, 0 keeps them regardless of age
+)
+
+// Snapshot is a copy of the secrets and their history taken automatically before a
+// destructive operation. The encryption key is not part of it: the secrets in a
+// snapshot are re-encrypted along with the others when rekeying, so they always
+// decrypt with the current key.
+type Snapshot struct {
+	ID        string    `json:"id"`         // Identifier of the snapshot, based on when it was taken
+	CreatedAt time.Time `json:"created_at"` // When the snapshot was taken
+	Reason    string    `json:"reason"`     // Operation the snapshot was taken before, e.g. delete
+	Secrets   int       `json:"secrets"`    // Number of secrets in the snapshot
+	path      string    // Directory of the snapshot
+}
+
+// SetSnapshots sets the directory where snapshots are stored, how many are kept and
+// for how long. A retention of 0 disables snapshots, and a maxAge of 0 keeps them
+// regardless of age.
+func SetSnapshots(path string, retention int, maxAge time.Duration) {
+	snapshotsPath = path
+	snapshotRetention = retention
+	snapshotMaxAge = maxAge
+}
+
+// TakeSnapshot copies the secrets in secretsPath and their history into a new
+// snapshot, then prunes snapshots beyond the retention. Nothing is done and nil is
+// returned when snapshots are disabled.
+func TakeSnapshot(secretsPath string, reason string) (snapshot *Snapshot, err error) {
+	if snapshotsPath == "" || snapshotRetention <= 0 {
+		return nil, nil
+	}
+
+	now := time.Now()
+	snapshot = &Snapshot{CreatedAt: now.UTC(), Reason: reason}
+
+	if snapshot.ID, err = createTimestampDir(snapshotsPath, now); err != nil {
+		return nil, fmt.Errorf("could not create snapshot: %w", err)
+	}
+	snapshot.path = filepath.Join(snapshotsPath, snapshot.ID)
+
+	defer func() {
+		if err != nil {
+			os.RemoveAll(snapshot.path)
+		}
+	}()
+
+	if snapshot.Secrets, err = copyTree(secretsPath, filepath.Join(snapshot.path, snapshotSecretsDir)); err != nil {
+		return nil, fmt.Errorf("could not take snapshot: %w", err)
+	}
+
+	if historyPath != "" {
+		if _, err = copyTree(historyPath, filepath.Join(snapshot.path, snapshotHistoryDir)); err != nil {
+			return nil, fmt.Errorf("could not take snapshot: %w", err)
+		}
+	}
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	if err = os.WriteFile(filepath.Join(snapshot.path, snapshotInfoFile), data, secretMode); err != nil {
+		return nil, fmt.Errorf("could not write snapshot: %w", err)
+	}
+
+	if err := pruneSnapshots(now); err != nil {
+		return snapshot, err
+	}
+
+	return snapshot, nil
+}
+
+// Snapshots returns every snapshot, oldest first.
+func Snapshots() ([]Snapshot, error) {
+	if snapshotsPath == "" {
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(snapshotsPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("could not read snapshots: %w", err)
+	}
+
+	var snapshots []Snapshot
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(snapshotsPath, entry.Name())
+
+		// Directories without a description are incomplete snapshots
+		data, err := os.ReadFile(filepath.Join(path, snapshotInfoFile))
+		if err != nil {
+			continue
+		}
+
+		var s Snapshot
+		if err := json.Unmarshal(data, &s); err != nil || s.ID != entry.Name() {
+			continue
+		}
+		s.path = path
+
+		snapshots = append(snapshots, s)
+	}
+
+	slices.SortFunc(snapshots, func(a, b Snapshot) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	return snapshots, nil
+}
+
+// FindSnapshot returns the snapshot with the given ID.
+func FindSnapshot(id string) (Snapshot, error) {
+	snapshots, err := Snapshots()
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	for _, s := range snapshots {
+		if s.ID == id {
+			return s, nil
+		}
+	}
+
+	return Snapshot{}, fmt.Errorf("snapshot '%s' %w", id, ErrNotFound)
+}
+
+// RestoreSnapshot replaces the secrets in secretsPath and their history with the
+// contents of the snapshot. The state being replaced is kept in a
+// new snapshot first, so restoring a snapshot can itself be undone.
+//
+// The contents of the snapshot are staged next to the current directories before
+// anything is replaced, and every replacement is undone if one of them fails.
+func RestoreSnapshot(secretsPath string, id string) (err error) {
+	snapshot, err := FindSnapshot(id)
+	if err != nil {
+		return err
+	}
+
+	dirs := [][2]string{{filepath.Join(snapshot.path, snapshotSecretsDir), secretsPath}}
+	if historyPath != "" {
+		dirs = append(dirs, [2]string{filepath.Join(snapshot.path, snapshotHistoryDir), historyPath})
+	}
+
+	var staged []string
+	defer func() {
+		for _, p := range staged {
+			os.RemoveAll(p)
+		}
+	}()
+
+	for _, d := range dirs {
+		stagingPath := d[1] + snapshotStagingExt
+		if err := os.RemoveAll(stagingPath); err != nil {
+			return fmt.Errorf("could not remove stale directory '%s': %w", stagingPath, err)
+		}
+		staged = append(staged, stagingPath)
+
+		if _, err := copyTree(d[0], stagingPath); err != nil {
+			return fmt.Errorf("could not restore snapshot '%s': %w", id, err)
+		}
+	}
+
+	// Taken only once the snapshot is staged, as pruning may remove the snapshot restored
+	if _, err := TakeSnapshot(secretsPath, "snapshots restore"); err != nil {
+		return err
+	}
+
+	var undo []func()
+	defer func() {
+		if err != nil {
+			for i := len(undo) - 1; i >= 0; i-- {
+				undo[i]()
+			}
+		}
+	}()
+
+	for _, d := range dirs {
+		target := d[1]
+		oldPath := target + rekeyOldExt
+
+		if err := os.RemoveAll(oldPath); err != nil {
+			return fmt.Errorf("could not remove stale directory '%s': %w", oldPath, err)
+		}
+
+		if err = os.Rename(target, oldPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("could not restore snapshot '%s': %w", id, err)
+		}
+		undo = append(undo, func() { os.RemoveAll(target); os.Rename(oldPath, target) })
+
+		if err = os.Rename(target+snapshotStagingExt, target); err != nil {
+			return fmt.Errorf("could not restore snapshot '%s': %w", id, err)
+		}
+	}
+
+	for _, d := range dirs {
+		os.RemoveAll(d[1] + rekeyOldExt)
+	}
+
+	return nil
+}
+
+// PurgeFromSnapshots shreds every copy of the secret with the given name, along with
+// its metadata and previous versions, from the snapshots taken up to before, or from
+// every snapshot if before is zero. A purged secret cannot then be brought back by
+// restoring a snapshot.
+func PurgeFromSnapshots(name string, before time.Time) error {
+	snapshots, err := Snapshots()
+	if err != nil {
+		return err
+	}
+
+	for _, s := range snapshots {
+		if !before.IsZero() && s.CreatedAt.After(before) {
+			continue
+		}
+
+		if err := s.purge(name); err != nil {
+			return fmt.Errorf("could not remove secret '%s' from snapshot '%s': %w", name, s.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// purge shreds the secret with the given name, its metadata and previous versions
+// from the snapshot. The secrets of nested namespaces are left untouched.
+func (s Snapshot) purge(name string) error {
+	secretsDir := filepath.Join(s.path, snapshotSecretsDir)
+
+	// The secret keeps the file extension it was stored with
+	files, err := filepath.Glob(filepath.Join(secretsDir, name) + ".*")
+	if err != nil {
+		return err
+	}
+
+	historyDir := filepath.Join(s.path, snapshotHistoryDir, name)
+	entries, err := os.ReadDir(historyDir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			files = append(files, filepath.Join(historyDir, entry.Name()))
+		}
+	}
+
+	if len(files) == 0 {
+		return nil
+	}
+
+	for _, f := range files {
+		if err := ShredFile(f); err != nil {
+			return err
+		}
+
+		if filepath.Dir(f) == filepath.Dir(filepath.Join(secretsDir, name)) && filepath.Ext(f) != metaExt {
+			s.Secrets--
+		}
+	}
+
+	if err := removeEmptyDirs(secretsDir, filepath.Dir(filepath.Join(secretsDir, name))); err != nil {
+		return err
+	}
+
+	if err := removeEmptyDirs(filepath.Join(s.path, snapshotHistoryDir), historyDir); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(s.path, snapshotInfoFile), data, secretMode)
+}
+
+// encryptedFiles returns the encrypted value of every secret in the snapshot and of
+// each of their previous versions.
+func (s Snapshot) encryptedFiles() ([]string, error) {
+	files, err := encryptedFiles(s.path, snapshotInfoFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read snapshot '%s': %w", s.ID, err)
+	}
+
+	return files, nil
+}
+
+// pruneSnapshots shreds the oldest snapshots beyond the retention, as well as the
+// snapshots older than the maximum age.
+func pruneSnapshots(now time.Time) error {
+	snapshots, err := Snapshots()
+	if err != nil {
+		return err
+	}
+
+	for i, s := range snapshots {
+		expired := snapshotMaxAge > 0 && now.Sub(s.CreatedAt) > snapshotMaxAge
+		if len(snapshots)-i <= snapshotRetention && !expired {
+			continue
+		}
+
+		if err := shredTree(s.path, ShredFile); err != nil {
+			return fmt.Errorf("could not remove snapshot '%s': %w", s.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// copyTree copies every file in src to dst, creating dst even if src does not exist.
+// It returns the number of secrets copied, i.e. files that are not metadata.
+func copyTree(src string, dst string) (int, error) {
+	if err := os.MkdirAll(dst, dirMode); err != nil {
+		return 0, fmt.Errorf("could not create directory '%s': %w", dst, err)
+	}
+
+	count := 0
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && path == src {
+				return filepath.SkipAll
+			}
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		if filepath.Ext(path) != metaExt {
+			count++
+		}
+
+		return copyFile(path, filepath.Join(dst, rel))
+	})
+
+	return count, err
+}
@@ -0,0 +1,137 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, ".key")
+	secretsPath := filepath.Join(dir, "secrets")
+
+	SetHistory(filepath.Join(dir, ".history"), 5)
+	defer SetHistory("", 0)
+
+	// Snapshots are disabled without a retention
+	SetSnapshots(filepath.Join(dir, ".snapshots"), 0, 0)
+	defer SetSnapshots("", 0, 0)
+
+	snapshot, err := TakeSnapshot(secretsPath, "delete")
+	assert.NoError(t, err)
+	assert.Nil(t, snapshot)
+
+	SetSnapshots(filepath.Join(dir, ".snapshots"), 2, 0)
+
+	secret, err := NewSecret(keyPath, "prod/db", filepath.Join(secretsPath, "prod", "db.thurin"))
+	assert.NoError(t, err)
+	assert.NoError(t, secret.Encrypt([]byte("one"), false))
+	assert.NoError(t, secret.Encrypt([]byte("two"), false))
+
+	snapshot, err = TakeSnapshot(secretsPath, "delete")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, snapshot.Secrets)
+
+	assert.NoError(t, RemoveSecret(secretsPath, *secret))
+
+	// The deleted secret comes back along with its history
+	assert.NoError(t, RestoreSnapshot(secretsPath, snapshot.ID))
+
+	value, err := secret.Decrypt()
+	assert.NoError(t, err)
+	assert.Equal(t, "two", string(value))
+
+	value, err = secret.DecryptVersion(1)
+	assert.NoError(t, err)
+	assert.Equal(t, "one", string(value))
+
+	// Restoring took a snapshot of the state it replaced
+	snapshots, err := Snapshots()
+	assert.NoError(t, err)
+	assert.Len(t, snapshots, 2)
+	assert.Equal(t, snapshot.ID, snapshots[0].ID)
+	assert.Equal(t, "snapshots restore", snapshots[1].Reason)
+	assert.Equal(t, 0, snapshots[1].Secrets)
+
+	// Only the most recent snapshots are kept
+	_, err = TakeSnapshot(secretsPath, "update")
+	assert.NoError(t, err)
+
+	snapshots, err = Snapshots()
+	assert.NoError(t, err)
+	assert.Len(t, snapshots, 2)
+	assert.NotEqual(t, snapshot.ID, snapshots[0].ID)
+
+	_, err = FindSnapshot(snapshot.ID)
+	assert.Error(t, err)
+	_, err = os.Stat(filepath.Join(dir, ".snapshots", snapshot.ID))
+	assert.True(t, os.IsNotExist(err))
+
+	// As well as only the snapshots younger than the maximum age
+	SetSnapshots(filepath.Join(dir, ".snapshots"), 10, time.Hour)
+	assert.NoError(t, pruneSnapshots(time.Now().Add(2*time.Hour)))
+
+	snapshots, err = Snapshots()
+	assert.NoError(t, err)
+	assert.Empty(t, snapshots)
+
+	assert.ErrorContains(t, RestoreSnapshot(secretsPath, "missing"), "does not exist")
+}
+
+func TestPurgeFromSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, ".key")
+	secretsPath := filepath.Join(dir, "secrets")
+
+	SetHistory(filepath.Join(dir, ".history"), 5)
+	defer SetHistory("", 0)
+	SetSnapshots(filepath.Join(dir, ".snapshots"), 5, 0)
+	defer SetSnapshots("", 0, 0)
+
+	secret, err := NewSecret(keyPath, "prod", filepath.Join(secretsPath, "prod.thurin"))
+	assert.NoError(t, err)
+	assert.NoError(t, secret.Encrypt([]byte("one"), false))
+	assert.NoError(t, secret.Encrypt([]byte("two"), false))
+
+	nested, err := NewSecret(keyPath, "prod/db", filepath.Join(secretsPath, "prod", "db.thurin"))
+	assert.NoError(t, err)
+	assert.NoError(t, nested.Encrypt([]byte("nested"), false))
+
+	snapshot, err := TakeSnapshot(secretsPath, "delete")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, snapshot.Secrets)
+
+	// Snapshots taken after the given time are left untouched
+	assert.NoError(t, PurgeFromSnapshots("prod", snapshot.CreatedAt.Add(-time.Second)))
+	_, err = os.Stat(filepath.Join(snapshot.path, snapshotSecretsDir, "prod.thurin"))
+	assert.NoError(t, err)
+
+	assert.NoError(t, PurgeSecret(secretsPath, *secret))
+
+	for _, p := range []string{
+		filepath.Join(snapshot.path, snapshotSecretsDir, "prod.thurin"),
+		filepath.Join(snapshot.path, snapshotSecretsDir, "prod.meta"),
+		filepath.Join(snapshot.path, snapshotHistoryDir, "prod", "1.thurin"),
+	} {
+		_, err := os.Stat(p)
+		assert.True(t, os.IsNotExist(err), p)
+	}
+
+	purged, err := FindSnapshot(snapshot.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, purged.Secrets)
+
+	// The purged secret does not come back, the secrets of the nested namespace do
+	assert.NoError(t, RestoreSnapshot(secretsPath, purged.ID))
+
+	_, err = os.Stat(secret.path)
+	assert.True(t, os.IsNotExist(err))
+
+	value, err := nested.Decrypt()
+	assert.NoError(t, err)
+	assert.Equal(t, "nested", string(value))
+}
@@ -0,0 +1,104 @@
+package secrets
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const reValidTag = `^[\w\-]+$`
+
+// TagFilter selects secrets by their tags.
+type TagFilter struct {
+	Include []string // Secrets must have all of these tags
+	Exclude []string // Secrets must have none of these tags
+}
+
+// IsEmpty reports whether the filter has no tags, i.e. it matches every secret.
+func (f TagFilter) IsEmpty() bool {
+	return len(f.Include) == 0 && len(f.Exclude) == 0
+}
+
+// Match reports whether the given tags satisfy the filter.
+func (f TagFilter) Match(tags []string) bool {
+	for _, t := range f.Include {
+		if !slices.Contains(tags, t) {
+			return false
+		}
+	}
+
+	for _, t := range f.Exclude {
+		if slices.Contains(tags, t) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// String returns a readable description of the filter.
+func (f TagFilter) String() string {
+	var parts []string
+
+	if len(f.Include) > 0 {
+		parts = append(parts, "tagged "+strings.Join(f.Include, ", "))
+	}
+
+	if len(f.Exclude) > 0 {
+		parts = append(parts, "not tagged "+strings.Join(f.Exclude, ", "))
+	}
+
+	return strings.Join(parts, " and ")
+}
+
+// FilterSecrets returns the secrets whose tags match the filter.
+func FilterSecrets(secretFiles []Secret, filter TagFilter) ([]Secret, error) {
+	if filter.IsEmpty() {
+		return secretFiles, nil
+	}
+
+	var filtered []Secret
+
+	for _, secret := range secretFiles {
+		meta, err := secret.Metadata()
+		if err != nil {
+			return nil, err
+		}
+
+		if filter.Match(meta.Tags) {
+			filtered = append(filtered, secret)
+		}
+	}
+
+	return filtered, nil
+}
+
+// ValidateTag checks if a string is a valid tag.
+func ValidateTag(s string) error {
+	var re = regexp.MustCompile(reValidTag)
+
+	if re.MatchString(s) {
+		return nil
+	}
+
+	return errors.New("invalid tag: Tags can only contain alphanumeric, hyphens and underscores")
+}
+
+// AddTags adds tags to the metadata, ignoring tags it already has.
+func (m *Metadata) AddTags(tags ...string) {
+	for _, t := range tags {
+		if !slices.Contains(m.Tags, t) {
+			m.Tags = append(m.Tags, t)
+		}
+	}
+
+	slices.Sort(m.Tags)
+}
+
+// RemoveTags removes tags from the metadata.
+func (m *Metadata) RemoveTags(tags ...string) {
+	m.Tags = slices.DeleteFunc(m.Tags, func(t string) bool {
+		return slices.Contains(tags, t)
+	})
+}
@@ -0,0 +1,41 @@
+package secrets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTag(t *testing.T) {
+	assert.NoError(t, ValidateTag("prod"))
+	assert.NoError(t, ValidateTag("team-payments"))
+	assert.NoError(t, ValidateTag("ci_2"))
+
+	assert.Error(t, ValidateTag(""))
+	assert.Error(t, ValidateTag("with space"))
+	assert.Error(t, ValidateTag("with/slash"))
+	assert.Error(t, ValidateTag("with,comma"))
+}
+
+func TestTagFilterMatch(t *testing.T) {
+	tags := []string{"ci", "prod"}
+
+	assert.True(t, TagFilter{}.Match(tags))
+	assert.True(t, TagFilter{}.Match(nil))
+	assert.True(t, TagFilter{Include: []string{"ci"}}.Match(tags))
+	assert.True(t, TagFilter{Include: []string{"ci", "prod"}}.Match(tags))
+	assert.False(t, TagFilter{Include: []string{"ci", "temp"}}.Match(tags))
+	assert.False(t, TagFilter{Exclude: []string{"prod"}}.Match(tags))
+	assert.True(t, TagFilter{Include: []string{"ci"}, Exclude: []string{"temp"}}.Match(tags))
+	assert.False(t, TagFilter{Include: []string{"ci"}}.Match(nil))
+}
+
+func TestMetadataTags(t *testing.T) {
+	var meta Metadata
+
+	meta.AddTags("prod", "ci", "prod")
+	assert.Equal(t, []string{"ci", "prod"}, meta.Tags)
+
+	meta.RemoveTags("ci", "missing")
+	assert.Equal(t, []string{"prod"}, meta.Tags)
+}
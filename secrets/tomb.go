@@ -0,0 +1,65 @@
+//go:build !windows
+
+package secrets
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/engmtcdrm/go-entomb"
+)
+
+// errTombFromKey is why tombFromKey cannot be used on this platform, nil if it can.
+var errTombFromKey error
+
+// tombFromKey creates a tomb from an unprotected key. As entomb only reads keys from
+// a path, the key is handed to it through a named pipe in a private directory, so it
+// only passes through the pipe buffer in memory and never reaches a disk.
+func tombFromKey(key []byte) (*entomb.Tomb, error) {
+	dir, err := os.MkdirTemp("", ".key-*")
+	if err != nil {
+		return nil, fmt.Errorf("could not create temporary key directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	pipePath := filepath.Join(dir, "key")
+	if err := syscall.Mkfifo(pipePath, uint32(secretMode)); err != nil {
+		return nil, fmt.Errorf("could not create temporary key pipe: %w", err)
+	}
+
+	written := make(chan error, 1)
+	go func() {
+		// Blocks until the pipe is opened for reading
+		f, err := os.OpenFile(pipePath, os.O_WRONLY, 0)
+		if err != nil {
+			written <- err
+			return
+		}
+
+		_, err = f.Write(key)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		written <- err
+	}()
+
+	tomb, err := entomb.NewTomb(pipePath, true, true)
+
+	// Releases the writer if entomb failed before reading the key
+	if r, openErr := os.OpenFile(pipePath, os.O_RDONLY|syscall.O_NONBLOCK, 0); openErr == nil {
+		r.Close()
+	}
+	writeErr := <-written
+
+	if err != nil {
+		return nil, fmt.Errorf("could not create tomb: %w", err)
+	}
+
+	if writeErr != nil {
+		return nil, fmt.Errorf("could not write temporary key pipe: %w", writeErr)
+	}
+
+	return tomb, nil
+}
@@ -0,0 +1,19 @@
+//go:build windows
+
+package secrets
+
+import (
+	"errors"
+
+	"github.com/engmtcdrm/go-entomb"
+)
+
+// errTombFromKey is why tombFromKey cannot be used on this platform, nil if it can.
+var errTombFromKey = errors.New("the unprotected key would have to be written to disk, as Windows has no memory backed directory or pipe entomb can read a key from")
+
+// tombFromKey creates a tomb from an unprotected key. As entomb only reads keys from
+// a path, this is not supported on Windows, where the key could only be handed over
+// through a file on disk.
+func tombFromKey(key []byte) (*entomb.Tomb, error) {
+	return nil, errTombFromKey
+}
@@ -0,0 +1,314 @@
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	trashInfoFile   = "trash.json" // File describing a trashed secret, within its directory
+	trashSecretName = "secret"     // Base name of the trashed secret and its metadata
+	trashHistoryDir = "history"    // Previous versions of the trashed secret
+)
+
+var trashPath string // Where deleted secrets are kept until the trash is emptied
+
+// ErrHistoryDropped is returned by RestoreTrashed when the secret was restored, but
+// its previous versions were not because no history is kept.
+var ErrHistoryDropped = errors.New("previous versions were not restored, as no history is kept")
+
+// TrashedSecret is a deleted secret kept in the trash.
+type TrashedSecret struct {
+	ID        string    `json:"id"`         // Identifier of the trashed secret, based on when it was deleted
+	Name      string    `json:"name"`       // Name of the secret before it was deleted
+	DeletedAt time.Time `json:"deleted_at"` // When the secret was deleted
+	path      string    // Directory of the trashed secret
+}
+
+// SetTrash sets the directory where deleted secrets are kept.
+func SetTrash(path string) {
+	trashPath = path
+}
+
+// TrashSecret moves a secret, its metadata and its history into the trash, recording
+// its name and when it was deleted. Directories left empty by the move are removed,
+// the same way RemoveSecret does.
+func TrashSecret(secretsPath string, secret Secret) (*TrashedSecret, error) {
+	if trashPath == "" {
+		return nil, errors.New("trash path is not set")
+	}
+
+	now := time.Now()
+	id, err := createTimestampDir(trashPath, now)
+	if err != nil {
+		return nil, fmt.Errorf("could not move secret '%s' to the trash: %w", secret.name, err)
+	}
+
+	trashed := &TrashedSecret{ID: id, Name: secret.name, DeletedAt: now.UTC(), path: filepath.Join(trashPath, id)}
+
+	data, err := json.MarshalIndent(trashed, "", "  ")
+	if err != nil {
+		os.RemoveAll(trashed.path)
+		return nil, err
+	}
+
+	if err := os.WriteFile(filepath.Join(trashed.path, trashInfoFile), data, secretMode); err != nil {
+		os.RemoveAll(trashed.path)
+		return nil, fmt.Errorf("could not move secret '%s' to the trash: %w", secret.name, err)
+	}
+
+	transfers, err := secret.trashTransfers(trashed.path)
+	if err != nil {
+		os.RemoveAll(trashed.path)
+		return nil, err
+	}
+
+	if err := moveFiles(transfers); err != nil {
+		os.RemoveAll(trashed.path)
+		return nil, fmt.Errorf("could not move secret '%s' to the trash: %w", secret.name, err)
+	}
+
+	if err := removeEmptyDirs(secretsPath, filepath.Dir(secret.path)); err != nil {
+		return trashed, err
+	}
+
+	if historyPath != "" {
+		if err := removeEmptyDirs(historyPath, secret.historyDir()); err != nil {
+			return trashed, err
+		}
+	}
+
+	return trashed, nil
+}
+
+// trashTransfers returns the source and destination of every file of the secret when
+// moving it into the trash directory dir.
+func (s *Secret) trashTransfers(dir string) ([][2]string, error) {
+	versions, err := s.previousVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	trashed := filepath.Join(dir, trashSecretName)
+	transfers := [][2]string{{s.path, trashed + filepath.Ext(s.path)}}
+
+	if _, err := os.Stat(s.MetaPath()); err == nil {
+		transfers = append(transfers, [2]string{s.MetaPath(), trashed + metaExt})
+	}
+
+	for _, v := range versions {
+		dst := Version{path: filepath.Join(dir, trashHistoryDir, filepath.Base(v.path))}
+		transfers = append(transfers, [2]string{v.path, dst.path})
+
+		if _, err := os.Stat(v.metaPath()); err == nil {
+			transfers = append(transfers, [2]string{v.metaPath(), dst.metaPath()})
+		}
+	}
+
+	return transfers, nil
+}
+
+// encryptedFiles returns the encrypted value of the trashed secret and of each of its
+// previous versions.
+func (t TrashedSecret) encryptedFiles() ([]string, error) {
+	files, err := encryptedFiles(t.path, trashInfoFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read trashed secret '%s': %w", t.Name, err)
+	}
+
+	return files, nil
+}
+
+// TrashedSecrets returns every secret in the trash, oldest first.
+func TrashedSecrets() ([]TrashedSecret, error) {
+	if trashPath == "" {
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(trashPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("could not read trash: %w", err)
+	}
+
+	var trashed []TrashedSecret
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(trashPath, entry.Name())
+
+		data, err := os.ReadFile(filepath.Join(path, trashInfoFile))
+		if err != nil {
+			continue
+		}
+
+		var t TrashedSecret
+		if err := json.Unmarshal(data, &t); err != nil || t.ID != entry.Name() {
+			continue
+		}
+		t.path = path
+
+		trashed = append(trashed, t)
+	}
+
+	slices.SortFunc(trashed, func(a, b TrashedSecret) int {
+		if c := a.DeletedAt.Compare(b.DeletedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	return trashed, nil
+}
+
+// RestoreTrashed moves the most recently deleted secret with the given name out of the
+// trash, along with its metadata and history. A secret that exists under the same
+// name is never overwritten. When no history is kept, the previous versions of the
+// secret are shredded with the rest of the trash entry and ErrHistoryDropped is
+// returned along with the restored secret.
+func RestoreTrashed(keyPath string, secretsPath string, name string) (*Secret, error) {
+	trashed, err := TrashedSecrets()
+	if err != nil {
+		return nil, err
+	}
+
+	var found *TrashedSecret
+	for i := len(trashed) - 1; i >= 0; i-- {
+		if trashed[i].Name == name {
+			found = &trashed[i]
+			break
+		}
+	}
+
+	if found == nil {
+		return nil, fmt.Errorf("secret '%s' is not in the trash: %w", name, ErrNotFound)
+	}
+
+	entries, err := os.ReadDir(found.path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read trashed secret '%s': %w", name, err)
+	}
+
+	// The trashed secret keeps the file extension it was stored with
+	var secretFile string
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if !entry.IsDir() && strings.TrimSuffix(entry.Name(), ext) == trashSecretName && ext != metaExt {
+			secretFile = entry.Name()
+		}
+	}
+
+	if secretFile == "" {
+		return nil, fmt.Errorf("trashed secret '%s' is corrupted: its encrypted value is missing", name)
+	}
+
+	secret, err := NewSecret(keyPath, name, filepath.Join(secretsPath, name+filepath.Ext(secretFile)))
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(secret.path); err == nil {
+		return nil, fmt.Errorf("secret '%s' %w", name, ErrExists)
+	}
+
+	trashedVersions, err := os.ReadDir(filepath.Join(found.path, trashHistoryDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("could not read history of trashed secret '%s': %w", name, err)
+	}
+
+	restores := [][2]string{{filepath.Join(found.path, secretFile), secret.path}}
+	if _, err := os.Stat(filepath.Join(found.path, trashSecretName+metaExt)); err == nil {
+		restores = append(restores, [2]string{filepath.Join(found.path, trashSecretName+metaExt), secret.MetaPath()})
+	}
+
+	dropped := 0
+	for _, entry := range trashedVersions {
+		if historyPath == "" {
+			if filepath.Ext(entry.Name()) != metaExt {
+				dropped++
+			}
+			continue
+		}
+
+		restores = append(restores, [2]string{
+			filepath.Join(found.path, trashHistoryDir, entry.Name()),
+			filepath.Join(secret.historyDir(), entry.Name()),
+		})
+	}
+
+	if err := moveFiles(restores); err != nil {
+		return nil, fmt.Errorf("could not restore secret '%s' from the trash: %w", name, err)
+	}
+
+	if err := shredTree(found.path, ShredFile); err != nil {
+		return secret, fmt.Errorf("could not remove secret '%s' from the trash: %w", name, err)
+	}
+
+	if dropped > 0 {
+		return secret, fmt.Errorf("%d previous version(s) of secret '%s' dropped: %w", dropped, name, ErrHistoryDropped)
+	}
+
+	return secret, nil
+}
+
+// EmptyTrash shreds the secrets deleted longer than olderThan ago, or every secret
+// in the trash if olderThan is 0, along with their copies in the snapshots taken
+// before they were deleted. It returns the secrets removed.
+func EmptyTrash(olderThan time.Duration, now time.Time) ([]TrashedSecret, error) {
+	trashed, err := TrashedSecrets()
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []TrashedSecret
+	for _, t := range trashed {
+		if olderThan > 0 && now.Sub(t.DeletedAt) <= olderThan {
+			continue
+		}
+
+		if err := shredTree(t.path, ShredFile); err != nil {
+			return removed, fmt.Errorf("could not remove secret '%s' from the trash: %w", t.Name, err)
+		}
+
+		// Snapshots taken before the secret was deleted still hold it
+		if err := PurgeFromSnapshots(t.Name, t.DeletedAt); err != nil {
+			return removed, err
+		}
+
+		removed = append(removed, t)
+	}
+
+	return removed, nil
+}
+
+// moveFiles moves every file from its source to its destination. If any move fails,
+// the moves already made are undone.
+func moveFiles(transfers [][2]string) (err error) {
+	var done [][2]string
+	defer func() {
+		if err != nil {
+			for i := len(done) - 1; i >= 0; i-- {
+				os.Rename(done[i][1], done[i][0])
+			}
+		}
+	}()
+
+	for _, t := range transfers {
+		if err = moveFile(t[0], t[1]); err != nil {
+			return err
+		}
+		done = append(done, t)
+	}
+
+	return nil
+}
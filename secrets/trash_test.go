@@ -0,0 +1,140 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrash(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, ".key")
+	secretsPath := filepath.Join(dir, "secrets")
+	historyDir := filepath.Join(dir, ".history")
+
+	SetHistory(historyDir, 5)
+	defer SetHistory("", 0)
+
+	_, err := TrashSecret(secretsPath, Secret{})
+	assert.ErrorContains(t, err, "trash path is not set")
+
+	SetTrash(filepath.Join(dir, ".trash"))
+	defer SetTrash("")
+
+	secret, err := NewSecret(keyPath, "prod/db/password", filepath.Join(secretsPath, "prod", "db", "password.thurin"))
+	assert.NoError(t, err)
+	assert.NoError(t, secret.Encrypt([]byte("one"), false))
+	assert.NoError(t, secret.Encrypt([]byte("two"), false))
+
+	trashed, err := TrashSecret(secretsPath, *secret)
+	assert.NoError(t, err)
+	assert.Equal(t, "prod/db/password", trashed.Name)
+
+	// The secret and its history are gone, without leaving empty directories behind
+	_, err = os.Stat(filepath.Join(secretsPath, "prod"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(historyDir, "prod"))
+	assert.True(t, os.IsNotExist(err))
+
+	list, err := TrashedSecrets()
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+	assert.Equal(t, trashed.ID, list[0].ID)
+
+	_, err = RestoreTrashed(keyPath, secretsPath, "missing")
+	assert.ErrorContains(t, err, "not in the trash")
+
+	restored, err := RestoreTrashed(keyPath, secretsPath, "prod/db/password")
+	assert.NoError(t, err)
+
+	value, err := restored.Decrypt()
+	assert.NoError(t, err)
+	assert.Equal(t, "two", string(value))
+
+	value, err = restored.DecryptVersion(1)
+	assert.NoError(t, err)
+	assert.Equal(t, "one", string(value))
+
+	list, err = TrashedSecrets()
+	assert.NoError(t, err)
+	assert.Empty(t, list)
+
+	// Without history, the secret is restored but dropping its previous versions is reported
+	_, err = TrashSecret(secretsPath, *restored)
+	assert.NoError(t, err)
+	SetHistory("", 0)
+
+	restored, err = RestoreTrashed(keyPath, secretsPath, "prod/db/password")
+	assert.ErrorIs(t, err, ErrHistoryDropped)
+	assert.ErrorContains(t, err, "1 previous version(s)")
+
+	value, err = restored.Decrypt()
+	assert.NoError(t, err)
+	assert.Equal(t, "two", string(value))
+
+	list, err = TrashedSecrets()
+	assert.NoError(t, err)
+	assert.Empty(t, list)
+
+	// Restoring never overwrites an existing secret
+	_, err = TrashSecret(secretsPath, *restored)
+	assert.NoError(t, err)
+	assert.NoError(t, restored.Encrypt([]byte("new"), false))
+
+	_, err = RestoreTrashed(keyPath, secretsPath, "prod/db/password")
+	assert.ErrorContains(t, err, "already exists")
+
+	// Only secrets deleted long enough ago are removed when emptying the trash
+	removed, err := EmptyTrash(time.Hour, time.Now())
+	assert.NoError(t, err)
+	assert.Empty(t, removed)
+
+	removed, err = EmptyTrash(time.Hour, time.Now().Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, removed, 1)
+
+	list, err = TrashedSecrets()
+	assert.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func TestEmptyTrash_Snapshots(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, ".key")
+	secretsPath := filepath.Join(dir, "secrets")
+
+	SetHistory(filepath.Join(dir, ".history"), 5)
+	defer SetHistory("", 0)
+	SetTrash(filepath.Join(dir, ".trash"))
+	defer SetTrash("")
+	SetSnapshots(filepath.Join(dir, ".snapshots"), 5, 0)
+	defer SetSnapshots("", 0, 0)
+
+	secret, err := NewSecret(keyPath, "db", filepath.Join(secretsPath, "db.thurin"))
+	assert.NoError(t, err)
+	assert.NoError(t, secret.Encrypt([]byte("old"), false))
+
+	before, err := TakeSnapshot(secretsPath, "delete")
+	assert.NoError(t, err)
+
+	_, err = TrashSecret(secretsPath, *secret)
+	assert.NoError(t, err)
+
+	// A new secret with the same name, in a snapshot taken after the deletion
+	assert.NoError(t, secret.Encrypt([]byte("new"), false))
+	after, err := TakeSnapshot(secretsPath, "update")
+	assert.NoError(t, err)
+
+	removed, err := EmptyTrash(0, time.Now())
+	assert.NoError(t, err)
+	assert.Len(t, removed, 1)
+
+	// Only the snapshot taken before the deletion held the secret removed from the trash
+	_, err = os.Stat(filepath.Join(before.path, snapshotSecretsDir, "db.thurin"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(after.path, snapshotSecretsDir, "db.thurin"))
+	assert.NoError(t, err)
+}
@@ -3,10 +3,13 @@ package secrets
 import (
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
 const reValidName = `^[\w\/\\\-]+$`
@@ -48,33 +51,41 @@ func GetSecretFiles(keyPath, secretsPath, secretExt string) ([]Secret, error) {
 	return secretFiles, nil
 }
 
-// RemoveSecret removes a secret from the specified secrets path
+// RemoveSecret shreds a secret, its metadata and its history from the specified secrets path
 func RemoveSecret(secretsPath string, secret Secret) error {
 	if secret.Path() == "" {
 		return errors.New("secret path cannot be empty")
 	}
 
-	if err := os.Remove(secret.Path()); err != nil {
+	if err := ShredFile(secret.Path()); err != nil {
 		return fmt.Errorf("could not remove secret '%s': %w", secret.name, err)
 	}
 
-	// Ignore trying to delete the secrets directory itself
-	if secret.Path() == secretsPath {
-		return nil
+	if err := ShredFile(secret.MetaPath()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("could not remove metadata of secret '%s': %w", secret.name, err)
 	}
 
-	dirIsEmpty, err := isDirEmpty(filepath.Dir(secret.Path()))
-	if err != nil {
-		return fmt.Errorf("could not check if directory is empty: %w", err)
+	if err := secret.removeHistory(); err != nil {
+		return err
 	}
 
-	if dirIsEmpty {
-		if err := os.Remove(filepath.Dir(secret.Path())); err != nil {
-			return fmt.Errorf("could not remove directory '%s': %w", filepath.Dir(secret.Path()), err)
-		}
+	// Remove every directory the secret leaves empty, up to the secrets directory
+	return removeEmptyDirs(secretsPath, filepath.Dir(secret.Path()))
+}
+
+// PurgeSecret shreds a secret along with its metadata and history like RemoveSecret,
+// and every copy of it kept in the snapshots, so it cannot be brought back.
+func PurgeSecret(secretsPath string, secret Secret) error {
+	if err := RemoveSecret(secretsPath, secret); err != nil {
+		return err
 	}
 
-	return nil
+	return PurgeFromSnapshots(secret.name, time.Time{})
+}
+
+// CleanupFile shreds a plain text file once its contents have been encrypted.
+func CleanupFile(path string) error {
+	return ShredFile(path)
 }
 
 // ValidateName checks if a string is a valid secret name
@@ -108,6 +119,31 @@ func ClearSecret(s *[]byte) {
 	}
 }
 
+// createTimestampDir creates a directory in parent named after t, e.g. 20250101-120000,
+// and returns its name. Directories created within the same second get a sequence
+// number, e.g. 20250101-120000-2.
+func createTimestampDir(parent string, t time.Time) (string, error) {
+	if err := os.MkdirAll(parent, dirMode); err != nil {
+		return "", err
+	}
+
+	base := t.Format("20060102-150405")
+	for i := 1; ; i++ {
+		name := base
+		if i > 1 {
+			name += "-" + strconv.Itoa(i)
+		}
+
+		err := os.Mkdir(filepath.Join(parent, name), dirMode)
+		if err == nil {
+			return name, nil
+		}
+		if !os.IsExist(err) {
+			return "", err
+		}
+	}
+}
+
 // isDirEmpty checks if a directory is empty.
 func isDirEmpty(dirPath string) (bool, error) {
 	entries, err := os.ReadDir(dirPath)
@@ -117,6 +153,31 @@ func isDirEmpty(dirPath string) (bool, error) {
 	return len(entries) == 0, nil
 }
 
+// removeEmptyDirs removes dir and each of its parents that are left empty, stopping at root.
+func removeEmptyDirs(root string, dir string) error {
+	root = filepath.Clean(root)
+
+	for dir = filepath.Clean(dir); dir != root && strings.HasPrefix(dir, root+string(filepath.Separator)); dir = filepath.Dir(dir) {
+		empty, err := isDirEmpty(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("could not check if directory is empty: %w", err)
+		}
+
+		if !empty {
+			return nil
+		}
+
+		if err := os.Remove(dir); err != nil {
+			return fmt.Errorf("could not remove directory '%s': %w", dir, err)
+		}
+	}
+
+	return nil
+}
+
 // trimSpaceBytes trims leading and trailing ASCII whitespace from a byte slice in-place.
 // Returns a subslice of the original slice, so the underlying array is not copied.
 func trimSpaceBytes(b *[]byte) []byte {